@@ -0,0 +1,29 @@
+package speedlog
+
+import (
+	"math"
+	"strconv"
+)
+
+// Append helpers for custom Encoders. They are the ones the built-in
+// encoders use and never allocate beyond growing buf.
+
+func AppendInt(buf []byte, v int64) []byte { return strconv.AppendInt(buf, v, 10) }
+
+func AppendUint(buf []byte, v uint64) []byte { return strconv.AppendUint(buf, v, 10) }
+
+// AppendFloat uses the shortest representation that round-trips.
+func AppendFloat(buf []byte, v float64) []byte { return strconv.AppendFloat(buf, v, 'g', -1, 64) }
+
+func AppendBool(buf []byte, v bool) []byte { return strconv.AppendBool(buf, v) }
+
+// AppendQuote appends s as a JSON string literal, replacing invalid UTF-8
+// with U+FFFD.
+func AppendQuote(buf []byte, s string) []byte { return appendJSONString(buf, s) }
+
+// AppendValue appends f's value the way TextEncoder renders it.
+func AppendValue(buf []byte, f Field) []byte { return appendValue(buf, &f) }
+
+func appendFloatBits(buf []byte, bits int64) []byte {
+	return AppendFloat(buf, math.Float64frombits(uint64(bits)))
+}
@@ -0,0 +1,89 @@
+package speedlog
+
+import "sync"
+
+// WithArena copies queued lines into chunkSize-byte chunks instead of
+// giving each line its own pooled buffer. A chunk goes back on a short
+// free list once the writer has consumed every line in it, so at very
+// high rates the heap holds a handful of large objects rather than
+// thousands of small ones. Lines over a quarter of a chunk still use the
+// pool. It has no effect with WithRingBuffer, whose slots already own
+// their buffers.
+func WithArena(chunkSize int) Option {
+	return func(l *Logger) {
+		if chunkSize < 4<<10 {
+			l.invalid("WithArena(%d): chunks must be at least 4 KiB", chunkSize)
+			return
+		}
+		l.arena = &arena{size: chunkSize}
+	}
+}
+
+// Carving and releasing happen under mu, which is held only for a few
+// adds; encoding happens outside it, into a pooled scratch buffer.
+type arena struct {
+	mu   sync.Mutex
+	size int
+	cur  *chunk
+	free []*chunk
+}
+
+type chunk struct {
+	buf  []byte
+	off  int
+	refs int // lines not yet released, plus one while current
+}
+
+const arenaFree = 4
+
+// alloc copies line into the current chunk, starting a new one when it
+// doesn't fit. It returns nil if line is too big for the arena.
+func (a *arena) alloc(line []byte) ([]byte, *chunk) {
+	n := len(line)
+	if n > a.size/4 {
+		return nil, nil
+	}
+	a.mu.Lock()
+	c := a.cur
+	if c == nil || c.off+n > len(c.buf) {
+		if c != nil {
+			a.unref(c)
+		}
+		c = a.fresh()
+		a.cur = c
+	}
+	b := c.buf[c.off : c.off+n : c.off+n]
+	c.off += n
+	c.refs++
+	a.mu.Unlock()
+	copy(b, line)
+	return b, c
+}
+
+func (a *arena) fresh() *chunk {
+	if n := len(a.free); n > 0 {
+		c := a.free[n-1]
+		a.free = a.free[:n-1]
+		c.off, c.refs = 0, 1
+		return c
+	}
+	return &chunk{buf: make([]byte, a.size), refs: 1}
+}
+
+func (a *arena) unref(c *chunk) {
+	if c.refs--; c.refs == 0 && len(a.free) < arenaFree {
+		a.free = append(a.free, c)
+	}
+}
+
+// release drops one reference per arena record in batch under a single
+// lock.
+func (a *arena) release(batch []record) {
+	a.mu.Lock()
+	for i := range batch {
+		if c := batch[i].chunk; c != nil {
+			a.unref(c)
+		}
+	}
+	a.mu.Unlock()
+}
@@ -0,0 +1,138 @@
+package speedlog
+
+import (
+	"errors"
+	"io"
+	"os"
+	"sync"
+	"time"
+)
+
+var ErrAuditField = errors.New("speedlog: audit event needs actor, action and object")
+
+type AuditOption func(*AuditLogger)
+
+// AuditLogger writes compliance events synchronously: Log returns only
+// after the entry reached the writer (and was fsynced, for writers with a
+// Sync method), and never samples or drops.
+type AuditLogger struct {
+	mu        sync.Mutex
+	w         io.Writer
+	enc       Encoder
+	signer    *signer
+	syncEvery int
+	unsynced  int
+	buf       []byte
+	closed    bool
+}
+
+// WithAuditSyncEvery fsyncs after every n entries instead of each one.
+// Up to n-1 acknowledged entries can then be lost in a crash.
+func WithAuditSyncEvery(n int) AuditOption {
+	return func(a *AuditLogger) {
+		if n > 0 {
+			a.syncEvery = n
+		}
+	}
+}
+
+func WithAuditEncoder(enc Encoder) AuditOption {
+	return func(a *AuditLogger) {
+		if enc != nil {
+			a.enc = enc
+		}
+	}
+}
+
+func WithAuditSigning(key []byte) AuditOption {
+	return func(a *AuditLogger) {
+		a.signer = newSigner(key)
+	}
+}
+
+func NewAuditLogger(w io.Writer, opts ...AuditOption) *AuditLogger {
+	a := &AuditLogger{w: w, enc: TextEncoder{}, syncEvery: 1}
+	for _, opt := range opts {
+		opt(a)
+	}
+	if a.signer != nil {
+		a.signer.resume(w)
+	}
+	return a
+}
+
+// Log records that actor performed action on object.
+func (a *AuditLogger) Log(actor, action, object string, fields ...Field) error {
+	if actor == "" || action == "" || object == "" {
+		return ErrAuditField
+	}
+	e := Entry{
+		Time:    time.Now(),
+		Level:   INFO,
+		Message: "audit",
+		Fields:  make([]Field, 0, 3+len(fields)),
+	}
+	e.Fields = append(e.Fields, String("actor", actor), String("action", action), String("object", object))
+	e.Fields = append(e.Fields, flatten(fields)...)
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.closed {
+		return os.ErrClosed
+	}
+	a.buf = a.enc.Encode(a.buf[:0], e)
+	if a.signer != nil {
+		a.buf = a.signer.sign(a.buf)
+	}
+	if _, err := a.w.Write(a.buf); err != nil {
+		return err
+	}
+	if f, ok := a.w.(flusher); ok {
+		if err := f.Flush(); err != nil {
+			return err
+		}
+	}
+	a.unsynced++
+	if a.unsynced >= a.syncEvery {
+		return a.sync()
+	}
+	return nil
+}
+
+type syncer interface {
+	Sync() error
+}
+
+func (a *AuditLogger) sync() error {
+	a.unsynced = 0
+	// Terminals and pipes can't be fsynced.
+	if a.w == os.Stdout || a.w == os.Stderr {
+		return nil
+	}
+	if s, ok := a.w.(syncer); ok {
+		return s.Sync()
+	}
+	return nil
+}
+
+func (a *AuditLogger) Sync() error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return a.sync()
+}
+
+func (a *AuditLogger) Close() error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.closed {
+		return nil
+	}
+	a.closed = true
+	err := a.sync()
+	if c, ok := a.w.(io.Closer); ok && a.w != os.Stdout && a.w != os.Stderr {
+		if cerr := c.Close(); err == nil {
+			err = cerr
+		}
+	}
+	return err
+}
@@ -0,0 +1,92 @@
+package speedlog
+
+// LogBatch logs entries as a unit: consecutive entries bound for the same
+// sinks are encoded into one buffer and queued with a single channel
+// operation, so they reach each sink in one write. Entries below the
+// level are skipped, a zero Time is set to now, and l's fields, hooks,
+// load shedding, the stderr fallback and WithSyncLevel apply as for any
+// call; suppression, Deferred, caller and stack fields don't. An entry
+// written inline ends the run before it.
+// With signing each entry is still queued on its own, since signatures
+// are per line.
+func (l *Logger) LogBatch(entries []Entry) {
+	var buf []byte
+	var mask uint64
+	level := -1
+	flush := func() {
+		if len(buf) > 0 {
+			l.push(buf, mask, level)
+		}
+		buf, level = nil, -1
+	}
+	for i := range entries {
+		src := &entries[i]
+		if !l.IsLevelEnabled(src.Level) {
+			continue
+		}
+		e := l.entry(src.Level, src.Message, src.Fields)
+		if !src.Time.IsZero() {
+			e.Time, e.ts = src.Time, nil
+		}
+		if l.hooks != nil && !l.runHooks(&e) {
+			continue
+		}
+		if l.shed != nil && e.Level < WARN && l.shedding(e.Level) {
+			continue
+		}
+		var m uint64
+		if set := l.set.Load(); set != nil && set.routed {
+			if m = set.maskFor(&e); m == 0 {
+				continue
+			}
+		}
+		if l.fallback != nil && e.Level >= WARN && l.degraded() {
+			l.toStderr(&e, nil)
+		}
+		if l.inline && e.Level >= l.inlineLevel {
+			flush()
+			l.writeInline(&e, nil, m)
+			continue
+		}
+		if len(buf) > 0 && (m != mask || l.signing) {
+			flush()
+		}
+		if buf == nil {
+			buf = l.bufPool.get(min(sizeHint(&e)*(len(entries)-i), maxPooledBuf))
+		}
+		n := len(buf)
+		buf = l.enc.Encode(buf, e)
+		mask, level = m, max(level, e.Level)
+		l.entries.Add(1)
+		if l.volume != nil {
+			l.volume.record(e.Level, e.Time, len(buf)-n)
+		}
+	}
+	flush()
+}
+
+// push queues an already encoded, pooled buffer of one or more lines.
+func (l *Logger) push(line []byte, mask uint64, level int) {
+	if !l.beginSend() {
+		l.late(line)
+		l.bufPool.put(line)
+		return
+	}
+	defer l.endSend()
+	l.enter()
+	if l.ring == nil || l.urgent(level) {
+		l.enqueue(record{line: line, mask: mask, level: level})
+		return
+	}
+	defer l.bufPool.put(line)
+	s, pos, ok := l.ring.claim(l.done)
+	if !ok {
+		l.late(line)
+		l.dropped()
+		return
+	}
+	s.buf = append(s.buf[:0], line...)
+	s.mask = mask
+	s.level = level
+	l.ring.publish(s, pos)
+}
@@ -0,0 +1,99 @@
+package speedlog
+
+import (
+	"sync"
+	"sync/atomic"
+)
+
+// bufTiers are the size classes of pooled line buffers. A buffer is pooled
+// in the largest class it can hold and handed out for requests up to that
+// class, so one huge message only recycles for other huge messages, and
+// anything past maxPooledBuf is left to the GC.
+var bufTiers = [...]int{512, 4 << 10, 32 << 10, 256 << 10}
+
+const maxPooledBuf = 1 << 20
+
+// lineBuf boxes a slice so pooling it doesn't allocate an interface value
+// on every Put; empty boxes are recycled through holders.
+type lineBuf struct {
+	b []byte
+}
+
+type bufPool struct {
+	tiers   [len(bufTiers)]sync.Pool
+	holders sync.Pool
+
+	gets, puts, news, discarded atomic.Uint64
+	pooled                      atomic.Int64
+}
+
+// PoolStats counts line buffer traffic. News are buffers the pools had to
+// allocate; once warmed up they should stay near zero. PooledBytes is the
+// capacity handed back and not taken out again, an upper bound because the
+// GC may free pooled buffers at any time.
+type PoolStats struct {
+	Gets        uint64
+	Puts        uint64
+	News        uint64
+	Discarded   uint64
+	PooledBytes int64
+}
+
+func (p *bufPool) stats() PoolStats {
+	return PoolStats{
+		Gets:        p.gets.Load(),
+		Puts:        p.puts.Load(),
+		News:        p.news.Load(),
+		Discarded:   p.discarded.Load(),
+		PooledBytes: max(p.pooled.Load(), 0),
+	}
+}
+
+func (a PoolStats) add(b PoolStats) PoolStats {
+	return PoolStats{a.Gets + b.Gets, a.Puts + b.Puts, a.News + b.News, a.Discarded + b.Discarded, a.PooledBytes + b.PooledBytes}
+}
+
+// get returns an empty buffer with room for at least n bytes when n fits
+// a size class.
+func (p *bufPool) get(n int) []byte {
+	p.gets.Add(1)
+	t := 0
+	for t < len(bufTiers)-1 && bufTiers[t] < n {
+		t++
+	}
+	if v := p.tiers[t].Get(); v != nil {
+		h := v.(*lineBuf)
+		b := h.b[:0]
+		h.b = nil
+		p.holders.Put(h)
+		p.pooled.Add(-int64(cap(b)))
+		return b
+	}
+	p.news.Add(1)
+	return make([]byte, 0, max(bufTiers[t], n))
+}
+
+func (p *bufPool) put(b []byte) {
+	c := cap(b)
+	if c < bufTiers[0] || c > maxPooledBuf {
+		p.discarded.Add(1)
+		return
+	}
+	p.puts.Add(1)
+	p.pooled.Add(int64(c))
+	t := len(bufTiers) - 1
+	for bufTiers[t] > c {
+		t--
+	}
+	h, _ := p.holders.Get().(*lineBuf)
+	if h == nil {
+		h = new(lineBuf)
+	}
+	h.b = b
+	p.tiers[t].Put(h)
+}
+
+// sizeHint guesses the encoded size of e to pick a size class.
+func sizeHint(e *Entry) int {
+	return 64 + len(e.Message) + 32*len(e.Fields)
+}
@@ -0,0 +1,390 @@
+package speedlog
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const busTimeout = 5 * time.Second
+
+// busAddr splits a nats:// or redis:// URL (or a bare host:port) into the
+// dial address and its credentials.
+func busAddr(raw, scheme, port string) (addr string, u *url.URL, err error) {
+	if !strings.Contains(raw, "://") {
+		raw = scheme + "://" + raw
+	}
+	if u, err = url.Parse(raw); err != nil {
+		return "", nil, err
+	}
+	if u.Scheme != scheme {
+		return "", nil, fmt.Errorf("speedlog: unsupported URL scheme %q", u.Scheme)
+	}
+	if u.Port() == "" {
+		return net.JoinHostPort(u.Hostname(), port), u, nil
+	}
+	return u.Host, u, nil
+}
+
+// NATSWriter is a sink that publishes each line (without its newline) as
+// one message on a NATS subject. Every sink write goes out as a single
+// socket write, so the sink buffer and flush interval set the batch size.
+// TLS isn't supported.
+type NATSWriter struct {
+	mu         sync.Mutex
+	r          redialer
+	subject    string
+	connect    []byte
+	maxPayload int
+	lines      partialLines
+	buf        []byte
+	lost       atomic.Pointer[net.Conn]
+	srvErr     atomic.Pointer[error]
+}
+
+// NewNATSWriter connects to addr, a nats://[user:pass@|token@]host[:port]
+// URL or a bare host:port. A lost connection is redialed on a later write.
+func NewNATSWriter(addr, subject string) (*NATSWriter, error) {
+	if subject == "" || strings.ContainsAny(subject, " \t\r\n") {
+		return nil, fmt.Errorf("speedlog: invalid NATS subject %q", subject)
+	}
+	hostport, u, err := busAddr(addr, "nats", "4222")
+	if err != nil {
+		return nil, err
+	}
+	opts := map[string]any{"verbose": false, "pedantic": false, "name": "speedlog", "lang": "go"}
+	if u.User != nil {
+		if pass, ok := u.User.Password(); ok {
+			opts["user"], opts["pass"] = u.User.Username(), pass
+		} else {
+			opts["auth_token"] = u.User.Username()
+		}
+	}
+	b, _ := json.Marshal(opts)
+	w := &NATSWriter{subject: subject, connect: append(append([]byte("CONNECT "), b...), "\r\nPING\r\n"...)}
+	w.r = redialer{network: "tcp", addr: hostport, timeout: busTimeout, setup: w.handshake}
+	if _, err := w.r.get(); err != nil {
+		return nil, err
+	}
+	return w, nil
+}
+
+func (w *NATSWriter) handshake(conn net.Conn) error {
+	br := bufio.NewReader(conn)
+	line, err := br.ReadString('\n')
+	if err != nil {
+		return err
+	}
+	info, ok := strings.CutPrefix(line, "INFO ")
+	if !ok {
+		return fmt.Errorf("speedlog: nats: unexpected greeting %q", strings.TrimSpace(line))
+	}
+	var srv struct {
+		TLSRequired bool `json:"tls_required"`
+		MaxPayload  int  `json:"max_payload"`
+	}
+	if err := json.Unmarshal([]byte(info), &srv); err != nil {
+		return fmt.Errorf("speedlog: nats: bad INFO: %w", err)
+	}
+	if srv.TLSRequired {
+		return errors.New("speedlog: nats: server requires TLS")
+	}
+	if _, err := conn.Write(w.connect); err != nil {
+		return err
+	}
+	// The PONG confirms the CONNECT was accepted.
+	for {
+		line, err := br.ReadString('\n')
+		if err != nil {
+			return err
+		}
+		line = strings.TrimSpace(line)
+		if line == "PONG" {
+			break
+		}
+		if msg, ok := strings.CutPrefix(line, "-ERR "); ok {
+			return fmt.Errorf("speedlog: nats: %s", msg)
+		}
+	}
+	w.maxPayload = srv.MaxPayload
+	go w.read(conn, br)
+	return nil
+}
+
+// read answers server PINGs, which NATS uses to drop dead clients, and
+// notes errors and the loss of conn for the next write.
+func (w *NATSWriter) read(conn net.Conn, br *bufio.Reader) {
+	for {
+		line, err := br.ReadString('\n')
+		if err != nil {
+			w.lost.Store(&conn)
+			return
+		}
+		switch line = strings.TrimSpace(line); {
+		case line == "PING":
+			_, _ = conn.Write([]byte("PONG\r\n"))
+		case strings.HasPrefix(line, "-ERR "):
+			err := fmt.Errorf("speedlog: nats: %s", line[5:])
+			w.srvErr.Store(&err)
+		}
+	}
+}
+
+func (w *NATSWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if c := w.lost.Swap(nil); c != nil && *c == w.r.conn {
+		w.r.drop()
+	}
+	n, err := w.lines.write(p, w.publish)
+	if e := w.srvErr.Swap(nil); e != nil && err == nil {
+		// The data went out; report the error without dropping the batch.
+		return n, *e
+	}
+	return n, err
+}
+
+func (w *NATSWriter) publish(lines []byte) error {
+	w.buf = w.buf[:0]
+	skipped := 0
+	_ = eachLine(lines, func(line []byte) error {
+		if w.maxPayload > 0 && len(line) > w.maxPayload {
+			skipped++
+			return nil
+		}
+		w.buf = append(w.buf, "PUB "...)
+		w.buf = append(w.buf, w.subject...)
+		w.buf = append(w.buf, ' ')
+		w.buf = strconv.AppendInt(w.buf, int64(len(line)), 10)
+		w.buf = append(w.buf, "\r\n"...)
+		w.buf = append(w.buf, line...)
+		w.buf = append(w.buf, "\r\n"...)
+		return nil
+	})
+	if len(w.buf) > 0 {
+		err := w.r.do(func(conn net.Conn) error {
+			_ = conn.SetWriteDeadline(time.Now().Add(busTimeout))
+			_, err := conn.Write(w.buf)
+			return err
+		})
+		if err != nil {
+			return err
+		}
+	}
+	if skipped > 0 {
+		return fmt.Errorf("speedlog: nats: dropped %d lines over the %d byte payload limit", skipped, w.maxPayload)
+	}
+	return nil
+}
+
+// Close publishes a trailing line that lacked a newline and closes the
+// connection.
+func (w *NATSWriter) Close() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	err := w.lines.flush(w.publish)
+	w.r.drop()
+	return err
+}
+
+// RedisStreamWriter is a sink that appends each line to a Redis Stream as
+// an entry with a single "line" field, via pipelined XADDs: one round trip
+// per sink write. TLS isn't supported.
+type RedisStreamWriter struct {
+	mu     sync.Mutex
+	r      redialer
+	br     *bufio.Reader
+	auth   [][]string
+	prefix []string
+	lines  partialLines
+	buf    []byte
+	n      int
+}
+
+// NewRedisStreamWriter connects to addr, a redis://[user:pass@]host[:port][/db]
+// URL or a bare host:port. With maxLen > 0 the stream is trimmed to about
+// maxLen entries (XADD MAXLEN ~). A lost connection is redialed on a later
+// write.
+func NewRedisStreamWriter(addr, stream string, maxLen int64) (*RedisStreamWriter, error) {
+	if stream == "" {
+		return nil, errors.New("speedlog: empty Redis stream key")
+	}
+	hostport, u, err := busAddr(addr, "redis", "6379")
+	if err != nil {
+		return nil, err
+	}
+	w := &RedisStreamWriter{prefix: []string{"XADD", stream}}
+	if maxLen > 0 {
+		w.prefix = append(w.prefix, "MAXLEN", "~", strconv.FormatInt(maxLen, 10))
+	}
+	w.prefix = append(w.prefix, "*", "line")
+	if u.User != nil {
+		if pass, ok := u.User.Password(); ok && u.User.Username() != "" {
+			w.auth = append(w.auth, []string{"AUTH", u.User.Username(), pass})
+		} else if ok {
+			w.auth = append(w.auth, []string{"AUTH", pass})
+		}
+	}
+	if db := strings.Trim(u.Path, "/"); db != "" && db != "0" {
+		if _, err := strconv.Atoi(db); err != nil {
+			return nil, fmt.Errorf("speedlog: invalid Redis database %q", db)
+		}
+		w.auth = append(w.auth, []string{"SELECT", db})
+	}
+	w.r = redialer{network: "tcp", addr: hostport, timeout: busTimeout, setup: w.handshake}
+	if _, err := w.r.get(); err != nil {
+		return nil, err
+	}
+	return w, nil
+}
+
+func (w *RedisStreamWriter) handshake(conn net.Conn) error {
+	w.br = bufio.NewReader(conn)
+	if len(w.auth) == 0 {
+		return nil
+	}
+	var buf []byte
+	for _, cmd := range w.auth {
+		buf = appendRESP(buf, cmd)
+	}
+	if _, err := conn.Write(buf); err != nil {
+		return err
+	}
+	for range w.auth {
+		reply, err := readRESP(w.br)
+		if err != nil {
+			return err
+		}
+		if reply != nil {
+			return reply
+		}
+	}
+	return nil
+}
+
+func appendRESP(buf []byte, args []string, extra ...[]byte) []byte {
+	buf = append(buf, '*')
+	buf = strconv.AppendInt(buf, int64(len(args)+len(extra)), 10)
+	buf = append(buf, "\r\n"...)
+	for _, a := range args {
+		buf = appendBulk(buf, []byte(a))
+	}
+	for _, a := range extra {
+		buf = appendBulk(buf, a)
+	}
+	return buf
+}
+
+func appendBulk(buf, b []byte) []byte {
+	buf = append(buf, '$')
+	buf = strconv.AppendInt(buf, int64(len(b)), 10)
+	buf = append(buf, "\r\n"...)
+	buf = append(buf, b...)
+	return append(buf, "\r\n"...)
+}
+
+// readRESP reads one RESP2 reply. A server error comes back as reply; err
+// means the connection itself is unusable.
+func readRESP(br *bufio.Reader) (reply, err error) {
+	line, err := br.ReadString('\n')
+	if err != nil {
+		return nil, err
+	}
+	line = strings.TrimSuffix(line, "\r\n")
+	if line == "" {
+		return nil, errors.New("speedlog: redis: empty reply")
+	}
+	switch line[0] {
+	case '+', ':':
+		return nil, nil
+	case '-':
+		return fmt.Errorf("speedlog: redis: %s", line[1:]), nil
+	case '$':
+		n, err := strconv.Atoi(line[1:])
+		if err != nil {
+			return nil, fmt.Errorf("speedlog: redis: bad reply %q", line)
+		}
+		if n >= 0 {
+			_, err = br.Discard(n + 2)
+		}
+		return nil, err
+	case '*':
+		n, err := strconv.Atoi(line[1:])
+		if err != nil {
+			return nil, fmt.Errorf("speedlog: redis: bad reply %q", line)
+		}
+		for range n {
+			r, err := readRESP(br)
+			if err != nil {
+				return nil, err
+			}
+			reply = firstErr(reply, r)
+		}
+		return reply, nil
+	}
+	return nil, fmt.Errorf("speedlog: redis: bad reply %q", line)
+}
+
+func firstErr(a, b error) error {
+	if a != nil {
+		return a
+	}
+	return b
+}
+
+func (w *RedisStreamWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.lines.write(p, w.add)
+}
+
+func (w *RedisStreamWriter) add(lines []byte) error {
+	w.buf, w.n = w.buf[:0], 0
+	_ = eachLine(lines, func(line []byte) error {
+		w.buf = appendRESP(w.buf, w.prefix, line)
+		w.n++
+		return nil
+	})
+	if w.n == 0 {
+		return nil
+	}
+	var reply error
+	err := w.r.do(func(conn net.Conn) error {
+		reply = nil
+		_ = conn.SetDeadline(time.Now().Add(busTimeout))
+		defer conn.SetDeadline(time.Time{})
+		if _, err := conn.Write(w.buf); err != nil {
+			return err
+		}
+		for range w.n {
+			r, err := readRESP(w.br)
+			if err != nil {
+				return err
+			}
+			reply = firstErr(reply, r)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	return reply
+}
+
+// Close appends a trailing line that lacked a newline and closes the
+// connection.
+func (w *RedisStreamWriter) Close() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	err := w.lines.flush(w.add)
+	w.r.drop()
+	return err
+}
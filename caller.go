@@ -0,0 +1,148 @@
+package speedlog
+
+import (
+	"path/filepath"
+	"reflect"
+	"runtime"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+// WithCaller adds a caller=file.go:line field to every entry.
+func WithCaller() Option {
+	return func(l *Logger) {
+		l.caller = true
+	}
+}
+
+// WithCallerFunction is WithCaller plus a func field with the calling
+// function as package.Func (or package.(*T).Method), without the import
+// path.
+func WithCallerFunction() Option {
+	return func(l *Logger) {
+		l.caller = true
+		l.callerFunc = true
+	}
+}
+
+// WithCallerSkip skips n more frames above the logging call when working
+// out the caller, for packages that wrap speedlog.
+func WithCallerSkip(n int) Option {
+	return func(l *Logger) {
+		l.skip += n
+	}
+}
+
+// AddCallerSkip returns a handle that skips n more frames than l.
+func (l *Logger) AddCallerSkip(n int) *Logger {
+	c := *l
+	c.skip += n
+	return &c
+}
+
+var (
+	helpers    sync.Map // function entry PC -> struct{}
+	hasHelpers atomic.Bool
+)
+
+// Helper marks the calling function as a logging helper, like
+// testing.T.Helper: its frames are skipped when working out the caller.
+// Call it at the top of the helper.
+func Helper() {
+	var pc [1]uintptr
+	if runtime.Callers(2, pc[:]) == 0 {
+		return
+	}
+	if fn := runtime.FuncForPC(pc[0] - 1); fn != nil {
+		if _, loaded := helpers.LoadOrStore(fn.Entry(), struct{}{}); !loaded {
+			hasHelpers.Store(true)
+		}
+	}
+}
+
+// internalPCs caches whether a program counter belongs to this package, so
+// callerPC only symbolizes each call site once.
+var internalPCs sync.Map
+
+// internalPrefix is this package's import path plus ".", taken from one of
+// its functions so it holds whatever path the module is published under.
+var internalPrefix = strings.TrimSuffix(runtime.FuncForPC(reflect.ValueOf(funcName).Pointer()).Name(), "funcName")
+
+// callerPC returns the first program counter on the stack outside of
+// speedlog itself and any Helper, then skip more.
+func callerPC(skip int) uintptr {
+	var pcs [32]uintptr
+	n := runtime.Callers(2, pcs[:])
+	for _, pc := range pcs[:n] {
+		if isInternal(pc) || hasHelpers.Load() && isHelper(pc) {
+			continue
+		}
+		if skip > 0 {
+			skip--
+			continue
+		}
+		return pc
+	}
+	return 0
+}
+
+// funcEntries caches the function entry PC for each call site PC.
+var funcEntries sync.Map
+
+func isHelper(pc uintptr) bool {
+	entry, ok := funcEntries.Load(pc)
+	if !ok {
+		var e uintptr
+		if fn := runtime.FuncForPC(pc - 1); fn != nil {
+			e = fn.Entry()
+		}
+		entry = e
+		funcEntries.Store(pc, entry)
+	}
+	_, ok = helpers.Load(entry)
+	return ok
+}
+
+func isInternal(pc uintptr) bool {
+	internal, ok := internalPCs.Load(pc)
+	if !ok {
+		name := ""
+		if fn := runtime.FuncForPC(pc - 1); fn != nil {
+			name = fn.Name()
+		}
+		internal = strings.HasPrefix(name, internalPrefix)
+		internalPCs.Store(pc, internal)
+	}
+	return internal.(bool)
+}
+
+func callerLine(pc uintptr) (string, int) {
+	frame := callerFrame(pc)
+	return frame.File, frame.Line
+}
+
+func (l *Logger) appendCaller(e *Entry) {
+	e.PC = callerPC(l.skip)
+	frame := callerFrame(e.PC)
+	fields := append(e.Fields[:len(e.Fields):len(e.Fields)], String("caller", filepath.Base(frame.File)+":"+strconv.Itoa(frame.Line)))
+	if l.callerFunc {
+		fields = append(fields, String("func", funcName(frame.Function)))
+	}
+	e.Fields = fields
+}
+
+func callerFrame(pc uintptr) runtime.Frame {
+	if pc == 0 {
+		return runtime.Frame{File: "???", Function: "???"}
+	}
+	frame, _ := runtime.CallersFrames([]uintptr{pc}).Next()
+	return frame
+}
+
+// funcName trims the import path: example.com/app/store.(*DB).Get becomes
+// store.(*DB).Get.
+func funcName(name string) string {
+	return name[strings.LastIndexByte(name, '/')+1:]
+}
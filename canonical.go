@@ -0,0 +1,114 @@
+package speedlog
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+type canonicalKey struct{}
+
+// Canonical collects fields and counters over a request and logs them as a
+// single line when End is called. All methods are safe for concurrent use
+// and do nothing on a nil *Canonical.
+type Canonical struct {
+	l      *Logger
+	ctx    context.Context
+	start  time.Time
+	mu     sync.Mutex
+	level  int
+	fields []Field
+	counts []Field
+	ended  bool
+}
+
+// StartCanonical begins a canonical log line. Pass c.Context() down the
+// call chain so CanonicalFrom can find it.
+func (l *Logger) StartCanonical(ctx context.Context) *Canonical {
+	c := &Canonical{l: l, start: time.Now(), level: INFO}
+	c.ctx = context.WithValue(ctx, canonicalKey{}, c)
+	return c
+}
+
+func StartCanonical(ctx context.Context) *Canonical { return defaultLogger().StartCanonical(ctx) }
+
+func CanonicalFrom(ctx context.Context) *Canonical {
+	c, _ := ctx.Value(canonicalKey{}).(*Canonical)
+	return c
+}
+
+func (c *Canonical) Context() context.Context {
+	if c == nil {
+		return context.Background()
+	}
+	return c.ctx
+}
+
+// Set adds fields, replacing earlier ones with the same key.
+func (c *Canonical) Set(fields ...Field) {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, f := range flatten(fields) {
+		c.fields = setField(c.fields, f)
+	}
+}
+
+// Count adds delta to the named counter.
+func (c *Canonical) Count(key string, delta int64) {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for i := range c.counts {
+		if c.counts[i].Key == key {
+			c.counts[i].num += delta
+			return
+		}
+	}
+	c.counts = append(c.counts, Int64(key, delta))
+}
+
+// SetLevel raises the level of the final line; it never lowers it.
+func (c *Canonical) SetLevel(level int) {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.level = max(c.level, level)
+}
+
+// End logs msg with every collected field, the counters and the elapsed
+// time. Only the first call logs.
+func (c *Canonical) End(msg string) {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	if c.ended {
+		c.mu.Unlock()
+		return
+	}
+	c.ended = true
+	fields := make([]Field, 0, len(c.fields)+len(c.counts)+1)
+	fields = append(fields, c.fields...)
+	fields = append(fields, c.counts...)
+	fields = append(fields, Duration("duration", time.Since(c.start)))
+	level := c.level
+	c.mu.Unlock()
+	c.l.log(level, msg, fields...)
+}
+
+func setField(fields []Field, f Field) []Field {
+	for i := range fields {
+		if fields[i].Key == f.Key {
+			fields[i] = f
+			return fields
+		}
+	}
+	return append(fields, f)
+}
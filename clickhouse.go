@@ -0,0 +1,203 @@
+package speedlog
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+)
+
+// ClickHouseConfig describes where and how a ClickHouseWriter inserts.
+// Only the HTTP interface is supported; the native protocol would need a
+// client library.
+type ClickHouseConfig struct {
+	URL      string // e.g. http://localhost:8123
+	Database string // optional
+	Table    string
+	User     string
+	Password string
+
+	// Column names; empty ones default to timestamp, level and message.
+	TimeColumn    string
+	LevelColumn   string
+	MessageColumn string
+	// FieldsColumn receives the fields as a JSON object string. When it is
+	// empty, fields become top-level columns of the same name (nested
+	// groups stay objects), and unknown ones are skipped by the server.
+	FieldsColumn string
+
+	// AsyncInsert lets the server buffer small inserts; WaitForAsync makes
+	// each request return only once its data is flushed.
+	AsyncInsert  bool
+	WaitForAsync bool
+
+	// Codec compresses request bodies (the server needs
+	// enable_http_compression); nil sends them as is.
+	Codec Codec
+
+	Client *http.Client // defaults to a client with a 10s timeout
+}
+
+// ClickHouseWriter is a sink that posts each write as one
+// INSERT ... FORMAT JSONEachRow request, so the sink buffer sets the batch
+// size. Lines are parsed back with ParseLine; use JSONEncoder to keep field
+// types.
+type ClickHouseWriter struct {
+	mu     sync.Mutex
+	cfg    ClickHouseConfig
+	url    string
+	client *http.Client
+	body   bytes.Buffer
+	zbody  bytes.Buffer
+	lines  partialLines
+}
+
+func NewClickHouseWriter(cfg ClickHouseConfig) (*ClickHouseWriter, error) {
+	if cfg.URL == "" || !validIdent(cfg.Table) || cfg.Database != "" && !validIdent(cfg.Database) {
+		return nil, errors.New("speedlog: ClickHouse URL and a valid table name are required")
+	}
+	for _, col := range []*string{&cfg.TimeColumn, &cfg.LevelColumn, &cfg.MessageColumn} {
+		if *col != "" && !validIdent(*col) {
+			return nil, fmt.Errorf("speedlog: invalid ClickHouse column %q", *col)
+		}
+	}
+	if cfg.TimeColumn == "" {
+		cfg.TimeColumn = "timestamp"
+	}
+	if cfg.LevelColumn == "" {
+		cfg.LevelColumn = "level"
+	}
+	if cfg.MessageColumn == "" {
+		cfg.MessageColumn = "message"
+	}
+	u, err := url.Parse(cfg.URL)
+	if err != nil {
+		return nil, err
+	}
+	table := cfg.Table
+	if cfg.Database != "" {
+		table = cfg.Database + "." + table
+	}
+	q := u.Query()
+	q.Set("query", "INSERT INTO "+table+" FORMAT JSONEachRow")
+	q.Set("date_time_input_format", "best_effort")
+	q.Set("input_format_skip_unknown_fields", "1")
+	if cfg.AsyncInsert {
+		q.Set("async_insert", "1")
+		if cfg.WaitForAsync {
+			q.Set("wait_for_async_insert", "1")
+		} else {
+			q.Set("wait_for_async_insert", "0")
+		}
+	}
+	u.RawQuery = q.Encode()
+	client := cfg.Client
+	if client == nil {
+		client = &http.Client{Timeout: 10 * time.Second}
+	}
+	return &ClickHouseWriter{cfg: cfg, url: u.String(), client: client}, nil
+}
+
+func (w *ClickHouseWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.lines.write(p, w.insert)
+}
+
+func (w *ClickHouseWriter) insert(lines []byte) error {
+	w.body.Reset()
+	var row []byte
+	for len(lines) > 0 {
+		i := bytes.IndexByte(lines, '\n')
+		line := lines[:i]
+		lines = lines[i+1:]
+		if len(bytes.TrimSpace(line)) == 0 {
+			continue
+		}
+		e, err := ParseLine(line)
+		if err != nil {
+			e = Entry{Time: time.Now(), Level: INFO, Message: string(line)}
+		}
+		row = w.appendRow(row[:0], &e)
+		w.body.Write(row)
+	}
+	if w.body.Len() == 0 {
+		return nil
+	}
+	body := w.body.Bytes()
+	if w.cfg.Codec != nil {
+		var err error
+		if body, err = compressBody(w.cfg.Codec, &w.zbody, body); err != nil {
+			return err
+		}
+	}
+	req, err := http.NewRequest(http.MethodPost, w.url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/x-ndjson")
+	if w.cfg.Codec != nil {
+		req.Header.Set("Content-Encoding", w.cfg.Codec.Name())
+	}
+	if w.cfg.User != "" {
+		req.Header.Set("X-ClickHouse-User", w.cfg.User)
+		req.Header.Set("X-ClickHouse-Key", w.cfg.Password)
+	}
+	resp, err := w.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("speedlog: clickhouse insert: %s: %s", resp.Status, bytes.TrimSpace(msg))
+	}
+	_, _ = io.Copy(io.Discard, resp.Body)
+	return nil
+}
+
+func (w *ClickHouseWriter) appendRow(buf []byte, e *Entry) []byte {
+	buf = append(buf, '{')
+	buf = appendJSONString(buf, w.cfg.TimeColumn)
+	buf = append(buf, `:"`...)
+	buf = e.Time.UTC().AppendFormat(buf, "2006-01-02 15:04:05.000000")
+	buf = append(buf, `",`...)
+	buf = appendJSONString(buf, w.cfg.LevelColumn)
+	buf = append(buf, ':')
+	buf = appendJSONString(buf, LevelName(e.Level))
+	buf = append(buf, ',')
+	buf = appendJSONString(buf, w.cfg.MessageColumn)
+	buf = append(buf, ':')
+	buf = appendJSONString(buf, e.Message)
+	if w.cfg.FieldsColumn != "" {
+		obj := []byte{'{'}
+		for i := range e.Fields {
+			if i > 0 {
+				obj = append(obj, ',')
+			}
+			obj = appendJSONField(obj, &e.Fields[i])
+		}
+		obj = append(obj, '}')
+		buf = append(buf, ',')
+		buf = appendJSONString(buf, w.cfg.FieldsColumn)
+		buf = append(buf, ':')
+		buf = appendJSONString(buf, string(obj))
+	} else {
+		for i := range e.Fields {
+			buf = append(buf, ',')
+			buf = appendJSONField(buf, &e.Fields[i])
+		}
+	}
+	return append(buf, "}\n"...)
+}
+
+// Close inserts a trailing line that lacked a newline.
+func (w *ClickHouseWriter) Close() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.lines.flush(w.insert)
+}
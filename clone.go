@@ -0,0 +1,34 @@
+package speedlog
+
+import "sync/atomic"
+
+// WithFields adds static fields to every entry of the logger. On Clone
+// they are added to the fields it already has.
+func WithFields(fields ...Field) Option {
+	return func(l *Logger) {
+		l.fields = append(l.fields[:len(l.fields):len(l.fields)], flatten(fields)...)
+	}
+}
+
+// Clone returns a logger on the same queue and sinks whose level, encoder
+// and fields can differ. Only WithLevel, WithEncoder and WithFields take
+// effect; pipeline options are ignored. A cloned level is independent of
+// the original's from then on, while With children keep sharing theirs.
+func (l *Logger) Clone(opts ...Option) *Logger {
+	const unset = -1 << 31
+	probe := &Logger{core: &core{}, level: new(atomic.Int32), fields: l.fields}
+	probe.level.Store(unset)
+	for _, opt := range opts {
+		opt(probe)
+	}
+	c := *l
+	c.fields = probe.fields
+	if level := probe.level.Load(); level != unset {
+		c.level = new(atomic.Int32)
+		c.level.Store(level)
+	}
+	if probe.enc != nil {
+		c.enc = probe.enc
+	}
+	return &c
+}
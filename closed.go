@@ -0,0 +1,70 @@
+package speedlog
+
+import (
+	"io"
+	"runtime"
+)
+
+// WithAfterClose sets where entries logged after Close go (default
+// os.Stderr): each is encoded and written in the logging call, one Write
+// per line. nil discards them; either way Stats().AfterClose counts them.
+func WithAfterClose(w io.Writer) Option {
+	return func(l *Logger) {
+		l.lateSet, l.lateW = true, w
+	}
+}
+
+// isClosed reports whether Close has closed done.
+func (l *Logger) isClosed() bool {
+	select {
+	case <-l.done:
+		return true
+	default:
+		return false
+	}
+}
+
+// beginSend admits a producer to the queue, or reports false once Close
+// has started its final drain. Producers bump sending before checking
+// closing and Close sets closing before waiting for sending to reach
+// zero, so every line either lands before that drain or goes to late.
+func (l *Logger) beginSend() bool {
+	l.sending.Add(1)
+	if l.closing.Load() {
+		l.sending.Add(-1)
+		return false
+	}
+	return true
+}
+
+func (l *Logger) endSend() { l.sending.Add(-1) }
+
+// stopSends turns new producers away and waits out those already in;
+// the writer is still running, so a send blocked on a full queue ends.
+func (l *Logger) stopSends() {
+	l.closing.Store(true)
+	for l.sending.Load() != 0 {
+		runtime.Gosched()
+	}
+}
+
+// late writes a line that missed the pipeline because the logger closed.
+func (l *Logger) late(line []byte) {
+	l.lateN.Add(1)
+	if l.lateW == nil {
+		return
+	}
+	l.lateMu.Lock()
+	_, _ = l.lateW.Write(line)
+	l.lateMu.Unlock()
+}
+
+func (l *Logger) lateEntry(e *Entry, raw []byte) {
+	if raw != nil {
+		l.late(raw)
+		return
+	}
+	buf := l.enc.Encode(l.bufPool.get(sizeHint(e)), *e)
+	l.late(buf)
+	l.bufPool.put(buf)
+}
@@ -0,0 +1,109 @@
+package speedlog
+
+import (
+	"bytes"
+	"io"
+	"os/exec"
+	"path/filepath"
+	"sync"
+)
+
+// CmdWriter turns a subprocess's output into one entry per line. Set it
+// as exec.Cmd's Stdout or Stderr: exec copies the pipe into it through
+// ReadFrom, which logs a final line that lacked a newline when the pipe
+// closes, before Wait returns. Used as a plain io.Writer, call Flush at
+// the end instead.
+type CmdWriter struct {
+	l      *Logger
+	cmd    *exec.Cmd
+	level  int
+	stream string
+	mu     sync.Mutex
+	buf    []byte
+}
+
+// maxCmdLine splits runaway lines so one chatty process can't grow the
+// buffer without bound.
+const maxCmdLine = 64 << 10
+
+// CommandWriters returns writers for cmd's stdout and stderr. Entries are
+// logged at level with cmd (the program's base name), pid and stream
+// fields:
+//
+//	out, errw := logger.CommandWriters(cmd, speedlog.INFO)
+//	cmd.Stdout, cmd.Stderr = out, errw
+//	err := cmd.Run()
+//
+// cmd is taken, not just its name, to read the pid once the process has
+// started.
+func (l *Logger) CommandWriters(cmd *exec.Cmd, level int) (stdout, stderr *CmdWriter) {
+	return &CmdWriter{l: l, cmd: cmd, level: level, stream: "stdout"},
+		&CmdWriter{l: l, cmd: cmd, level: level, stream: "stderr"}
+}
+
+func (w *CmdWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	n := len(p)
+	for len(p) > 0 {
+		i := bytes.IndexByte(p, '\n')
+		if i < 0 {
+			w.buf = append(w.buf, p...)
+			for len(w.buf) >= maxCmdLine {
+				w.emit(w.buf[:maxCmdLine])
+				w.buf = append(w.buf[:0], w.buf[maxCmdLine:]...)
+			}
+			break
+		}
+		if len(w.buf) > 0 {
+			w.buf = append(w.buf, p[:i]...)
+			w.emit(w.buf)
+			w.buf = w.buf[:0]
+		} else {
+			w.emit(p[:i])
+		}
+		p = p[i+1:]
+	}
+	return n, nil
+}
+
+// ReadFrom logs the lines read from r until it ends, then any partial
+// line left over.
+func (w *CmdWriter) ReadFrom(r io.Reader) (int64, error) {
+	buf := make([]byte, 32<<10)
+	var n int64
+	for {
+		k, err := r.Read(buf)
+		if k > 0 {
+			_, _ = w.Write(buf[:k])
+			n += int64(k)
+		}
+		if err != nil {
+			w.Flush()
+			if err == io.EOF {
+				err = nil
+			}
+			return n, err
+		}
+	}
+}
+
+// Flush logs any buffered partial line.
+func (w *CmdWriter) Flush() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if len(w.buf) > 0 {
+		w.emit(w.buf)
+		w.buf = w.buf[:0]
+	}
+}
+
+func (w *CmdWriter) emit(line []byte) {
+	line = bytes.TrimSuffix(line, []byte{'\r'})
+	pid := 0
+	// exec sets Process before it starts the goroutines that write here.
+	if w.cmd.Process != nil {
+		pid = w.cmd.Process.Pid
+	}
+	w.l.log(w.level, string(line), String("cmd", filepath.Base(w.cmd.Path)), Int("pid", pid), String("stream", w.stream))
+}
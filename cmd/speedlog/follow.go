@@ -0,0 +1,94 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+	"os"
+	"time"
+)
+
+// eachLine calls fn for every line of the files (or stdin). With follow it
+// keeps polling the last file for new lines, reopening it when it is
+// rotated away or truncated.
+func eachLine(files []string, follow bool, out *bufio.Writer, fn func([]byte) error) error {
+	if len(files) == 0 {
+		return scanLines(os.Stdin, fn)
+	}
+	for i, name := range files {
+		if follow && i == len(files)-1 {
+			return followFile(name, out, fn)
+		}
+		f, err := openLog(name)
+		if err != nil {
+			return err
+		}
+		err = scanLines(f, fn)
+		f.Close()
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func scanLines(r io.Reader, fn func([]byte) error) error {
+	sc := bufio.NewScanner(r)
+	sc.Buffer(make([]byte, 0, 64<<10), 1<<20)
+	for sc.Scan() {
+		if err := fn(sc.Bytes()); err != nil {
+			return err
+		}
+	}
+	return sc.Err()
+}
+
+func followFile(name string, out *bufio.Writer, fn func([]byte) error) error {
+	f, err := os.Open(name)
+	if err != nil {
+		return err
+	}
+	defer func() { f.Close() }()
+	var partial []byte
+	buf := make([]byte, 64<<10)
+	var off int64
+	for {
+		n, err := f.Read(buf)
+		if n > 0 {
+			off += int64(n)
+			data := append(partial, buf[:n]...)
+			for {
+				i := bytes.IndexByte(data, '\n')
+				if i < 0 {
+					break
+				}
+				if err := fn(data[:i]); err != nil {
+					return err
+				}
+				data = data[i+1:]
+			}
+			partial = append(partial[:0], data...)
+			continue
+		}
+		if err != nil && err != io.EOF {
+			return err
+		}
+		if err := out.Flush(); err != nil {
+			return err
+		}
+		time.Sleep(250 * time.Millisecond)
+		cur, serr := f.Stat()
+		fi, perr := os.Stat(name)
+		switch {
+		case perr != nil || serr != nil:
+			// Rotated away and not recreated yet; keep the old handle.
+		case !os.SameFile(cur, fi) || fi.Size() < off:
+			nf, err := os.Open(name)
+			if err != nil {
+				continue
+			}
+			f.Close()
+			f, off, partial = nf, 0, partial[:0]
+		}
+	}
+}
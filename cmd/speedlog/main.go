@@ -0,0 +1,267 @@
+// Command speedlog filters, converts, pretty-prints, verifies and replays
+// speedlog output.
+package main
+
+import (
+	"bufio"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"strings"
+
+	"speedlog"
+)
+
+const usage = `usage: speedlog <command> [flags] [file...]
+
+commands:
+  filter   print matching lines (-level, -field, -match, -expr, -f to follow)
+  convert  rewrite entries as -to text|json
+  pretty   render entries for humans, one field per line
+  verify   check the signature chain of a signed log (-key)
+  replay   re-send WAL or dead-letter files to -to tcp://|udp://|unix://|file (-rate, -rm)
+
+Files default to standard input.
+`
+
+func main() {
+	if len(os.Args) < 2 {
+		fmt.Fprint(os.Stderr, usage)
+		os.Exit(2)
+	}
+	var err error
+	switch cmd, args := os.Args[1], os.Args[2:]; cmd {
+	case "filter", "tail":
+		err = filterCmd(args)
+	case "convert":
+		err = convertCmd(args)
+	case "pretty":
+		err = prettyCmd(args)
+	case "verify":
+		err = verifyCmd(args)
+	case "replay":
+		err = replayCmd(args)
+	case "-h", "-help", "--help", "help":
+		fmt.Print(usage)
+	default:
+		fmt.Fprintf(os.Stderr, "speedlog: unknown command %q\n\n%s", cmd, usage)
+		os.Exit(2)
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "speedlog:", err)
+		os.Exit(1)
+	}
+}
+
+type fieldFlags []string
+
+func (f *fieldFlags) String() string     { return strings.Join(*f, ",") }
+func (f *fieldFlags) Set(v string) error { *f = append(*f, v); return nil }
+
+func filterCmd(args []string) error {
+	fs := flag.NewFlagSet("filter", flag.ExitOnError)
+	level := fs.String("level", "", "minimum level")
+	var fields fieldFlags
+	fs.Var(&fields, "field", "key=value the entry must have (repeatable)")
+	match := fs.String("match", "", "regular expression the line must match")
+	expr := fs.String("expr", "", `routing expression, e.g. 'level >= WARN && fields.component == "db"'`)
+	follow := fs.Bool("f", false, "keep reading as the file grows")
+	_ = fs.Parse(args)
+
+	var preds []speedlog.Predicate
+	if *level != "" {
+		min, err := speedlog.ParseLevel(*level)
+		if err != nil {
+			return err
+		}
+		preds = append(preds, speedlog.LevelRange(min, speedlog.FATAL))
+	}
+	for _, kv := range fields {
+		key, val, ok := strings.Cut(kv, "=")
+		if !ok {
+			return fmt.Errorf("-field %q: want key=value", kv)
+		}
+		p, err := speedlog.Compile("fields." + key + " == " + quote(val))
+		if err != nil {
+			return err
+		}
+		preds = append(preds, p)
+	}
+	if *expr != "" {
+		p, err := speedlog.Compile(*expr)
+		if err != nil {
+			return err
+		}
+		preds = append(preds, p)
+	}
+	var re *regexp.Regexp
+	if *match != "" {
+		var err error
+		if re, err = regexp.Compile(*match); err != nil {
+			return err
+		}
+	}
+	pred := speedlog.And(preds...)
+	out := bufio.NewWriter(os.Stdout)
+	defer out.Flush()
+	return eachLine(fs.Args(), *follow, out, func(line []byte) error {
+		if re != nil && !re.Match(line) {
+			return nil
+		}
+		if len(preds) > 0 {
+			e, err := speedlog.ParseLine(line)
+			if err != nil || !pred(e) {
+				return nil
+			}
+		}
+		_, err := out.Write(append(line, '\n'))
+		return err
+	})
+}
+
+func quote(s string) string { return fmt.Sprintf("%q", s) }
+
+func convertCmd(args []string) error {
+	fs := flag.NewFlagSet("convert", flag.ExitOnError)
+	to := fs.String("to", "json", "output format: text or json")
+	_ = fs.Parse(args)
+	var enc speedlog.Encoder
+	switch *to {
+	case "json":
+		enc = speedlog.JSONEncoder{}
+	case "text":
+		enc = speedlog.TextEncoder{}
+	case "binary":
+		return errors.New("there is no binary format")
+	default:
+		return fmt.Errorf("unknown format %q", *to)
+	}
+	return decodeEach(fs.Args(), func(out *bufio.Writer, e speedlog.Entry) error {
+		_, err := out.Write(enc.Encode(nil, e))
+		return err
+	})
+}
+
+func prettyCmd(args []string) error {
+	fs := flag.NewFlagSet("pretty", flag.ExitOnError)
+	noColor := fs.Bool("no-color", false, "disable colors")
+	_ = fs.Parse(args)
+	enc := speedlog.NewConsoleEncoder(speedlog.ConsoleConfig{NoColor: *noColor})
+	return decodeEach(fs.Args(), func(out *bufio.Writer, e speedlog.Entry) error {
+		fields := e.Fields
+		e.Fields = nil
+		out.Write(enc.Encode(nil, e))
+		writeFields(out, "    ", fields)
+		return nil
+	})
+}
+
+func writeFields(out *bufio.Writer, indent string, fields []speedlog.Field) {
+	for _, f := range fields {
+		if g, ok := f.Value().([]speedlog.Field); ok {
+			fmt.Fprintf(out, "%s%s:\n", indent, f.Key)
+			writeFields(out, indent+"  ", g)
+			continue
+		}
+		v := string(speedlog.AppendValue(nil, f))
+		if s, ok := f.Value().(string); ok && strings.Contains(s, "\n") {
+			v = strings.ReplaceAll(s, "\n", "\n"+indent+"  ")
+		}
+		fmt.Fprintf(out, "%s%s: %s\n", indent, f.Key, v)
+	}
+}
+
+func verifyCmd(args []string) error {
+	fs := flag.NewFlagSet("verify", flag.ExitOnError)
+	key := fs.String("key", "", "HMAC key (empty for plain SHA-256 chains)")
+	keyFile := fs.String("keyfile", "", "read the HMAC key from a file")
+	_ = fs.Parse(args)
+	k := []byte(*key)
+	if *keyFile != "" {
+		b, err := os.ReadFile(*keyFile)
+		if err != nil {
+			return err
+		}
+		k = []byte(strings.TrimRight(string(b), "\r\n"))
+	}
+	return eachFile(fs.Args(), func(name string, r io.Reader) error {
+		if err := speedlog.Verify(r, k); err != nil {
+			return fmt.Errorf("%s: %w", name, err)
+		}
+		fmt.Printf("%s: ok\n", name)
+		return nil
+	})
+}
+
+func decodeEach(files []string, fn func(*bufio.Writer, speedlog.Entry) error) error {
+	out := bufio.NewWriter(os.Stdout)
+	defer out.Flush()
+	return eachFile(files, func(name string, r io.Reader) error {
+		d := speedlog.NewDecoder(r)
+		for {
+			e, err := d.Decode()
+			switch {
+			case err == io.EOF:
+				return nil
+			case errors.Is(err, speedlog.ErrMalformed):
+				fmt.Fprintf(os.Stderr, "%s: %v\n", name, err)
+				continue
+			case err != nil:
+				return fmt.Errorf("%s: %w", name, err)
+			}
+			if err := fn(out, e); err != nil {
+				return err
+			}
+		}
+	})
+}
+
+func eachFile(files []string, fn func(name string, r io.Reader) error) error {
+	if len(files) == 0 {
+		return fn("stdin", os.Stdin)
+	}
+	for _, name := range files {
+		f, err := openLog(name)
+		if err != nil {
+			return err
+		}
+		err = fn(name, f)
+		f.Close()
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+type compressedFile struct {
+	io.ReadCloser
+	f *os.File
+}
+
+func (c compressedFile) Close() error {
+	c.ReadCloser.Close()
+	return c.f.Close()
+}
+
+// openLog opens name, decompressing it when its extension matches a
+// registered codec (rotated files from WithCompression).
+func openLog(name string) (io.ReadCloser, error) {
+	f, err := os.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	c, ok := speedlog.CodecForFile(name)
+	if !ok {
+		return f, nil
+	}
+	zr, err := c.NewReader(f)
+	if err != nil {
+		f.Close()
+		return nil, fmt.Errorf("%s: %w", name, err)
+	}
+	return compressedFile{zr, f}, nil
+}
@@ -0,0 +1,58 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"strings"
+
+	"speedlog"
+)
+
+func replayCmd(args []string) error {
+	fs := flag.NewFlagSet("replay", flag.ExitOnError)
+	to := fs.String("to", "-", "destination: tcp://host:port, udp://host:port, unix:///path, unixgram:///path, a file, or - for stdout")
+	rate := fs.Int("rate", 0, "lines per second (0 = unlimited)")
+	remove := fs.Bool("rm", false, "delete each file once it was sent")
+	_ = fs.Parse(args)
+	if fs.NArg() == 0 {
+		return errors.New("replay needs at least one file")
+	}
+	w, err := openDest(*to)
+	if err != nil {
+		return err
+	}
+	l, err := speedlog.New(speedlog.WithWriter(w))
+	if err != nil {
+		return err
+	}
+	var errs []error
+	for _, name := range fs.Args() {
+		n, err := l.Replay(name, 0, speedlog.ReplayOptions{Rate: *rate, Remove: *remove})
+		fmt.Fprintf(os.Stderr, "%s: %d lines\n", name, n)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", name, err))
+		}
+	}
+	return errors.Join(append(errs, l.Close())...)
+}
+
+func openDest(to string) (io.Writer, error) {
+	scheme, addr, ok := strings.Cut(to, "://")
+	switch {
+	case to == "-":
+		return os.Stdout, nil
+	case !ok:
+		return os.OpenFile(to, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	case scheme == "tcp":
+		return net.Dial("tcp", addr)
+	case scheme == "udp":
+		return speedlog.NewUDPWriter(addr, 0, false)
+	case scheme == "unix" || scheme == "unixgram":
+		return speedlog.NewUnixWriter(addr, scheme == "unixgram")
+	}
+	return nil, fmt.Errorf("unsupported destination %q", to)
+}
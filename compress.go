@@ -0,0 +1,202 @@
+package speedlog
+
+import (
+	"bytes"
+	"compress/gzip"
+	"compress/zlib"
+	"io"
+	"os"
+	"strings"
+	"sync"
+)
+
+// Codec is a compression format, shared by CompressWriter, WithCompression
+// for rotated files, the HTTP sinks and the CLI. gzip, zlib, zstd and
+// snappy are built in; others plug in through RegisterCodec.
+type Codec interface {
+	// Name is the registry key, sent as Content-Encoding by HTTP sinks.
+	Name() string
+	// Ext is the suffix of compressed files, e.g. ".gz".
+	Ext() string
+	// NewWriter compresses into w. A Flush() error method on the result
+	// is called on every sink flush, so readers can decode that far.
+	NewWriter(w io.Writer) (io.WriteCloser, error)
+	NewReader(r io.Reader) (io.ReadCloser, error)
+}
+
+type stdCodec struct {
+	name, ext string
+	level     int
+	writer    func(w io.Writer, level int) (io.WriteCloser, error)
+	reader    func(r io.Reader) (io.ReadCloser, error)
+}
+
+func (c *stdCodec) Name() string { return c.name }
+
+func (c *stdCodec) Ext() string { return c.ext }
+
+func (c *stdCodec) NewWriter(w io.Writer) (io.WriteCloser, error) { return c.writer(w, c.level) }
+
+func (c *stdCodec) NewReader(r io.Reader) (io.ReadCloser, error) { return c.reader(r) }
+
+// Gzip returns the gzip codec at a compress/gzip level.
+func Gzip(level int) Codec {
+	return &stdCodec{
+		name: "gzip", ext: ".gz", level: level,
+		writer: func(w io.Writer, level int) (io.WriteCloser, error) { return gzip.NewWriterLevel(w, level) },
+		reader: func(r io.Reader) (io.ReadCloser, error) { return gzip.NewReader(r) },
+	}
+}
+
+// Zlib returns the zlib codec at a compress/zlib level. Its name is
+// "deflate", the Content-Encoding that means zlib-wrapped data.
+func Zlib(level int) Codec {
+	return &stdCodec{
+		name: "deflate", ext: ".zz", level: level,
+		writer: func(w io.Writer, level int) (io.WriteCloser, error) { return zlib.NewWriterLevel(w, level) },
+		reader: zlib.NewReader,
+	}
+}
+
+var codecs = struct {
+	sync.RWMutex
+	byName map[string]Codec
+}{byName: map[string]Codec{
+	"gzip":    Gzip(gzip.DefaultCompression),
+	"deflate": Zlib(zlib.DefaultCompression),
+	"zstd":    Zstd(),
+	"snappy":  Snappy(),
+}}
+
+// RegisterCodec adds c under its name, replacing a codec of that name
+// (including a built-in, e.g. to change the default gzip level).
+func RegisterCodec(c Codec) {
+	codecs.Lock()
+	defer codecs.Unlock()
+	codecs.byName[c.Name()] = c
+}
+
+func LookupCodec(name string) (Codec, bool) {
+	codecs.RLock()
+	defer codecs.RUnlock()
+	c, ok := codecs.byName[name]
+	return c, ok
+}
+
+// CodecForFile returns the registered codec whose Ext ends path. If
+// several do, the longest extension wins (".tar.gz" over ".gz"), then the
+// first name in sort order.
+func CodecForFile(path string) (Codec, bool) {
+	codecs.RLock()
+	defer codecs.RUnlock()
+	var best Codec
+	for name, c := range codecs.byName {
+		ext := c.Ext()
+		if ext == "" || !strings.HasSuffix(path, ext) {
+			continue
+		}
+		if best == nil || len(ext) > len(best.Ext()) || len(ext) == len(best.Ext()) && name < best.Name() {
+			best = c
+		}
+	}
+	return best, best != nil
+}
+
+type compressWriter struct {
+	w  io.Writer
+	zw io.WriteCloser
+}
+
+// CompressWriter compresses the stream with c; the logger's periodic flush
+// emits a sync point when c's writer can flush, so a reader can decode
+// everything written so far.
+func CompressWriter(w io.Writer, c Codec) (io.WriteCloser, error) {
+	zw, err := c.NewWriter(w)
+	if err != nil {
+		return nil, err
+	}
+	return &compressWriter{w: w, zw: zw}, nil
+}
+
+// GzipWriter is CompressWriter with Gzip(level).
+func GzipWriter(w io.Writer, level int) (io.WriteCloser, error) {
+	return CompressWriter(w, Gzip(level))
+}
+
+func (c *compressWriter) Write(p []byte) (int, error) { return c.zw.Write(p) }
+
+func (c *compressWriter) Flush() error {
+	if f, ok := c.zw.(flusher); ok {
+		if err := f.Flush(); err != nil {
+			return err
+		}
+	}
+	if f, ok := c.w.(flusher); ok {
+		return f.Flush()
+	}
+	return nil
+}
+
+func (c *compressWriter) Close() error {
+	err := c.zw.Close()
+	if cl, ok := c.w.(io.Closer); ok {
+		if cerr := cl.Close(); err == nil {
+			err = cerr
+		}
+	}
+	return err
+}
+
+// compressBody compresses body into buf for an HTTP request.
+func compressBody(c Codec, buf *bytes.Buffer, body []byte) ([]byte, error) {
+	buf.Reset()
+	zw, err := c.NewWriter(buf)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := zw.Write(body); err != nil {
+		return nil, err
+	}
+	if err := zw.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// compressFile writes path through c to path+c.Ext(), syncs it and
+// removes path.
+func compressFile(c Codec, path string) (string, error) {
+	src, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer src.Close()
+	dst := path + c.Ext()
+	f, err := os.OpenFile(dst, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0o644)
+	if err != nil {
+		return "", err
+	}
+	zw, err := c.NewWriter(f)
+	if err == nil {
+		_, err = io.Copy(zw, src)
+		if cerr := zw.Close(); err == nil {
+			err = cerr
+		}
+	}
+	if err == nil {
+		err = f.Sync()
+	}
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		os.Remove(dst)
+		return "", err
+	}
+	return dst, os.Remove(path)
+}
+
+func exists(path string) bool {
+	_, err := os.Lstat(path)
+	return !os.IsNotExist(err)
+}
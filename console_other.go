@@ -0,0 +1,7 @@
+//go:build !windows
+
+package speedlog
+
+import "io"
+
+func enableColor(w io.Writer) bool { return true }
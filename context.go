@@ -0,0 +1,44 @@
+package speedlog
+
+import "context"
+
+type (
+	loggerKey struct{}
+	fieldsKey struct{}
+)
+
+// ContextWith returns a context carrying fields on top of any already in
+// ctx; loggers obtained through Ctx or FromContext attach them. Dropping
+// back to the parent context pops them.
+func ContextWith(ctx context.Context, fields ...Field) context.Context {
+	if len(fields) == 0 {
+		return ctx
+	}
+	prev := ContextFields(ctx)
+	return context.WithValue(ctx, fieldsKey{}, append(prev[:len(prev):len(prev)], flatten(fields)...))
+}
+
+func ContextFields(ctx context.Context) []Field {
+	fields, _ := ctx.Value(fieldsKey{}).([]Field)
+	return fields
+}
+
+// NewContext stores l in ctx for FromContext.
+func NewContext(ctx context.Context, l *Logger) context.Context {
+	return context.WithValue(ctx, loggerKey{}, l)
+}
+
+// FromContext returns the logger stored by NewContext (or the default
+// logger) with the context's fields attached.
+func FromContext(ctx context.Context) *Logger {
+	l, ok := ctx.Value(loggerKey{}).(*Logger)
+	if !ok {
+		l = defaultLogger()
+	}
+	return l.Ctx(ctx)
+}
+
+// Ctx returns l with the fields carried by ctx.
+func (l *Logger) Ctx(ctx context.Context) *Logger {
+	return l.With(ContextFields(ctx)...)
+}
@@ -0,0 +1,177 @@
+package speedlog
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"strings"
+	"sync"
+)
+
+type rotator interface {
+	Rotate() error
+}
+
+// Controller is a running control socket; Close stops it and removes the
+// socket file.
+type Controller struct {
+	l     *Logger
+	ln    net.Listener
+	wg    sync.WaitGroup
+	mu    sync.Mutex
+	conns map[net.Conn]struct{}
+}
+
+// ServeControl listens on a unix socket at path (e.g. "/run/app/speedlog.ctl")
+// for line-based commands:
+//
+//	level            print the current level
+//	level <name>     set the level (debug, info, warn, error, ...)
+//	flush            flush all sinks
+//	rotate           rotate sinks that support it (FileWriter)
+//	stats            print Stats as JSON
+//	tail             reply "ok", then stream every line written until the
+//	                 client disconnects
+//
+// Every reply is one line starting with "ok" or "error".
+func (l *Logger) ServeControl(path string) (*Controller, error) {
+	if fi, err := os.Lstat(path); err == nil && fi.Mode()&os.ModeSocket != 0 {
+		_ = os.Remove(path)
+	}
+	ln, err := net.Listen("unix", path)
+	if err != nil {
+		return nil, err
+	}
+	if err := os.Chmod(path, 0o600); err != nil {
+		_ = ln.Close()
+		return nil, err
+	}
+	c := &Controller{l: l, ln: ln, conns: map[net.Conn]struct{}{}}
+	c.wg.Add(1)
+	go c.accept()
+	return c, nil
+}
+
+func (c *Controller) accept() {
+	defer c.wg.Done()
+	for {
+		conn, err := c.ln.Accept()
+		if err != nil {
+			return
+		}
+		c.mu.Lock()
+		c.conns[conn] = struct{}{}
+		c.mu.Unlock()
+		c.wg.Add(1)
+		go c.serve(conn)
+	}
+}
+
+func (c *Controller) serve(conn net.Conn) {
+	defer c.wg.Done()
+	defer func() {
+		c.mu.Lock()
+		delete(c.conns, conn)
+		c.mu.Unlock()
+		_ = conn.Close()
+	}()
+	sc := bufio.NewScanner(conn)
+	for sc.Scan() {
+		args := strings.Fields(sc.Text())
+		if len(args) == 1 && strings.EqualFold(args[0], "tail") {
+			c.tail(conn)
+			return
+		}
+		reply := c.exec(args)
+		if _, err := io.WriteString(conn, reply+"\n"); err != nil {
+			return
+		}
+	}
+}
+
+// tail streams lines until the client hangs up or the controller closes.
+func (c *Controller) tail(conn net.Conn) {
+	lines, stop := c.l.Tail(0)
+	defer stop()
+	if _, err := io.WriteString(conn, "ok\n"); err != nil {
+		return
+	}
+	gone := make(chan struct{})
+	go func() {
+		_, _ = io.Copy(io.Discard, conn)
+		close(gone)
+	}()
+	for {
+		select {
+		case chunk := <-lines:
+			if _, err := conn.Write(chunk); err != nil {
+				return
+			}
+		case <-gone:
+			return
+		}
+	}
+}
+
+func (c *Controller) exec(args []string) string {
+	if len(args) == 0 {
+		return "error empty command"
+	}
+	switch cmd := strings.ToLower(args[0]); {
+	case cmd == "level" && len(args) == 1:
+		return "ok " + LevelName(c.l.GetLevel())
+	case cmd == "level":
+		level, err := ParseLevel(args[1])
+		if err != nil {
+			return "error " + err.Error()
+		}
+		c.l.SetLevel(level)
+		return "ok " + LevelName(level)
+	case cmd == "flush":
+		c.l.syncAll()
+		return result(c.l.sinkErrs())
+	case cmd == "rotate":
+		return result(c.l.rotate())
+	case cmd == "stats":
+		b, err := json.Marshal(c.l.Stats())
+		if err != nil {
+			return "error " + err.Error()
+		}
+		return "ok " + string(b)
+	}
+	return fmt.Sprintf("error unknown command %q", args[0])
+}
+
+func result(err error) string {
+	if err != nil {
+		return "error " + strings.ReplaceAll(err.Error(), "\n", "; ")
+	}
+	return "ok"
+}
+
+func (l *Logger) rotate() error {
+	var errs []error
+	for _, s := range l.sinkList() {
+		if r, ok := s.w.(rotator); ok {
+			if err := r.Rotate(); err != nil {
+				errs = append(errs, &SinkError{Sink: s.id, Err: err})
+			}
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func (c *Controller) Close() error {
+	err := c.ln.Close()
+	c.mu.Lock()
+	for conn := range c.conns {
+		_ = conn.Close()
+	}
+	c.mu.Unlock()
+	c.wg.Wait()
+	return err
+}
@@ -0,0 +1,133 @@
+package speedlog
+
+import (
+	"slices"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+type counters struct {
+	interval time.Duration
+	mu       sync.Mutex
+	byName   map[string]*atomic.Int64
+	running  atomic.Bool
+}
+
+// WithCounterInterval sets how often Count totals are logged (default 10s).
+func WithCounterInterval(d time.Duration) Option {
+	return func(l *Logger) {
+		if d <= 0 {
+			l.invalid("WithCounterInterval(%v): interval must be positive", d)
+			return
+		}
+		l.counters.interval = d
+	}
+}
+
+// Count adds one to the named counter. Counters are logged together as a
+// single INFO "counters" entry per interval, whatever the level, then
+// reset.
+func (l *Logger) Count(name string) { l.CountN(name, 1) }
+
+func (l *Logger) CountN(name string, n int64) {
+	if l.nop {
+		return
+	}
+	c := &l.counters
+	c.mu.Lock()
+	v, ok := c.byName[name]
+	if !ok {
+		if c.byName == nil {
+			c.byName = map[string]*atomic.Int64{}
+		}
+		v = new(atomic.Int64)
+		c.byName[name] = v
+	}
+	c.mu.Unlock()
+	v.Add(n)
+	if !c.running.Load() && c.running.CompareAndSwap(false, true) {
+		l.startCounters()
+	}
+}
+
+func Count(name string) { defaultLogger().Count(name) }
+
+func CountN(name string, n int64) { defaultLogger().CountN(name, n) }
+
+// startCounters runs the counter loop on the root logger, so no handle's
+// fields or name end up in the "counters" entries. Close's own flush
+// covers whatever is counted after it has started.
+func (l *Logger) startCounters() {
+	l.lifeMu.Lock()
+	defer l.lifeMu.Unlock()
+	if l.closed {
+		return
+	}
+	l.wg.Add(1)
+	go l.root.counterLoop()
+}
+
+// counterLoop exits after an interval with nothing counted, like the idle
+// writer, and the next Count starts it again. Count adds before checking
+// running and the loop clears running before checking the counters, so a
+// count can't be left with no loop to log it.
+func (l *Logger) counterLoop() {
+	defer l.wg.Done()
+	c := &l.counters
+	ticker := time.NewTicker(c.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			if l.flushCounters() {
+				continue
+			}
+			c.running.Store(false)
+			if !c.pending() || !c.running.CompareAndSwap(false, true) {
+				return
+			}
+		case <-l.done:
+			return
+		}
+	}
+}
+
+func (c *counters) pending() bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, v := range c.byName {
+		if v.Load() != 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// flushCounters logs and resets the totals, reporting whether there were
+// any.
+func (l *Logger) flushCounters() bool {
+	c := &l.counters
+	c.mu.Lock()
+	var fields []Field
+	for name, v := range c.byName {
+		if n := v.Swap(0); n != 0 {
+			fields = append(fields, Int64(name, n))
+		}
+	}
+	c.mu.Unlock()
+	if len(fields) == 0 {
+		return false
+	}
+	slices.SortFunc(fields, func(a, b Field) int { return strings.Compare(a.Key, b.Key) })
+	// The entry is INFO but skips the level check, like the level-change
+	// notice: Count calls go through no level, so their totals shouldn't
+	// vanish when the logger is set to WARN.
+	e := l.root.entry(INFO, "counters", fields)
+	if l.hooks != nil && !l.root.runHooks(&e) {
+		return true
+	}
+	l.root.emit(&e)
+	return true
+}
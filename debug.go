@@ -0,0 +1,23 @@
+//go:build !speedlog_nodebug
+
+package speedlog
+
+// DebugEnabled is false in binaries built with the speedlog_nodebug tag.
+// Guarding expensive arguments with it lets the compiler drop them too:
+//
+//	if speedlog.DebugEnabled {
+//		logger.Debugf("state %v", dump())
+//	}
+const DebugEnabled = true
+
+func Debug(msg string) { defaultLogger().log(DEBUG, msg) }
+
+func Debugf(format string, a ...any) { defaultLogger().logf(DEBUG, format, a...) }
+
+func Debugln(a ...any) { defaultLogger().logln(DEBUG, a...) }
+
+func (l *Logger) Debug(msg string) { l.log(DEBUG, msg) }
+
+func (l *Logger) Debugf(format string, a ...any) { l.logf(DEBUG, format, a...) }
+
+func (l *Logger) Debugln(a ...any) { l.logln(DEBUG, a...) }
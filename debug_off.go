@@ -0,0 +1,20 @@
+//go:build speedlog_nodebug
+
+package speedlog
+
+// With speedlog_nodebug the Debug family are empty and inline away;
+// arguments without side effects are then dropped by the compiler as well.
+// Log(DEBUG, ...) is unaffected.
+const DebugEnabled = false
+
+func Debug(string) {}
+
+func Debugf(string, ...any) {}
+
+func Debugln(...any) {}
+
+func (*Logger) Debug(string) {}
+
+func (*Logger) Debugf(string, ...any) {}
+
+func (*Logger) Debugln(...any) {}
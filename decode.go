@@ -0,0 +1,147 @@
+package speedlog
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var ErrMalformed = errors.New("speedlog: malformed log line")
+
+// Decoder reads entries back from speedlog output: TextEncoder or
+// ConsoleEncoder lines (colors are stripped) and JSONEncoder objects,
+// detected per line. There is no binary encoder, so no binary format.
+//
+// Text output is lossy: field values come back as strings, groups as
+// dotted keys, and a message that itself contains " key=value" is split
+// there.
+type Decoder struct {
+	sc *bufio.Scanner
+}
+
+func NewDecoder(r io.Reader) *Decoder {
+	sc := bufio.NewScanner(r)
+	sc.Buffer(make([]byte, 0, 64<<10), 1<<20)
+	return &Decoder{sc: sc}
+}
+
+// Decode returns the next entry, skipping blank lines, and io.EOF at the
+// end. A line that doesn't parse returns an error wrapping ErrMalformed;
+// decoding can continue after it.
+func (d *Decoder) Decode() (Entry, error) {
+	for d.sc.Scan() {
+		line := bytes.TrimSpace(d.sc.Bytes())
+		if len(line) > 0 {
+			return ParseLine(line)
+		}
+	}
+	if err := d.sc.Err(); err != nil {
+		return Entry{}, err
+	}
+	return Entry{}, io.EOF
+}
+
+// ParseLine parses one line of text or JSON output.
+func ParseLine(line []byte) (Entry, error) {
+	line = bytes.TrimSpace(line)
+	if len(line) > 0 && line[0] == '{' {
+		level, msg, t, fields, ok := parseJSONLine(line, -1)
+		if !ok || level < 0 {
+			return Entry{}, fmt.Errorf("%w: %.40q", ErrMalformed, line)
+		}
+		return Entry{Time: t, Level: level, Message: msg, Fields: fields}, nil
+	}
+	return parseTextLine(string(stripANSI(line)))
+}
+
+func stripANSI(b []byte) []byte {
+	if bytes.IndexByte(b, 0x1b) < 0 {
+		return b
+	}
+	out := make([]byte, 0, len(b))
+	for i := 0; i < len(b); i++ {
+		if b[i] == 0x1b && i+1 < len(b) && b[i+1] == '[' {
+			j := i + 2
+			for j < len(b) && (b[j] < '@' || b[j] > '~') {
+				j++
+			}
+			i = j
+			continue
+		}
+		out = append(out, b[i])
+	}
+	return out
+}
+
+func parseTextLine(s string) (Entry, error) {
+	bad := fmt.Errorf("%w: %.40q", ErrMalformed, s)
+	if len(s) < len(timeLayout)+2 {
+		return Entry{}, bad
+	}
+	t, err := time.ParseInLocation(timeLayout, s[:len(timeLayout)], time.Local)
+	if err != nil || s[len(timeLayout)] != ' ' {
+		return Entry{}, bad
+	}
+	rest := s[len(timeLayout)+1:]
+	name, rest, _ := strings.Cut(rest, " ")
+	level, ok := parseLevelName(name)
+	if !ok {
+		return Entry{}, bad
+	}
+	e := Entry{Time: t, Level: level, Message: rest}
+	// The message ends where the rest of the line first parses as fields.
+	for i := 0; i <= len(rest); i++ {
+		if i > 0 && (i == len(rest) || rest[i] != ' ') {
+			continue
+		}
+		if fields, ok := parseTextFields(rest[i:]); ok {
+			e.Message, e.Fields = rest[:i], fields
+			break
+		}
+	}
+	return e, nil
+}
+
+// parseTextFields parses a sequence of " key=value", values either bare or
+// strconv-quoted as appendText writes them.
+func parseTextFields(s string) ([]Field, bool) {
+	var fields []Field
+	for s != "" {
+		if s[0] != ' ' {
+			return nil, false
+		}
+		s = s[1:]
+		eq := strings.IndexByte(s, '=')
+		if eq <= 0 || strings.ContainsAny(s[:eq], " \"") {
+			return nil, false
+		}
+		key := s[:eq]
+		s = s[eq+1:]
+		var val string
+		if strings.HasPrefix(s, `"`) {
+			q, err := strconv.QuotedPrefix(s)
+			if err != nil {
+				return nil, false
+			}
+			val, _ = strconv.Unquote(q)
+			s = s[len(q):]
+		} else {
+			end := strings.IndexByte(s, ' ')
+			if end < 0 {
+				end = len(s)
+			}
+			val = s[:end]
+			if strings.ContainsAny(val, `="`) {
+				return nil, false
+			}
+			s = s[end:]
+		}
+		fields = append(fields, String(key, val))
+	}
+	return fields, true
+}
@@ -0,0 +1,55 @@
+package speedlog
+
+import "sync"
+
+// deferBuf holds a request's DEBUG/INFO entries until it either logs an
+// ERROR (they are written out, original timestamps and all) or is dropped.
+type deferBuf struct {
+	mu        sync.Mutex
+	max       int
+	entries   []Entry
+	triggered bool
+}
+
+// Deferred returns a logger that keeps up to max DEBUG and INFO entries in
+// memory, regardless of the level setting, and writes them only if an ERROR
+// or worse is logged through it (or a With child of it). After that it
+// passes everything straight through. Use one per request.
+func (l *Logger) Deferred(max int) *Logger {
+	if max <= 0 {
+		max = 256
+	}
+	c := *l
+	c.deferred = &deferBuf{max: max}
+	return &c
+}
+
+func (d *deferBuf) holds(level int) bool { return level < WARN }
+
+// admit reports whether e should be written now, replaying the held
+// entries first when e is the trigger.
+func (d *deferBuf) admit(l *Logger, e *Entry) bool {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	switch {
+	case d.triggered:
+		return true
+	case d.holds(e.Level):
+		if len(d.entries) == d.max {
+			copy(d.entries, d.entries[1:])
+			d.entries = d.entries[:d.max-1]
+		}
+		held := *e
+		held.Fields = append([]Field(nil), e.Fields...)
+		d.entries = append(d.entries, held)
+		return false
+	case e.Level >= ERROR:
+		d.triggered = true
+		for i := range d.entries {
+			l.emit(&d.entries[i])
+		}
+		d.entries = nil
+		return true
+	}
+	return l.IsLevelEnabled(e.Level)
+}
@@ -0,0 +1,95 @@
+package speedlog
+
+import (
+	"io"
+	"os"
+	"sync/atomic"
+	"time"
+)
+
+// fallback copies WARN and above to stderr while no sink is usable.
+type fallback struct {
+	w       io.Writer
+	after   time.Duration
+	since   atomic.Int64 // start of the current outage
+	active  atomic.Bool
+	started atomic.Int64
+	copied  atomic.Uint64
+}
+
+// WithStderrFallback watches for every sink failing (last write failed, or
+// ejected) or the writer being stuck in a write, for at least after. Until
+// a sink succeeds again, WARN and above are also written straight to
+// stderr by the logging call, so an outage of the logging backend doesn't
+// hide the errors it causes. A notice is written to stderr when this
+// starts, and a WARN entry through the sinks when they recover.
+func WithStderrFallback(after time.Duration) Option {
+	return func(l *Logger) {
+		if after <= 0 {
+			l.invalid("WithStderrFallback(%v): duration must be positive", after)
+			return
+		}
+		l.fallback = &fallback{w: os.Stderr, after: after}
+	}
+}
+
+// sinksDown reports whether no sink can currently take a write.
+func (l *Logger) sinksDown(now int64) bool {
+	if busy := l.busySince.Load(); busy != 0 && time.Duration(now-busy) >= l.fallback.after {
+		return true
+	}
+	sinks := l.sinkList()
+	for _, s := range sinks {
+		if s.w == l.fallback.w || !s.ejected.Load() && s.failing.Load() == nil {
+			return false
+		}
+	}
+	return len(sinks) > 0
+}
+
+// degraded moves the fallback state and reports whether it is on. It runs
+// on WARN+ entries and on every writer tick, so recovery is noticed even
+// when nothing is logged.
+func (l *Logger) degraded() bool {
+	fb := l.fallback
+	now := time.Now().UnixNano()
+	if !l.sinksDown(now) {
+		fb.since.Store(0)
+		if fb.active.CompareAndSwap(true, false) {
+			go l.fallbackRecovered()
+		}
+		return false
+	}
+	fb.since.CompareAndSwap(0, now)
+	if time.Duration(now-fb.since.Load()) >= fb.after && fb.active.CompareAndSwap(false, true) {
+		fb.started.Store(now)
+		l.fallbackNotice(Entry{Time: time.Now(), Level: WARN, Message: "all sinks failing, copying WARN and above to stderr"})
+	}
+	return fb.active.Load()
+}
+
+func (l *Logger) fallbackNotice(e Entry) {
+	buf := l.enc.Encode(l.bufPool.get(sizeHint(&e)), e)
+	_, _ = l.fallback.w.Write(buf)
+	l.bufPool.put(buf)
+}
+
+func (l *Logger) toStderr(e *Entry, raw []byte) {
+	l.fallback.copied.Add(1)
+	if raw != nil {
+		_, _ = l.fallback.w.Write(raw)
+		return
+	}
+	l.fallbackNotice(*e)
+}
+
+func (l *Logger) fallbackRecovered() {
+	fb := l.fallback
+	fields := []Field{
+		Uint64("copied", fb.copied.Swap(0)),
+		Duration("duration", time.Since(time.Unix(0, fb.started.Load())).Round(time.Millisecond)),
+	}
+	l.fallbackNotice(Entry{Time: time.Now(), Level: WARN, Message: "sinks recovered, stopped copying to stderr", Fields: fields})
+	// The sinks missed the outage; tell them once where its entries went.
+	l.root.log(WARN, "sinks were down, WARN and above went to stderr", fields...)
+}
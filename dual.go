@@ -0,0 +1,41 @@
+package speedlog
+
+import "slices"
+
+// DualEncoder gives one sink two formats: entries below Level get the
+// compact Short form and the rest the detailed Full one, so routine
+// traffic stays small while warnings carry everything. For example,
+//
+//	DualEncoder{Level: WARN, ShortFields: []string{"req_id"},
+//		Full: NewConsoleEncoder(ConsoleConfig{Expand: true})}
+//
+// with WithStacktrace(WARN) writes INFO as one line with only req_id and
+// WARN and above with all fields and the stack below. Nil encoders mean
+// TextEncoder{}.
+type DualEncoder struct {
+	Level       int
+	Short, Full Encoder
+	// ShortFields, when not nil, are the only top-level field keys Short
+	// sees.
+	ShortFields []string
+}
+
+func (d DualEncoder) Encode(buf []byte, e Entry) []byte {
+	enc := d.Full
+	if e.Level < d.Level {
+		enc = d.Short
+		if d.ShortFields != nil {
+			kept := make([]Field, 0, len(d.ShortFields))
+			for _, f := range e.Fields {
+				if slices.Contains(d.ShortFields, f.Key) {
+					kept = append(kept, f)
+				}
+			}
+			e.Fields = kept
+		}
+	}
+	if enc == nil {
+		enc = TextEncoder{}
+	}
+	return enc.Encode(buf, e)
+}
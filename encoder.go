@@ -0,0 +1,263 @@
+package speedlog
+
+import (
+	"fmt"
+	"reflect"
+	"runtime"
+	"strings"
+	"time"
+)
+
+// Entry is what encoders and routing predicates see: the entry before it
+// is rendered to bytes.
+type Entry struct {
+	Time    time.Time
+	Level   int
+	Message string
+	Fields  []Field
+
+	// PC is the logging call's program counter under WithCaller, 0
+	// otherwise; see Frame.
+	PC uintptr
+
+	// ts is the cached rendering of Time in timeLayout, when there is one.
+	ts []byte
+}
+
+// Frame resolves PC, for encoders that lay out the caller themselves
+// instead of using the caller field.
+func (e Entry) Frame() runtime.Frame { return callerFrame(e.PC) }
+
+type Encoder interface {
+	Encode(buf []byte, e Entry) []byte
+}
+
+func WithEncoder(enc Encoder) Option {
+	return func(l *Logger) {
+		switch {
+		case enc == nil:
+			l.invalid("WithEncoder(nil)")
+		case l.encSet:
+			l.invalid("WithEncoder given more than once")
+		default:
+			l.enc, l.encSet = enc, true
+		}
+	}
+}
+
+func LevelName(level int) string {
+	if level >= 0 && level < len(levelNames) {
+		return levelNames[level]
+	}
+	return "UNK"
+}
+
+// ParseLevel accepts level names case-insensitively ("warning" too).
+func ParseLevel(name string) (int, error) {
+	name = strings.ToUpper(name)
+	if name == "WARNING" {
+		return WARN, nil
+	}
+	for level, n := range levelNames {
+		if n == name {
+			return level, nil
+		}
+	}
+	return 0, fmt.Errorf("speedlog: unknown level %q", name)
+}
+
+func appendTime(buf []byte, e *Entry) []byte {
+	if e.ts != nil {
+		return append(buf, e.ts...)
+	}
+	return e.Time.AppendFormat(buf, timeLayout)
+}
+
+// levelName looks level up in an encoder's LevelNames before falling back
+// to the default name.
+func levelName(names map[int]string, level int) string {
+	if name, ok := names[level]; ok {
+		return name
+	}
+	return LevelName(level)
+}
+
+// LowercaseLevelNames returns "debug", "info", ... for an encoder's
+// LevelNames.
+func LowercaseLevelNames() map[int]string {
+	names := make(map[int]string, len(levelNames))
+	for level, name := range levelNames {
+		names[level] = strings.ToLower(name)
+	}
+	return names
+}
+
+// TextEncoder is the default "2006-01-02 15:04:05.000 LEVEL message" format.
+type TextEncoder struct {
+	// LevelNames overrides the rendered name per level, e.g.
+	// {WARN: "WARNING"}.
+	LevelNames map[int]string
+}
+
+func (t TextEncoder) Encode(buf []byte, e Entry) []byte {
+	buf = appendTime(buf, &e)
+	buf = append(buf, ' ')
+	buf = append(buf, levelName(t.LevelNames, e.Level)...)
+	buf = append(buf, ' ')
+	buf = append(buf, e.Message...)
+	buf = appendFields(buf, e.Fields)
+	return append(buf, '\n')
+}
+
+// LevelStyle overrides how one level is rendered. Color is an ANSI SGR
+// parameter list such as "33" (yellow) or "1;31" (bold red). Empty fields
+// keep the default.
+type LevelStyle struct {
+	Name  string
+	Color string
+}
+
+type ConsoleConfig struct {
+	Levels  map[int]LevelStyle
+	NoColor bool
+	// Expand moves wrapped errors (with a "caused by" line per layer) and
+	// multi-line strings such as stack traces out of the line into
+	// indented blocks below it. Decoder can't read expanded output back.
+	Expand bool
+}
+
+type ConsoleEncoder struct {
+	cfg    ConsoleConfig
+	levels [][]byte
+	extra  map[int][]byte
+	unk    []byte
+}
+
+var defaultLevelColors = map[int]string{
+	DEBUG: "90",
+	INFO:  "36",
+	WARN:  "33",
+	ERROR: "31",
+	PANIC: "1;31",
+	FATAL: "1;31",
+}
+
+func NewConsoleEncoder(cfg ConsoleConfig) *ConsoleEncoder {
+	c := &ConsoleEncoder{cfg: cfg, levels: make([][]byte, len(levelNames)), extra: map[int][]byte{}}
+	render := func(name, color string) []byte {
+		if cfg.NoColor || color == "" {
+			return []byte(name)
+		}
+		return []byte("\x1b[" + color + "m" + name + "\x1b[0m")
+	}
+	for level := range levelNames {
+		style := LevelStyle{Name: levelNames[level], Color: defaultLevelColors[level]}
+		if o, ok := cfg.Levels[level]; ok {
+			if o.Name != "" {
+				style.Name = o.Name
+			}
+			if o.Color != "" {
+				style.Color = o.Color
+			}
+		}
+		c.levels[level] = render(style.Name, style.Color)
+	}
+	for level, o := range cfg.Levels {
+		if level < 0 || level >= len(levelNames) {
+			c.extra[level] = render(o.Name, o.Color)
+		}
+	}
+	c.unk = render("UNK", "")
+	return c
+}
+
+func (c *ConsoleEncoder) Encode(buf []byte, e Entry) []byte {
+	buf = appendTime(buf, &e)
+	buf = append(buf, ' ')
+	switch name, ok := c.extra[e.Level]; {
+	case e.Level >= 0 && e.Level < len(c.levels):
+		buf = append(buf, c.levels[e.Level]...)
+	case ok:
+		buf = append(buf, name...)
+	default:
+		buf = append(buf, c.unk...)
+	}
+	buf = append(buf, ' ')
+	buf = append(buf, e.Message...)
+	if !c.cfg.Expand {
+		buf = appendFields(buf, e.Fields)
+		return append(buf, '\n')
+	}
+	blocks := 0
+	for i := range e.Fields {
+		if isBlock(&e.Fields[i]) {
+			blocks++
+		} else {
+			buf = appendFields(buf, e.Fields[i:i+1])
+		}
+	}
+	for i := 0; blocks > 0; i++ {
+		f := &e.Fields[i]
+		if !isBlock(f) {
+			continue
+		}
+		blocks--
+		buf = append(buf, "\n    "...)
+		buf = append(buf, f.Key...)
+		buf = append(buf, ':')
+		if f.kind == stringKind {
+			for line := range strings.Lines(f.str) {
+				buf = append(buf, "\n      "...)
+				buf = append(buf, strings.TrimSuffix(line, "\n")...)
+			}
+			continue
+		}
+		// errors.Join's message is its branches' joined by newlines, and
+		// those get a line each anyway.
+		if _, joined := f.val.(interface{ Unwrap() []error }); !joined {
+			buf = append(buf, ' ')
+			buf = append(buf, f.val.(error).Error()...)
+		}
+		buf, _ = appendCauseLines(buf, f.val.(error), 0)
+	}
+	return append(buf, '\n')
+}
+
+// isBlock reports whether Expand renders f below the line.
+func isBlock(f *Field) bool {
+	switch f.kind {
+	case stringKind:
+		return strings.IndexByte(f.str, '\n') >= 0
+	case errorKind:
+		switch f.val.(type) {
+		case interface{ Unwrap() error }, interface{ Unwrap() []error }:
+			return true
+		}
+	}
+	return false
+}
+
+// appendCauseLines writes a "caused by" line for every error wrapped by
+// err, depth-first and with the same 32-layer cap as appendCauses.
+func appendCauseLines(buf []byte, err error, n int) ([]byte, int) {
+	var next []error
+	switch u := err.(type) {
+	case interface{ Unwrap() error }:
+		next = []error{u.Unwrap()}
+	case interface{ Unwrap() []error }:
+		next = u.Unwrap()
+	}
+	for _, e := range next {
+		if e == nil || n >= 32 {
+			continue
+		}
+		n++
+		buf = append(buf, "\n      caused by: "...)
+		buf = append(buf, e.Error()...)
+		buf = append(buf, " ("...)
+		buf = append(buf, reflect.TypeOf(e).String()...)
+		buf = append(buf, ')')
+		buf, n = appendCauseLines(buf, e, n)
+	}
+	return buf, n
+}
@@ -0,0 +1,175 @@
+package speedlog
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/binary"
+	"errors"
+	"io"
+)
+
+const maxFrameSize = 64 << 20
+
+var (
+	ErrFrameTooLarge = errors.New("speedlog: encrypted frame too large")
+	// ErrFrameOrder means frames were removed, reordered or repeated.
+	ErrFrameOrder = errors.New("speedlog: encrypted frame out of sequence")
+)
+
+// seqSize is the authenticated frame header: a random stream ID, one per
+// EncryptWriter, and the frame's number within the stream.
+const seqSize = 16
+
+type encryptWriter struct {
+	w      io.Writer
+	aead   cipher.AEAD
+	buf    []byte
+	stream [8]byte
+	n      uint64
+}
+
+func newAEAD(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// EncryptWriter seals every Write into its own frame: 4-byte big-endian
+// length, 8-byte stream ID, 8-byte frame number, 12-byte nonce, AES-GCM
+// ciphertext. Stream ID and frame number are authenticated as additional
+// data, so DecryptReader notices frames that were dropped, reordered or
+// replayed, also across writers appending to the same file.
+func EncryptWriter(w io.Writer, key []byte) (io.WriteCloser, error) {
+	aead, err := newAEAD(key)
+	if err != nil {
+		return nil, err
+	}
+	e := &encryptWriter{w: w, aead: aead}
+	if _, err := rand.Read(e.stream[:]); err != nil {
+		return nil, err
+	}
+	return e, nil
+}
+
+func (e *encryptWriter) Write(p []byte) (int, error) {
+	if len(p) == 0 {
+		return 0, nil
+	}
+	ns := e.aead.NonceSize()
+	size := seqSize + ns + len(p) + e.aead.Overhead()
+	if size > maxFrameSize {
+		return 0, ErrFrameTooLarge
+	}
+	buf := e.buf[:0]
+	buf = binary.BigEndian.AppendUint32(buf, uint32(size))
+	buf = append(buf, e.stream[:]...)
+	buf = binary.BigEndian.AppendUint64(buf, e.n)
+	buf = append(buf, make([]byte, ns)...)
+	seq, nonce := buf[4:4+seqSize], buf[4+seqSize:]
+	if _, err := rand.Read(nonce); err != nil {
+		return 0, err
+	}
+	buf = e.aead.Seal(buf, nonce, p, seq)
+	e.buf = buf
+	if _, err := e.w.Write(buf); err != nil {
+		return 0, err
+	}
+	e.n++
+	return len(p), nil
+}
+
+func (e *encryptWriter) Close() error {
+	if c, ok := e.w.(io.Closer); ok {
+		return c.Close()
+	}
+	return nil
+}
+
+type decryptReader struct {
+	r      io.Reader
+	aead   cipher.AEAD
+	buf    []byte
+	out    []byte
+	stream [8]byte
+	want   uint64 // next frame number of stream
+	seen   map[[8]byte]bool
+}
+
+// DecryptReader returns the plaintext of EncryptWriter frames. Each stream
+// must start at frame 0 and continue without gaps, and a finished stream
+// can't come back; otherwise Read fails with ErrFrameOrder. Frames missing
+// from the end of a stream can't be told from a writer that stopped
+// there.
+func DecryptReader(r io.Reader, key []byte) (io.Reader, error) {
+	aead, err := newAEAD(key)
+	if err != nil {
+		return nil, err
+	}
+	return &decryptReader{r: r, aead: aead}, nil
+}
+
+func (d *decryptReader) Read(p []byte) (int, error) {
+	for len(d.out) == 0 {
+		if err := d.next(); err != nil {
+			return 0, err
+		}
+	}
+	n := copy(p, d.out)
+	d.out = d.out[n:]
+	return n, nil
+}
+
+func (d *decryptReader) next() error {
+	var hdr [4]byte
+	if _, err := io.ReadFull(d.r, hdr[:]); err != nil {
+		return err
+	}
+	size := int(binary.BigEndian.Uint32(hdr[:]))
+	ns := d.aead.NonceSize()
+	if size > maxFrameSize {
+		return ErrFrameTooLarge
+	}
+	if size < seqSize+ns+d.aead.Overhead() {
+		return errors.New("speedlog: encrypted frame truncated")
+	}
+	if cap(d.buf) < size {
+		d.buf = make([]byte, size)
+	}
+	frame := d.buf[:size]
+	if _, err := io.ReadFull(d.r, frame); err != nil {
+		if err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+		return err
+	}
+	seq, nonce, sealed := frame[:seqSize], frame[seqSize:seqSize+ns], frame[seqSize+ns:]
+	out, err := d.aead.Open(sealed[:0], nonce, sealed, seq)
+	if err != nil {
+		return err
+	}
+	var stream [8]byte
+	copy(stream[:], seq)
+	switch n := binary.BigEndian.Uint64(seq[8:]); {
+	case n == 0 && !d.seen[stream]:
+		if d.seen == nil {
+			d.seen = map[[8]byte]bool{}
+		}
+		d.seen[stream] = true
+		d.stream, d.want = stream, 0
+	case n != d.want || stream != d.stream:
+		return ErrFrameOrder
+	}
+	d.want++
+	d.out = out
+	return nil
+}
+
+func (e *encryptWriter) Flush() error {
+	if f, ok := e.w.(flusher); ok {
+		return f.Flush()
+	}
+	return nil
+}
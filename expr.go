@@ -0,0 +1,349 @@
+package speedlog
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// Compile parses a routing expression into a Predicate:
+//
+//	level >= WARN && fields.component == "billing"
+//	!(logger == "db") || fields.http.status >= 500
+//
+// Operands are level, msg, logger, fields.<key> (dots reach into groups),
+// level names, numbers, quoted strings, true and false. A bare operand is
+// true when it exists and isn't false or zero. Comparisons are numeric
+// when both sides are numbers and textual otherwise; any comparison with
+// a missing field is false.
+func Compile(src string) (Predicate, error) {
+	p := &exprParser{src: src}
+	p.next()
+	e, err := p.or()
+	if err == nil && p.err != nil {
+		err = p.err
+	}
+	if err == nil && p.tok.kind != tokEOF {
+		err = p.errorf("unexpected %q", p.tok.text)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return func(en Entry) bool { return truthy(e(&en)) }, nil
+}
+
+type tokKind int
+
+const (
+	tokEOF tokKind = iota
+	tokIdent
+	tokNumber
+	tokString
+	tokOp
+)
+
+type token struct {
+	kind tokKind
+	text string
+	pos  int
+}
+
+type exprParser struct {
+	src string
+	off int
+	tok token
+	err error
+}
+
+// value is an operand at evaluation time; ok is false for a missing
+// field.
+type value struct {
+	num   float64
+	str   string
+	isNum bool
+	ok    bool
+}
+
+type evalFunc func(e *Entry) value
+
+func (p *exprParser) errorf(format string, args ...any) error {
+	return fmt.Errorf("speedlog: expression at %d: %s", p.tok.pos, fmt.Sprintf(format, args...))
+}
+
+func (p *exprParser) next() {
+	for p.off < len(p.src) && (p.src[p.off] == ' ' || p.src[p.off] == '\t' || p.src[p.off] == '\n') {
+		p.off++
+	}
+	start := p.off
+	if p.off == len(p.src) {
+		p.tok = token{kind: tokEOF, pos: start}
+		return
+	}
+	c := p.src[p.off]
+	switch {
+	case c == '"':
+		end := p.off + 1
+		for end < len(p.src) && p.src[end] != '"' {
+			if p.src[end] == '\\' {
+				end++
+			}
+			end++
+		}
+		if end >= len(p.src) {
+			p.tok = token{kind: tokString, pos: start}
+			p.err = fmt.Errorf("speedlog: expression at %d: unterminated string", start)
+			p.off = len(p.src)
+			return
+		}
+		s, err := strconv.Unquote(p.src[p.off : end+1])
+		if err != nil {
+			p.err = fmt.Errorf("speedlog: expression at %d: %v", start, err)
+		}
+		p.off = end + 1
+		p.tok = token{kind: tokString, text: s, pos: start}
+	case c >= '0' && c <= '9' || c == '-' && p.off+1 < len(p.src) && p.src[p.off+1] >= '0' && p.src[p.off+1] <= '9':
+		p.off++
+		for p.off < len(p.src) && (p.src[p.off] >= '0' && p.src[p.off] <= '9' || p.src[p.off] == '.') {
+			p.off++
+		}
+		p.tok = token{kind: tokNumber, text: p.src[start:p.off], pos: start}
+	case c == '_' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z':
+		for p.off < len(p.src) {
+			c := p.src[p.off]
+			if c != '_' && c != '.' && c != '-' && (c < 'a' || c > 'z') && (c < 'A' || c > 'Z') && (c < '0' || c > '9') {
+				break
+			}
+			p.off++
+		}
+		p.tok = token{kind: tokIdent, text: p.src[start:p.off], pos: start}
+	default:
+		for _, op := range []string{"&&", "||", "==", "!=", ">=", "<=", ">", "<", "!", "(", ")"} {
+			if strings.HasPrefix(p.src[p.off:], op) {
+				p.off += len(op)
+				p.tok = token{kind: tokOp, text: op, pos: start}
+				return
+			}
+		}
+		p.tok = token{kind: tokOp, text: string(c), pos: start}
+		p.err = fmt.Errorf("speedlog: expression at %d: unexpected %q", start, c)
+		p.off = len(p.src)
+	}
+}
+
+func (p *exprParser) isOp(op string) bool { return p.tok.kind == tokOp && p.tok.text == op }
+
+func (p *exprParser) or() (evalFunc, error) {
+	l, err := p.and()
+	for err == nil && p.isOp("||") {
+		p.next()
+		var r evalFunc
+		if r, err = p.and(); err == nil {
+			a, b := l, r
+			l = func(e *Entry) value { return boolValue(truthy(a(e)) || truthy(b(e))) }
+		}
+	}
+	return l, err
+}
+
+func (p *exprParser) and() (evalFunc, error) {
+	l, err := p.unary()
+	for err == nil && p.isOp("&&") {
+		p.next()
+		var r evalFunc
+		if r, err = p.unary(); err == nil {
+			a, b := l, r
+			l = func(e *Entry) value { return boolValue(truthy(a(e)) && truthy(b(e))) }
+		}
+	}
+	return l, err
+}
+
+func (p *exprParser) unary() (evalFunc, error) {
+	if p.isOp("!") {
+		p.next()
+		x, err := p.unary()
+		if err != nil {
+			return nil, err
+		}
+		return func(e *Entry) value { return boolValue(!truthy(x(e))) }, nil
+	}
+	l, err := p.operand()
+	if err != nil {
+		return nil, err
+	}
+	if p.tok.kind != tokOp {
+		return l, nil
+	}
+	op := p.tok.text
+	switch op {
+	case "==", "!=", "<", "<=", ">", ">=":
+	default:
+		return l, nil
+	}
+	p.next()
+	r, err := p.operand()
+	if err != nil {
+		return nil, err
+	}
+	return func(e *Entry) value { return boolValue(compare(l(e), op, r(e))) }, nil
+}
+
+func (p *exprParser) operand() (evalFunc, error) {
+	if p.err != nil {
+		return nil, p.err
+	}
+	tok := p.tok
+	switch tok.kind {
+	case tokOp:
+		if tok.text != "(" {
+			return nil, p.errorf("unexpected %q", tok.text)
+		}
+		p.next()
+		x, err := p.or()
+		if err != nil {
+			return nil, err
+		}
+		if !p.isOp(")") {
+			return nil, p.errorf("missing )")
+		}
+		p.next()
+		return x, nil
+	case tokNumber:
+		n, err := strconv.ParseFloat(tok.text, 64)
+		if err != nil {
+			return nil, p.errorf("bad number %q", tok.text)
+		}
+		p.next()
+		v := numValue(n)
+		return func(*Entry) value { return v }, nil
+	case tokString:
+		p.next()
+		v := value{str: tok.text, ok: true}
+		return func(*Entry) value { return v }, nil
+	case tokEOF:
+		return nil, p.errorf("unexpected end of expression")
+	}
+	p.next()
+	switch name := tok.text; {
+	case name == "level":
+		return func(e *Entry) value { return numValue(float64(e.Level)) }, nil
+	case name == "msg":
+		return func(e *Entry) value { return value{str: e.Message, ok: true} }, nil
+	case name == "logger":
+		return fieldOperand([]string{"logger"}), nil
+	case name == "true" || name == "false":
+		v := boolValue(name == "true")
+		return func(*Entry) value { return v }, nil
+	case strings.HasPrefix(name, "fields.") && len(name) > len("fields."):
+		return fieldOperand(strings.Split(name[len("fields."):], ".")), nil
+	}
+	if level, err := ParseLevel(tok.text); err == nil {
+		v := numValue(float64(level))
+		return func(*Entry) value { return v }, nil
+	}
+	return nil, fmt.Errorf("speedlog: expression at %d: unknown name %q", tok.pos, tok.text)
+}
+
+// fieldOperand looks up path, first as one dotted key and then through
+// nested groups.
+func fieldOperand(path []string) evalFunc {
+	key := strings.Join(path, ".")
+	return func(e *Entry) value {
+		if f, ok := e.Field(key); ok {
+			return fieldValue(&f)
+		}
+		fields := e.Fields
+		for i, k := range path {
+			var found *Field
+			for j := len(fields) - 1; j >= 0; j-- {
+				if fields[j].Key == k {
+					found = &fields[j]
+					break
+				}
+			}
+			switch {
+			case found == nil:
+				return value{}
+			case i == len(path)-1:
+				return fieldValue(found)
+			case found.kind != groupKind:
+				return value{}
+			}
+			fields = found.val.([]Field)
+		}
+		return value{}
+	}
+}
+
+func fieldValue(f *Field) value {
+	switch f.kind {
+	case stringKind:
+		return value{str: f.str, ok: true}
+	case intKind:
+		return numValue(float64(f.num))
+	case uintKind:
+		return numValue(float64(uint64(f.num)))
+	case floatKind:
+		return numValue(math.Float64frombits(uint64(f.num)))
+	case boolKind:
+		return boolValue(f.num == 1)
+	}
+	return value{str: string(appendValue(nil, f)), ok: true}
+}
+
+func numValue(n float64) value { return value{num: n, isNum: true, ok: true} }
+
+func boolValue(b bool) value {
+	if b {
+		return value{num: 1, str: "true", isNum: true, ok: true}
+	}
+	return value{str: "false", isNum: true, ok: true}
+}
+
+func truthy(v value) bool {
+	if !v.ok {
+		return false
+	}
+	if v.isNum {
+		return v.num != 0
+	}
+	return v.str != "" && v.str != "false"
+}
+
+func compare(a value, op string, b value) bool {
+	if !a.ok || !b.ok {
+		return false
+	}
+	var c int
+	if a.isNum && b.isNum {
+		switch {
+		case a.num < b.num:
+			c = -1
+		case a.num > b.num:
+			c = 1
+		}
+	} else {
+		c = strings.Compare(a.text(), b.text())
+	}
+	switch op {
+	case "==":
+		return c == 0
+	case "!=":
+		return c != 0
+	case "<":
+		return c < 0
+	case "<=":
+		return c <= 0
+	case ">":
+		return c > 0
+	}
+	return c >= 0
+}
+
+func (v value) text() string {
+	if v.isNum && v.str == "" {
+		return strconv.FormatFloat(v.num, 'g', -1, 64)
+	}
+	return v.str
+}
@@ -0,0 +1,12 @@
+package speedlog
+
+import (
+	"os"
+	"syscall"
+)
+
+const fallocKeepSize = 0x1
+
+func preallocate(f *os.File, off, n int64) error {
+	return syscall.Fallocate(int(f.Fd()), fallocKeepSize, off, n)
+}
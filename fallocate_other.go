@@ -0,0 +1,12 @@
+//go:build !linux
+
+package speedlog
+
+import (
+	"errors"
+	"os"
+)
+
+func preallocate(f *os.File, off, n int64) error {
+	return errors.ErrUnsupported
+}
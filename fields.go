@@ -0,0 +1,297 @@
+package speedlog
+
+import (
+	"fmt"
+	"math"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+type fieldKind uint8
+
+const (
+	stringKind fieldKind = iota
+	intKind
+	uintKind
+	floatKind
+	boolKind
+	durationKind
+	timeKind
+	errorKind
+	anyKind
+	groupKind
+	inlineKind
+)
+
+// Field is a key/value pair attached to an entry. Build one with String,
+// Int, Err, Any and friends; the typed constructors don't allocate.
+type Field struct {
+	Key  string
+	kind fieldKind
+	num  int64
+	str  string
+	val  any
+}
+
+func String(key, value string) Field {
+	return Field{Key: key, kind: stringKind, str: value}
+}
+
+func Int(key string, value int) Field {
+	return Field{Key: key, kind: intKind, num: int64(value)}
+}
+
+func Int64(key string, value int64) Field {
+	return Field{Key: key, kind: intKind, num: value}
+}
+
+func Uint64(key string, value uint64) Field {
+	return Field{Key: key, kind: uintKind, num: int64(value)}
+}
+
+func Float64(key string, value float64) Field {
+	return Field{Key: key, kind: floatKind, num: int64(math.Float64bits(value))}
+}
+
+func Bool(key string, value bool) Field {
+	f := Field{Key: key, kind: boolKind}
+	if value {
+		f.num = 1
+	}
+	return f
+}
+
+func Duration(key string, value time.Duration) Field {
+	return Field{Key: key, kind: durationKind, num: int64(value)}
+}
+
+func Time(key string, value time.Time) Field {
+	return Field{Key: key, kind: timeKind, val: value}
+}
+
+// Err is an "error" field; a nil error renders as <nil>.
+func Err(err error) Field {
+	return Field{Key: "error", kind: errorKind, val: err}
+}
+
+// Group nests fields under key: key.sub=v in text, {"key":{"sub":v}} in
+// JSON.
+func Group(key string, fields ...Field) Field {
+	return Field{Key: key, kind: groupKind, val: flatten(fields)}
+}
+
+// Fields merges m into the entry as top-level fields, in key order so
+// the output is stable. Values go through Any.
+func Fields(m map[string]any) Field {
+	fields := make([]Field, 0, len(m))
+	for k, v := range m {
+		fields = append(fields, Any(k, v))
+	}
+	slices.SortFunc(fields, func(a, b Field) int { return strings.Compare(a.Key, b.Key) })
+	return Field{kind: inlineKind, val: fields}
+}
+
+// FieldSlice merges fields into the entry where it stands, for context
+// collected into a slice before the call. The slice isn't copied.
+func FieldSlice(fields []Field) Field {
+	return Field{kind: inlineKind, val: fields}
+}
+
+// flatten splices Fields and FieldSlice values into the list around them,
+// returning fields itself when there are none.
+func flatten(fields []Field) []Field {
+	i := 0
+	for i < len(fields) && fields[i].kind != inlineKind {
+		i++
+	}
+	if i == len(fields) {
+		return fields
+	}
+	out := append(make([]Field, 0, len(fields)+8), fields[:i]...)
+	for _, f := range fields[i:] {
+		if f.kind == inlineKind {
+			inner, _ := f.val.([]Field)
+			out = append(out, flatten(inner)...)
+		} else {
+			out = append(out, f)
+		}
+	}
+	return out
+}
+
+// Any picks the typed constructor for common types and falls back to
+// fmt's %v rendering for everything else.
+func Any(key string, value any) Field {
+	switch v := value.(type) {
+	case string:
+		return String(key, v)
+	case int:
+		return Int(key, v)
+	case int8:
+		return Int64(key, int64(v))
+	case int16:
+		return Int64(key, int64(v))
+	case int32:
+		return Int64(key, int64(v))
+	case int64:
+		return Int64(key, v)
+	case uint:
+		return Uint64(key, uint64(v))
+	case uint8:
+		return Uint64(key, uint64(v))
+	case uint16:
+		return Uint64(key, uint64(v))
+	case uint32:
+		return Uint64(key, uint64(v))
+	case uint64:
+		return Uint64(key, v)
+	case float32:
+		return Float64(key, float64(v))
+	case float64:
+		return Float64(key, v)
+	case bool:
+		return Bool(key, v)
+	case time.Duration:
+		return Duration(key, v)
+	case time.Time:
+		return Time(key, v)
+	case error:
+		return Field{Key: key, kind: errorKind, val: v}
+	}
+	return Field{Key: key, kind: anyKind, val: value}
+}
+
+// Value returns the field's value as string, int64, uint64, float64, bool,
+// time.Duration, time.Time, error, []Field for a Group (or Fields and
+// FieldSlice, before they're merged), or whatever was
+// passed to Any.
+func (f Field) Value() any {
+	switch f.kind {
+	case stringKind:
+		return f.str
+	case intKind:
+		return f.num
+	case uintKind:
+		return uint64(f.num)
+	case floatKind:
+		return math.Float64frombits(uint64(f.num))
+	case boolKind:
+		return f.num == 1
+	case durationKind:
+		return time.Duration(f.num)
+	}
+	return f.val
+}
+
+func (f Field) equal(o Field) bool {
+	if f.Key != o.Key || f.kind != o.kind || f.num != o.num || f.str != o.str {
+		return false
+	}
+	if f.val == nil || o.val == nil {
+		return f.val == o.val
+	}
+	if f.kind == groupKind || f.kind == inlineKind {
+		a, b := f.val.([]Field), o.val.([]Field)
+		if len(a) != len(b) {
+			return false
+		}
+		for i := range a {
+			if !a[i].equal(b[i]) {
+				return false
+			}
+		}
+		return true
+	}
+	if !reflect.TypeOf(f.val).Comparable() || !reflect.TypeOf(o.val).Comparable() {
+		return false
+	}
+	return f.val == o.val
+}
+
+// Field returns the last field with the given key.
+func (e Entry) Field(key string) (Field, bool) {
+	for i := len(e.Fields) - 1; i >= 0; i-- {
+		if e.Fields[i].Key == key {
+			return e.Fields[i], true
+		}
+	}
+	return Field{}, false
+}
+
+func appendFields(buf []byte, fields []Field) []byte {
+	return appendPrefixed(buf, "", fields)
+}
+
+func appendPrefixed(buf []byte, prefix string, fields []Field) []byte {
+	for i := range fields {
+		f := &fields[i]
+		if f.kind == groupKind {
+			buf = appendPrefixed(buf, prefix+f.Key+".", f.val.([]Field))
+			continue
+		}
+		buf = append(buf, ' ')
+		buf = append(buf, prefix...)
+		buf = append(buf, f.Key...)
+		buf = append(buf, '=')
+		buf = appendValue(buf, f)
+	}
+	return buf
+}
+
+// nest adds fields inside the group at path, reusing (a copy of) the
+// trailing group when it is already open.
+func nest(fields []Field, path []string, add []Field) []Field {
+	add = flatten(add)
+	if len(path) == 0 {
+		return append(fields[:len(fields):len(fields)], add...)
+	}
+	if last := len(fields) - 1; last >= 0 && fields[last].kind == groupKind && fields[last].Key == path[0] {
+		g := fields[last]
+		g.val = nest(g.val.([]Field), path[1:], add)
+		return append(fields[:last:last], g)
+	}
+	return append(fields[:len(fields):len(fields)], Group(path[0], nest(nil, path[1:], add)...))
+}
+
+func appendValue(buf []byte, f *Field) []byte {
+	switch f.kind {
+	case stringKind:
+		return appendText(buf, f.str)
+	case intKind:
+		return AppendInt(buf, f.num)
+	case uintKind:
+		return AppendUint(buf, uint64(f.num))
+	case floatKind:
+		return appendFloatBits(buf, f.num)
+	case boolKind:
+		return AppendBool(buf, f.num == 1)
+	case durationKind:
+		return append(buf, time.Duration(f.num).String()...)
+	case timeKind:
+		return f.val.(time.Time).AppendFormat(buf, time.RFC3339Nano)
+	case errorKind:
+		if f.val == nil {
+			return append(buf, "<nil>"...)
+		}
+		return appendText(buf, f.val.(error).Error())
+	}
+	return appendText(buf, fmt.Sprint(f.val))
+}
+
+// appendText quotes s when it would otherwise be ambiguous in key=value
+// output.
+func appendText(buf []byte, s string) []byte {
+	if s == "" {
+		return append(buf, `""`...)
+	}
+	for i := 0; i < len(s); i++ {
+		if c := s[i]; c <= ' ' || c == '"' || c == '=' || c >= utf8.RuneSelf {
+			return strconv.AppendQuote(buf, s)
+		}
+	}
+	return append(buf, s...)
+}
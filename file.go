@@ -0,0 +1,274 @@
+package speedlog
+
+import (
+	"os"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+type FileOption func(*FileWriter)
+
+type FileWriter struct {
+	mu      sync.Mutex
+	path    string
+	f       *os.File
+	size    int64
+	alloc   int64
+	extent  int64
+	lock    bool
+	check   time.Duration
+	checked time.Time
+
+	tmpl     pathTemplate
+	loc      *time.Location
+	nextName time.Time
+
+	every      time.Duration
+	rotLoc     *time.Location
+	nextRotate time.Time
+
+	budget    int64
+	unchecked int64
+
+	manifest   string
+	codec      Codec
+	archive    func(path string) error
+	archiveDel bool
+	finishMu   sync.Mutex
+	finishQ    []string
+	finishDone bool
+	finishSig  chan struct{}
+	finishWG   sync.WaitGroup
+	finishErr  atomic.Pointer[error]
+}
+
+func WithPreallocate(extent int64) FileOption {
+	return func(fw *FileWriter) {
+		if extent > 0 {
+			fw.extent = extent
+		}
+	}
+}
+
+// WithLocking takes an exclusive flock around every write so several
+// processes can append to the same file. Each Write lands as one
+// contiguous run no matter its size; preallocation is disabled because the
+// file grows behind this process's back.
+func WithLocking() FileOption {
+	return func(fw *FileWriter) {
+		fw.lock = true
+	}
+}
+
+// WithReopenCheck stats path at most once per interval (on the next write)
+// and reopens it if it was renamed or removed by an external rotator, or
+// picks up the new size if it was truncated in place.
+func WithReopenCheck(interval time.Duration) FileOption {
+	return func(fw *FileWriter) {
+		if interval > 0 {
+			fw.check = interval
+		}
+	}
+}
+
+func NewFileWriter(path string, opts ...FileOption) (*FileWriter, error) {
+	fw := &FileWriter{path: path}
+	for _, opt := range opts {
+		opt(fw)
+	}
+	if fw.lock {
+		fw.extent = 0
+	}
+	if fw.every > 0 {
+		fw.nextRotate = nextBoundary(time.Now(), fw.every, fw.rotLoc)
+	}
+	if fw.tmpl != nil {
+		if err := fw.switchPath(time.Now()); err != nil {
+			return nil, err
+		}
+	} else {
+		if err := fw.open(); err != nil {
+			return nil, err
+		}
+		if fw.budget > 0 {
+			fw.enforceBudget()
+		}
+	}
+	if fw.archive != nil || fw.manifest != "" || fw.codec != nil {
+		fw.startFinisher()
+	}
+	return fw, nil
+}
+
+func (fw *FileWriter) open() error {
+	f, err := os.OpenFile(fw.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return err
+	}
+	fi, err := f.Stat()
+	if err != nil {
+		_ = f.Close()
+		return err
+	}
+	fw.f = f
+	fw.size = fi.Size()
+	fw.alloc = fw.size
+	return nil
+}
+
+func (fw *FileWriter) reopenIfMoved() error {
+	fi, err := os.Stat(fw.path)
+	if err != nil && !os.IsNotExist(err) {
+		return nil
+	}
+	cur, cerr := fw.f.Stat()
+	if cerr != nil {
+		return nil
+	}
+	if err == nil && os.SameFile(fi, cur) {
+		if fi.Size() < fw.size {
+			fw.size = fi.Size()
+			fw.alloc = fw.size
+		}
+		return nil
+	}
+	fw.trim()
+	_ = fw.f.Close()
+	fw.f = nil
+	return fw.open()
+}
+
+func (fw *FileWriter) reserve(n int64) {
+	if fw.extent == 0 || fw.size+n <= fw.alloc {
+		return
+	}
+	want := (fw.size + n + fw.extent - 1) / fw.extent * fw.extent
+	if err := preallocate(fw.f, fw.alloc, want-fw.alloc); err != nil {
+		// Not every filesystem supports it; carry on with plain appends.
+		fw.extent = 0
+		return
+	}
+	fw.alloc = want
+}
+
+// trim gives back the blocks reserved past the last write, which a
+// KEEP_SIZE reservation leaves allocated until the file is truncated.
+func (fw *FileWriter) trim() {
+	if fw.alloc > fw.size {
+		_ = fw.f.Truncate(fw.size)
+		fw.alloc = fw.size
+	}
+}
+
+func (fw *FileWriter) Write(p []byte) (int, error) {
+	fw.mu.Lock()
+	defer fw.mu.Unlock()
+	if fw.f == nil {
+		return 0, os.ErrClosed
+	}
+	if fw.tmpl != nil {
+		if err := fw.switchPath(time.Now()); err != nil {
+			return 0, err
+		}
+	}
+	if fw.every > 0 {
+		if err := fw.rotateDue(time.Now()); err != nil {
+			return 0, err
+		}
+	}
+	if fw.check > 0 {
+		if now := time.Now(); now.Sub(fw.checked) >= fw.check {
+			fw.checked = now
+			if err := fw.reopenIfMoved(); err != nil {
+				return 0, err
+			}
+		}
+	}
+	if fw.lock {
+		if err := lockFile(fw.f); err != nil {
+			return 0, err
+		}
+		defer unlockFile(fw.f)
+	}
+	fw.reserve(int64(len(p)))
+	n, err := fw.f.Write(p)
+	fw.size += int64(n)
+	if fw.budget > 0 {
+		if fw.unchecked += int64(n); fw.unchecked > fw.budget/16 {
+			fw.enforceBudget()
+		}
+	}
+	if e := fw.finishErr.Swap(nil); e != nil && err == nil {
+		// The data is on disk; only the archival failed.
+		return n, *e
+	}
+	return n, err
+}
+
+func (fw *FileWriter) Sync() error {
+	fw.mu.Lock()
+	defer fw.mu.Unlock()
+	if fw.f == nil {
+		return nil
+	}
+	return fw.f.Sync()
+}
+
+func (fw *FileWriter) Close() error {
+	fw.mu.Lock()
+	defer fw.mu.Unlock()
+	if fw.f == nil {
+		return nil
+	}
+	fw.trim()
+	err := fw.f.Close()
+	fw.f = nil
+	if fw.finishSig != nil {
+		fw.stopFinisher()
+		if e := fw.finishErr.Swap(nil); e != nil && err == nil {
+			err = *e
+		}
+	}
+	return err
+}
+
+// Rotate renames the current file to path.YYYYMMDD-HHMMSS and starts a new
+// one at path.
+func (fw *FileWriter) Rotate() error {
+	fw.mu.Lock()
+	defer fw.mu.Unlock()
+	if fw.f == nil {
+		return os.ErrClosed
+	}
+	return fw.rotate()
+}
+
+func (fw *FileWriter) rotate() error {
+	fw.trim()
+	if err := fw.f.Close(); err != nil {
+		return err
+	}
+	fw.f = nil
+	base := fw.path + "." + time.Now().Format("20060102-150405")
+	dst := base
+	for i := 1; ; i++ {
+		if !exists(dst) && (fw.codec == nil || !exists(dst+fw.codec.Ext())) {
+			break
+		}
+		dst = base + "." + strconv.Itoa(i)
+	}
+	if err := os.Rename(fw.path, dst); err != nil {
+		_ = fw.open()
+		return err
+	}
+	fw.finished(dst)
+	if err := fw.open(); err != nil {
+		return err
+	}
+	if fw.budget > 0 {
+		fw.enforceBudget()
+	}
+	return nil
+}
@@ -0,0 +1,10 @@
+//go:build !(linux || darwin || freebsd || netbsd || openbsd || dragonfly)
+
+package speedlog
+
+import "os"
+
+// Without flock, WithLocking falls back to plain O_APPEND writes.
+func lockFile(f *os.File) error { return nil }
+
+func unlockFile(f *os.File) error { return nil }
@@ -0,0 +1,66 @@
+package speedlog
+
+import "time"
+
+// FlushPolicy is one trigger for flushing a sink's buffer; see WithFlush.
+type FlushPolicy struct {
+	interval time.Duration
+	bytes    int
+	entries  int
+	level    int
+	atLevel  bool
+}
+
+// FlushEvery flushes once d has passed since the last flush.
+func FlushEvery(d time.Duration) FlushPolicy { return FlushPolicy{interval: d} }
+
+// FlushAtBytes flushes once n bytes are buffered. The sink buffer grows to
+// at least n so bufio doesn't flush earlier on its own.
+func FlushAtBytes(n int) FlushPolicy { return FlushPolicy{bytes: n} }
+
+// FlushAtEntries flushes once n entries were written since the last flush.
+func FlushAtEntries(n int) FlushPolicy { return FlushPolicy{entries: n} }
+
+// FlushAtLevel flushes right after any batch containing an entry at or
+// above level.
+func FlushAtLevel(level int) FlushPolicy { return FlushPolicy{level: level, atLevel: true} }
+
+// WithFlush replaces the logger's adaptive flush for this sink by the
+// given triggers, any of which flushes: a terminal can flush every 10ms
+// while an upload batcher waits for 8 MiB. Sync, Close and a full buffer
+// still flush. Without FlushEvery nothing flushes on a timer, so data can
+// sit in the buffer until a trigger fires.
+func WithFlush(policies ...FlushPolicy) SinkOption {
+	return func(s *sink) {
+		for _, p := range policies {
+			s.flushPol.interval = max(s.flushPol.interval, p.interval)
+			s.flushPol.bytes = max(s.flushPol.bytes, p.bytes)
+			s.flushPol.entries = max(s.flushPol.entries, p.entries)
+			if p.atLevel {
+				s.flushPol.level, s.flushPol.atLevel = p.level, true
+			}
+		}
+		s.customFlush = len(policies) > 0
+	}
+}
+
+// periodic is a timer tick; sinks with their own policy only flush when
+// its interval is due.
+func (s *sink) periodic() {
+	if s.customFlush && (s.flushPol.interval == 0 || time.Since(s.lastFlush) < s.flushPol.interval) {
+		if s.wal != nil && s.wal.replay && !s.ejected.Load() {
+			s.resend()
+		}
+		return
+	}
+	s.tick()
+}
+
+// flushDue checks the count, size and level triggers after a write.
+func (s *sink) flushDue(n, top int) bool {
+	s.sinceFlush += n
+	p := &s.flushPol
+	return p.entries > 0 && s.sinceFlush >= p.entries ||
+		p.bytes > 0 && s.bw.Buffered() >= p.bytes ||
+		p.atLevel && top >= p.level
+}
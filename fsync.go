@@ -0,0 +1,81 @@
+package speedlog
+
+import "os"
+
+type fsyncMode int
+
+const (
+	fsyncNever fsyncMode = iota
+	fsyncOnFlush
+	fsyncEvery
+	fsyncAtLevel
+)
+
+// FsyncPolicy says when a sink fsyncs its writer (anything with a
+// Sync() error method, such as *os.File or FileWriter). Between fsyncs a
+// crash can lose whatever the OS hasn't written back yet.
+type FsyncPolicy struct {
+	mode  fsyncMode
+	n     int
+	level int
+}
+
+// FsyncNever leaves write-back to the OS. This is the default.
+func FsyncNever() FsyncPolicy { return FsyncPolicy{} }
+
+// FsyncOnFlush fsyncs after every flush tick that wrote something.
+func FsyncOnFlush() FsyncPolicy { return FsyncPolicy{mode: fsyncOnFlush} }
+
+// FsyncEvery fsyncs once at least n entries were written since the last
+// one, at the end of the batch that crossed it.
+func FsyncEvery(n int) FsyncPolicy { return FsyncPolicy{mode: fsyncEvery, n: max(n, 1)} }
+
+// FsyncAtLevel flushes and fsyncs right after any batch containing an
+// entry at or above level, so it (and everything before it) is on disk
+// before the writer moves on.
+func FsyncAtLevel(level int) FsyncPolicy { return FsyncPolicy{mode: fsyncAtLevel, level: level} }
+
+func WithFsync(p FsyncPolicy) SinkOption {
+	return func(s *sink) {
+		s.fsync = p
+	}
+}
+
+// durable applies the policy after n entries (highest level top) were
+// written.
+func (s *sink) durable(n, top int) {
+	s.dirty = true
+	switch s.fsync.mode {
+	case fsyncEvery:
+		if s.unsynced += n; s.unsynced >= s.fsync.n {
+			s.flush()
+			s.sync()
+		}
+	case fsyncAtLevel:
+		if top >= s.fsync.level {
+			s.flush()
+			s.sync()
+		}
+	}
+}
+
+func (s *sink) sync() {
+	s.unsynced, s.dirty = 0, false
+	if s.w == os.Stdout || s.w == os.Stderr || s.ejected.Load() {
+		return
+	}
+	if f, ok := s.w.(syncer); ok {
+		if err := f.Sync(); err != nil {
+			s.fail(err)
+		}
+	}
+}
+
+// finish is the last flush before close; any policy but FsyncNever ends
+// with an fsync.
+func (s *sink) finish() {
+	s.flush()
+	if s.fsync.mode != fsyncNever && s.dirty {
+		s.sync()
+	}
+}
@@ -0,0 +1,116 @@
+package grpclog
+
+import (
+	"context"
+	"sync/atomic"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"speedlog"
+)
+
+type AccessOption func(*access)
+
+type access struct {
+	slow     time.Duration
+	sample   uint64
+	errs     map[codes.Code]bool
+	ordinary atomic.Uint64
+}
+
+// WithSlowThreshold logs calls that take d or longer at WARN, whatever the
+// sampling (default 1s; 0 turns it off).
+func WithSlowThreshold(d time.Duration) AccessOption {
+	return func(a *access) {
+		if d >= 0 {
+			a.slow = d
+		}
+	}
+}
+
+// WithSampling logs one in n of the ordinary calls (fast, no error code)
+// at INFO, with a sample_rate=n field. 1, the default, logs them all; 0
+// logs none.
+func WithSampling(n int) AccessOption {
+	return func(a *access) {
+		if n >= 0 {
+			a.sample = uint64(n)
+		}
+	}
+}
+
+// WithErrorCodes sets the codes logged at WARN as errors. The default is
+// the server-side failures: Unknown, DeadlineExceeded, Unimplemented,
+// Internal, Unavailable and DataLoss.
+func WithErrorCodes(cs ...codes.Code) AccessOption {
+	return func(a *access) {
+		a.errs = map[codes.Code]bool{}
+		for _, c := range cs {
+			a.errs[c] = true
+		}
+	}
+}
+
+func newAccess(opts []AccessOption) *access {
+	a := &access{slow: time.Second, sample: 1, errs: map[codes.Code]bool{
+		codes.Unknown: true, codes.DeadlineExceeded: true, codes.Unimplemented: true,
+		codes.Internal: true, codes.Unavailable: true, codes.DataLoss: true,
+	}}
+	for _, opt := range opts {
+		opt(a)
+	}
+	return a
+}
+
+// AccessLogUnary logs one "rpc" entry per call with method, code and
+// duration, plus error for failed calls, like httplog.AccessLog: slow
+// calls and error codes always, at WARN, the rest at INFO as sampled. Chain
+// it after RequestIDUnary so the entries carry the request fields.
+func AccessLogUnary(l *speedlog.Logger, opts ...AccessOption) grpc.UnaryServerInterceptor {
+	a := newAccess(opts)
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		start := time.Now()
+		resp, err := handler(ctx, req)
+		a.log(ctx, l, info.FullMethod, time.Since(start), err)
+		return resp, err
+	}
+}
+
+func AccessLogStream(l *speedlog.Logger, opts ...AccessOption) grpc.StreamServerInterceptor {
+	a := newAccess(opts)
+	return func(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		start := time.Now()
+		err := handler(srv, ss)
+		a.log(ss.Context(), l, info.FullMethod, time.Since(start), err)
+		return err
+	}
+}
+
+func (a *access) log(ctx context.Context, l *speedlog.Logger, method string, d time.Duration, err error) {
+	st := status.Convert(err)
+	level, rate := speedlog.WARN, uint64(0)
+	if (a.slow == 0 || d < a.slow) && !a.errs[st.Code()] {
+		if a.sample == 0 || (a.ordinary.Add(1)-1)%a.sample != 0 {
+			return
+		}
+		level, rate = speedlog.INFO, a.sample
+	}
+	if !l.IsLevelEnabled(level) {
+		return
+	}
+	fields := []speedlog.Field{
+		speedlog.String("method", method),
+		speedlog.String("code", st.Code().String()),
+		speedlog.Duration("duration", d),
+	}
+	if err != nil {
+		fields = append(fields, speedlog.String("error", st.Message()))
+	}
+	if rate > 1 {
+		fields = append(fields, speedlog.Uint64("sample_rate", rate))
+	}
+	l.Ctx(ctx).Log(level, "rpc", fields...)
+}
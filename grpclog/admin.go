@@ -0,0 +1,88 @@
+// Package grpclog wires speedlog into gRPC. It is a module of its own so
+// speedlog itself stays free of dependencies.
+package grpclog
+
+import (
+	"context"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"speedlog"
+	"speedlog/grpclog/adminpb"
+)
+
+// NewAdminServer serves the LoggerAdmin service for l; register it with
+// adminpb.RegisterLoggerAdminServer. Anyone who can reach it can change
+// the level and read every line, so serve it with credentials or on an
+// internal listener only.
+func NewAdminServer(l *speedlog.Logger) adminpb.LoggerAdminServer {
+	return &adminServer{l: l}
+}
+
+type adminServer struct {
+	adminpb.UnimplementedLoggerAdminServer
+	l *speedlog.Logger
+}
+
+func (s *adminServer) GetLevel(context.Context, *adminpb.GetLevelRequest) (*adminpb.Level, error) {
+	return &adminpb.Level{Name: speedlog.LevelName(s.l.GetLevel())}, nil
+}
+
+func (s *adminServer) SetLevel(_ context.Context, req *adminpb.Level) (*adminpb.Level, error) {
+	level, err := speedlog.ParseLevel(req.GetName())
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	s.l.SetLevel(level)
+	return &adminpb.Level{Name: speedlog.LevelName(level)}, nil
+}
+
+func (s *adminServer) GetStatus(context.Context, *adminpb.GetStatusRequest) (*adminpb.Status, error) {
+	st := s.l.Stats()
+	out := &adminpb.Status{
+		QueueLen: int64(st.QueueLen),
+		QueueCap: int64(st.QueueCap),
+		Entries:  st.Entries,
+		Sinks:    make([]*adminpb.SinkStatus, len(st.Sinks)),
+	}
+	for i, ss := range st.Sinks {
+		out.Sinks[i] = &adminpb.SinkStatus{
+			Id:       int64(ss.ID),
+			Name:     ss.Name,
+			Written:  ss.Written,
+			Dropped:  ss.Dropped,
+			Errors:   ss.Errors,
+			Timeouts: ss.Timeouts,
+			Expired:  ss.Expired,
+			Ejected:  ss.Ejected,
+		}
+	}
+	if err := s.l.Healthy(); err != nil {
+		out.HealthError = err.Error()
+	}
+	return out, nil
+}
+
+// maxTailBuffer caps the client-chosen TailRequest.buffer, which sizes a
+// channel on the server.
+const maxTailBuffer = 4096
+
+// Tail streams every line written until the client goes away. Chunks are
+// dropped, not queued, while the client is more than Buffer behind.
+func (s *adminServer) Tail(req *adminpb.TailRequest, stream grpc.ServerStreamingServer[adminpb.TailChunk]) error {
+	lines, stop := s.l.Tail(int(min(req.GetBuffer(), maxTailBuffer)))
+	defer stop()
+	ctx := stream.Context()
+	for {
+		select {
+		case chunk := <-lines:
+			if err := stream.Send(&adminpb.TailChunk{Lines: chunk}); err != nil {
+				return err
+			}
+		case <-ctx.Done():
+			return status.FromContextError(ctx.Err()).Err()
+		}
+	}
+}
@@ -0,0 +1,507 @@
+// Admin service for a process using speedlog, served by
+// grpclog.NewAdminServer. Each RPC is backed by the Logger method noted.
+
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// versions:
+// 	protoc-gen-go v1.36.11
+// 	protoc        (unknown)
+// source: admin.proto
+
+package adminpb
+
+import (
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	reflect "reflect"
+	sync "sync"
+	unsafe "unsafe"
+)
+
+const (
+	// Verify that this generated code is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(20 - protoimpl.MinVersion)
+	// Verify that runtime/protoimpl is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
+)
+
+type GetLevelRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetLevelRequest) Reset() {
+	*x = GetLevelRequest{}
+	mi := &file_admin_proto_msgTypes[0]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetLevelRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetLevelRequest) ProtoMessage() {}
+
+func (x *GetLevelRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_admin_proto_msgTypes[0]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetLevelRequest.ProtoReflect.Descriptor instead.
+func (*GetLevelRequest) Descriptor() ([]byte, []int) {
+	return file_admin_proto_rawDescGZIP(), []int{0}
+}
+
+type Level struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Name          string                 `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Level) Reset() {
+	*x = Level{}
+	mi := &file_admin_proto_msgTypes[1]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Level) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Level) ProtoMessage() {}
+
+func (x *Level) ProtoReflect() protoreflect.Message {
+	mi := &file_admin_proto_msgTypes[1]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Level.ProtoReflect.Descriptor instead.
+func (*Level) Descriptor() ([]byte, []int) {
+	return file_admin_proto_rawDescGZIP(), []int{1}
+}
+
+func (x *Level) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
+}
+
+type GetStatusRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetStatusRequest) Reset() {
+	*x = GetStatusRequest{}
+	mi := &file_admin_proto_msgTypes[2]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetStatusRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetStatusRequest) ProtoMessage() {}
+
+func (x *GetStatusRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_admin_proto_msgTypes[2]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetStatusRequest.ProtoReflect.Descriptor instead.
+func (*GetStatusRequest) Descriptor() ([]byte, []int) {
+	return file_admin_proto_rawDescGZIP(), []int{2}
+}
+
+type SinkStatus struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Written       uint64                 `protobuf:"varint,1,opt,name=written,proto3" json:"written,omitempty"`
+	Dropped       uint64                 `protobuf:"varint,2,opt,name=dropped,proto3" json:"dropped,omitempty"`
+	Errors        uint64                 `protobuf:"varint,3,opt,name=errors,proto3" json:"errors,omitempty"`
+	Timeouts      uint64                 `protobuf:"varint,4,opt,name=timeouts,proto3" json:"timeouts,omitempty"`
+	Ejected       bool                   `protobuf:"varint,5,opt,name=ejected,proto3" json:"ejected,omitempty"`
+	Id            int64                  `protobuf:"varint,6,opt,name=id,proto3" json:"id,omitempty"`
+	Name          string                 `protobuf:"bytes,7,opt,name=name,proto3" json:"name,omitempty"`
+	Expired       uint64                 `protobuf:"varint,8,opt,name=expired,proto3" json:"expired,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *SinkStatus) Reset() {
+	*x = SinkStatus{}
+	mi := &file_admin_proto_msgTypes[3]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *SinkStatus) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*SinkStatus) ProtoMessage() {}
+
+func (x *SinkStatus) ProtoReflect() protoreflect.Message {
+	mi := &file_admin_proto_msgTypes[3]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use SinkStatus.ProtoReflect.Descriptor instead.
+func (*SinkStatus) Descriptor() ([]byte, []int) {
+	return file_admin_proto_rawDescGZIP(), []int{3}
+}
+
+func (x *SinkStatus) GetWritten() uint64 {
+	if x != nil {
+		return x.Written
+	}
+	return 0
+}
+
+func (x *SinkStatus) GetDropped() uint64 {
+	if x != nil {
+		return x.Dropped
+	}
+	return 0
+}
+
+func (x *SinkStatus) GetErrors() uint64 {
+	if x != nil {
+		return x.Errors
+	}
+	return 0
+}
+
+func (x *SinkStatus) GetTimeouts() uint64 {
+	if x != nil {
+		return x.Timeouts
+	}
+	return 0
+}
+
+func (x *SinkStatus) GetEjected() bool {
+	if x != nil {
+		return x.Ejected
+	}
+	return false
+}
+
+func (x *SinkStatus) GetId() int64 {
+	if x != nil {
+		return x.Id
+	}
+	return 0
+}
+
+func (x *SinkStatus) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
+}
+
+func (x *SinkStatus) GetExpired() uint64 {
+	if x != nil {
+		return x.Expired
+	}
+	return 0
+}
+
+type Status struct {
+	state    protoimpl.MessageState `protogen:"open.v1"`
+	QueueLen int64                  `protobuf:"varint,1,opt,name=queue_len,json=queueLen,proto3" json:"queue_len,omitempty"`
+	QueueCap int64                  `protobuf:"varint,2,opt,name=queue_cap,json=queueCap,proto3" json:"queue_cap,omitempty"`
+	Sinks    []*SinkStatus          `protobuf:"bytes,3,rep,name=sinks,proto3" json:"sinks,omitempty"`
+	// Empty when Healthy returns nil.
+	HealthError   string `protobuf:"bytes,4,opt,name=health_error,json=healthError,proto3" json:"health_error,omitempty"`
+	Entries       uint64 `protobuf:"varint,5,opt,name=entries,proto3" json:"entries,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Status) Reset() {
+	*x = Status{}
+	mi := &file_admin_proto_msgTypes[4]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Status) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Status) ProtoMessage() {}
+
+func (x *Status) ProtoReflect() protoreflect.Message {
+	mi := &file_admin_proto_msgTypes[4]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Status.ProtoReflect.Descriptor instead.
+func (*Status) Descriptor() ([]byte, []int) {
+	return file_admin_proto_rawDescGZIP(), []int{4}
+}
+
+func (x *Status) GetQueueLen() int64 {
+	if x != nil {
+		return x.QueueLen
+	}
+	return 0
+}
+
+func (x *Status) GetQueueCap() int64 {
+	if x != nil {
+		return x.QueueCap
+	}
+	return 0
+}
+
+func (x *Status) GetSinks() []*SinkStatus {
+	if x != nil {
+		return x.Sinks
+	}
+	return nil
+}
+
+func (x *Status) GetHealthError() string {
+	if x != nil {
+		return x.HealthError
+	}
+	return ""
+}
+
+func (x *Status) GetEntries() uint64 {
+	if x != nil {
+		return x.Entries
+	}
+	return 0
+}
+
+type TailRequest struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// Chunks buffered for a slow client before chunks are dropped; 0 means
+	// 64, and values above 4096 are treated as 4096.
+	Buffer        int32 `protobuf:"varint,1,opt,name=buffer,proto3" json:"buffer,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *TailRequest) Reset() {
+	*x = TailRequest{}
+	mi := &file_admin_proto_msgTypes[5]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *TailRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*TailRequest) ProtoMessage() {}
+
+func (x *TailRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_admin_proto_msgTypes[5]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use TailRequest.ProtoReflect.Descriptor instead.
+func (*TailRequest) Descriptor() ([]byte, []int) {
+	return file_admin_proto_rawDescGZIP(), []int{5}
+}
+
+func (x *TailRequest) GetBuffer() int32 {
+	if x != nil {
+		return x.Buffer
+	}
+	return 0
+}
+
+type TailChunk struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// One or more complete, newline-terminated encoded entries.
+	Lines         []byte `protobuf:"bytes,1,opt,name=lines,proto3" json:"lines,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *TailChunk) Reset() {
+	*x = TailChunk{}
+	mi := &file_admin_proto_msgTypes[6]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *TailChunk) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*TailChunk) ProtoMessage() {}
+
+func (x *TailChunk) ProtoReflect() protoreflect.Message {
+	mi := &file_admin_proto_msgTypes[6]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use TailChunk.ProtoReflect.Descriptor instead.
+func (*TailChunk) Descriptor() ([]byte, []int) {
+	return file_admin_proto_rawDescGZIP(), []int{6}
+}
+
+func (x *TailChunk) GetLines() []byte {
+	if x != nil {
+		return x.Lines
+	}
+	return nil
+}
+
+var File_admin_proto protoreflect.FileDescriptor
+
+const file_admin_proto_rawDesc = "" +
+	"\n" +
+	"\vadmin.proto\x12\x11speedlog.admin.v1\"\x11\n" +
+	"\x0fGetLevelRequest\"\x1b\n" +
+	"\x05Level\x12\x12\n" +
+	"\x04name\x18\x01 \x01(\tR\x04name\"\x12\n" +
+	"\x10GetStatusRequest\"\xcc\x01\n" +
+	"\n" +
+	"SinkStatus\x12\x18\n" +
+	"\awritten\x18\x01 \x01(\x04R\awritten\x12\x18\n" +
+	"\adropped\x18\x02 \x01(\x04R\adropped\x12\x16\n" +
+	"\x06errors\x18\x03 \x01(\x04R\x06errors\x12\x1a\n" +
+	"\btimeouts\x18\x04 \x01(\x04R\btimeouts\x12\x18\n" +
+	"\aejected\x18\x05 \x01(\bR\aejected\x12\x0e\n" +
+	"\x02id\x18\x06 \x01(\x03R\x02id\x12\x12\n" +
+	"\x04name\x18\a \x01(\tR\x04name\x12\x18\n" +
+	"\aexpired\x18\b \x01(\x04R\aexpired\"\xb4\x01\n" +
+	"\x06Status\x12\x1b\n" +
+	"\tqueue_len\x18\x01 \x01(\x03R\bqueueLen\x12\x1b\n" +
+	"\tqueue_cap\x18\x02 \x01(\x03R\bqueueCap\x123\n" +
+	"\x05sinks\x18\x03 \x03(\v2\x1d.speedlog.admin.v1.SinkStatusR\x05sinks\x12!\n" +
+	"\fhealth_error\x18\x04 \x01(\tR\vhealthError\x12\x18\n" +
+	"\aentries\x18\x05 \x01(\x04R\aentries\"%\n" +
+	"\vTailRequest\x12\x16\n" +
+	"\x06buffer\x18\x01 \x01(\x05R\x06buffer\"!\n" +
+	"\tTailChunk\x12\x14\n" +
+	"\x05lines\x18\x01 \x01(\fR\x05lines2\xac\x02\n" +
+	"\vLoggerAdmin\x12H\n" +
+	"\bGetLevel\x12\".speedlog.admin.v1.GetLevelRequest\x1a\x18.speedlog.admin.v1.Level\x12>\n" +
+	"\bSetLevel\x12\x18.speedlog.admin.v1.Level\x1a\x18.speedlog.admin.v1.Level\x12K\n" +
+	"\tGetStatus\x12#.speedlog.admin.v1.GetStatusRequest\x1a\x19.speedlog.admin.v1.Status\x12F\n" +
+	"\x04Tail\x12\x1e.speedlog.admin.v1.TailRequest\x1a\x1c.speedlog.admin.v1.TailChunk0\x01B\"Z speedlog/grpclog/adminpb;adminpbb\x06proto3"
+
+var (
+	file_admin_proto_rawDescOnce sync.Once
+	file_admin_proto_rawDescData []byte
+)
+
+func file_admin_proto_rawDescGZIP() []byte {
+	file_admin_proto_rawDescOnce.Do(func() {
+		file_admin_proto_rawDescData = protoimpl.X.CompressGZIP(unsafe.Slice(unsafe.StringData(file_admin_proto_rawDesc), len(file_admin_proto_rawDesc)))
+	})
+	return file_admin_proto_rawDescData
+}
+
+var file_admin_proto_msgTypes = make([]protoimpl.MessageInfo, 7)
+var file_admin_proto_goTypes = []any{
+	(*GetLevelRequest)(nil),  // 0: speedlog.admin.v1.GetLevelRequest
+	(*Level)(nil),            // 1: speedlog.admin.v1.Level
+	(*GetStatusRequest)(nil), // 2: speedlog.admin.v1.GetStatusRequest
+	(*SinkStatus)(nil),       // 3: speedlog.admin.v1.SinkStatus
+	(*Status)(nil),           // 4: speedlog.admin.v1.Status
+	(*TailRequest)(nil),      // 5: speedlog.admin.v1.TailRequest
+	(*TailChunk)(nil),        // 6: speedlog.admin.v1.TailChunk
+}
+var file_admin_proto_depIdxs = []int32{
+	3, // 0: speedlog.admin.v1.Status.sinks:type_name -> speedlog.admin.v1.SinkStatus
+	0, // 1: speedlog.admin.v1.LoggerAdmin.GetLevel:input_type -> speedlog.admin.v1.GetLevelRequest
+	1, // 2: speedlog.admin.v1.LoggerAdmin.SetLevel:input_type -> speedlog.admin.v1.Level
+	2, // 3: speedlog.admin.v1.LoggerAdmin.GetStatus:input_type -> speedlog.admin.v1.GetStatusRequest
+	5, // 4: speedlog.admin.v1.LoggerAdmin.Tail:input_type -> speedlog.admin.v1.TailRequest
+	1, // 5: speedlog.admin.v1.LoggerAdmin.GetLevel:output_type -> speedlog.admin.v1.Level
+	1, // 6: speedlog.admin.v1.LoggerAdmin.SetLevel:output_type -> speedlog.admin.v1.Level
+	4, // 7: speedlog.admin.v1.LoggerAdmin.GetStatus:output_type -> speedlog.admin.v1.Status
+	6, // 8: speedlog.admin.v1.LoggerAdmin.Tail:output_type -> speedlog.admin.v1.TailChunk
+	5, // [5:9] is the sub-list for method output_type
+	1, // [1:5] is the sub-list for method input_type
+	1, // [1:1] is the sub-list for extension type_name
+	1, // [1:1] is the sub-list for extension extendee
+	0, // [0:1] is the sub-list for field type_name
+}
+
+func init() { file_admin_proto_init() }
+func file_admin_proto_init() {
+	if File_admin_proto != nil {
+		return
+	}
+	type x struct{}
+	out := protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: unsafe.Slice(unsafe.StringData(file_admin_proto_rawDesc), len(file_admin_proto_rawDesc)),
+			NumEnums:      0,
+			NumMessages:   7,
+			NumExtensions: 0,
+			NumServices:   1,
+		},
+		GoTypes:           file_admin_proto_goTypes,
+		DependencyIndexes: file_admin_proto_depIdxs,
+		MessageInfos:      file_admin_proto_msgTypes,
+	}.Build()
+	File_admin_proto = out.File
+	file_admin_proto_goTypes = nil
+	file_admin_proto_depIdxs = nil
+}
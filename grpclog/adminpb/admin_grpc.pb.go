@@ -0,0 +1,250 @@
+// Admin service for a process using speedlog, served by
+// grpclog.NewAdminServer. Each RPC is backed by the Logger method noted.
+
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// versions:
+// - protoc-gen-go-grpc v1.6.2
+// - protoc             (unknown)
+// source: admin.proto
+
+package adminpb
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.64.0 or later.
+const _ = grpc.SupportPackageIsVersion9
+
+const (
+	LoggerAdmin_GetLevel_FullMethodName  = "/speedlog.admin.v1.LoggerAdmin/GetLevel"
+	LoggerAdmin_SetLevel_FullMethodName  = "/speedlog.admin.v1.LoggerAdmin/SetLevel"
+	LoggerAdmin_GetStatus_FullMethodName = "/speedlog.admin.v1.LoggerAdmin/GetStatus"
+	LoggerAdmin_Tail_FullMethodName      = "/speedlog.admin.v1.LoggerAdmin/Tail"
+)
+
+// LoggerAdminClient is the client API for LoggerAdmin service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+type LoggerAdminClient interface {
+	// Logger.GetLevel
+	GetLevel(ctx context.Context, in *GetLevelRequest, opts ...grpc.CallOption) (*Level, error)
+	// Logger.SetLevel (names as accepted by speedlog.ParseLevel)
+	SetLevel(ctx context.Context, in *Level, opts ...grpc.CallOption) (*Level, error)
+	// Logger.Stats and Logger.Healthy
+	GetStatus(ctx context.Context, in *GetStatusRequest, opts ...grpc.CallOption) (*Status, error)
+	// Logger.Tail
+	Tail(ctx context.Context, in *TailRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[TailChunk], error)
+}
+
+type loggerAdminClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewLoggerAdminClient(cc grpc.ClientConnInterface) LoggerAdminClient {
+	return &loggerAdminClient{cc}
+}
+
+func (c *loggerAdminClient) GetLevel(ctx context.Context, in *GetLevelRequest, opts ...grpc.CallOption) (*Level, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(Level)
+	err := c.cc.Invoke(ctx, LoggerAdmin_GetLevel_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *loggerAdminClient) SetLevel(ctx context.Context, in *Level, opts ...grpc.CallOption) (*Level, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(Level)
+	err := c.cc.Invoke(ctx, LoggerAdmin_SetLevel_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *loggerAdminClient) GetStatus(ctx context.Context, in *GetStatusRequest, opts ...grpc.CallOption) (*Status, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(Status)
+	err := c.cc.Invoke(ctx, LoggerAdmin_GetStatus_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *loggerAdminClient) Tail(ctx context.Context, in *TailRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[TailChunk], error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &LoggerAdmin_ServiceDesc.Streams[0], LoggerAdmin_Tail_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &grpc.GenericClientStream[TailRequest, TailChunk]{ClientStream: stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type LoggerAdmin_TailClient = grpc.ServerStreamingClient[TailChunk]
+
+// LoggerAdminServer is the server API for LoggerAdmin service.
+// All implementations must embed UnimplementedLoggerAdminServer
+// for forward compatibility.
+type LoggerAdminServer interface {
+	// Logger.GetLevel
+	GetLevel(context.Context, *GetLevelRequest) (*Level, error)
+	// Logger.SetLevel (names as accepted by speedlog.ParseLevel)
+	SetLevel(context.Context, *Level) (*Level, error)
+	// Logger.Stats and Logger.Healthy
+	GetStatus(context.Context, *GetStatusRequest) (*Status, error)
+	// Logger.Tail
+	Tail(*TailRequest, grpc.ServerStreamingServer[TailChunk]) error
+	mustEmbedUnimplementedLoggerAdminServer()
+}
+
+// UnimplementedLoggerAdminServer must be embedded to have
+// forward compatible implementations.
+//
+// NOTE: this should be embedded by value instead of pointer to avoid a nil
+// pointer dereference when methods are called.
+type UnimplementedLoggerAdminServer struct{}
+
+func (UnimplementedLoggerAdminServer) GetLevel(context.Context, *GetLevelRequest) (*Level, error) {
+	return nil, status.Error(codes.Unimplemented, "method GetLevel not implemented")
+}
+func (UnimplementedLoggerAdminServer) SetLevel(context.Context, *Level) (*Level, error) {
+	return nil, status.Error(codes.Unimplemented, "method SetLevel not implemented")
+}
+func (UnimplementedLoggerAdminServer) GetStatus(context.Context, *GetStatusRequest) (*Status, error) {
+	return nil, status.Error(codes.Unimplemented, "method GetStatus not implemented")
+}
+func (UnimplementedLoggerAdminServer) Tail(*TailRequest, grpc.ServerStreamingServer[TailChunk]) error {
+	return status.Error(codes.Unimplemented, "method Tail not implemented")
+}
+func (UnimplementedLoggerAdminServer) mustEmbedUnimplementedLoggerAdminServer() {}
+func (UnimplementedLoggerAdminServer) testEmbeddedByValue()                     {}
+
+// UnsafeLoggerAdminServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to LoggerAdminServer will
+// result in compilation errors.
+type UnsafeLoggerAdminServer interface {
+	mustEmbedUnimplementedLoggerAdminServer()
+}
+
+func RegisterLoggerAdminServer(s grpc.ServiceRegistrar, srv LoggerAdminServer) {
+	// If the following call panics, it indicates UnimplementedLoggerAdminServer was
+	// embedded by pointer and is nil.  This will cause panics if an
+	// unimplemented method is ever invoked, so we test this at initialization
+	// time to prevent it from happening at runtime later due to I/O.
+	if t, ok := srv.(interface{ testEmbeddedByValue() }); ok {
+		t.testEmbeddedByValue()
+	}
+	s.RegisterService(&LoggerAdmin_ServiceDesc, srv)
+}
+
+func _LoggerAdmin_GetLevel_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetLevelRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(LoggerAdminServer).GetLevel(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: LoggerAdmin_GetLevel_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(LoggerAdminServer).GetLevel(ctx, req.(*GetLevelRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _LoggerAdmin_SetLevel_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(Level)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(LoggerAdminServer).SetLevel(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: LoggerAdmin_SetLevel_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(LoggerAdminServer).SetLevel(ctx, req.(*Level))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _LoggerAdmin_GetStatus_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetStatusRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(LoggerAdminServer).GetStatus(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: LoggerAdmin_GetStatus_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(LoggerAdminServer).GetStatus(ctx, req.(*GetStatusRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _LoggerAdmin_Tail_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(TailRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(LoggerAdminServer).Tail(m, &grpc.GenericServerStream[TailRequest, TailChunk]{ServerStream: stream})
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type LoggerAdmin_TailServer = grpc.ServerStreamingServer[TailChunk]
+
+// LoggerAdmin_ServiceDesc is the grpc.ServiceDesc for LoggerAdmin service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var LoggerAdmin_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "speedlog.admin.v1.LoggerAdmin",
+	HandlerType: (*LoggerAdminServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "GetLevel",
+			Handler:    _LoggerAdmin_GetLevel_Handler,
+		},
+		{
+			MethodName: "SetLevel",
+			Handler:    _LoggerAdmin_SetLevel_Handler,
+		},
+		{
+			MethodName: "GetStatus",
+			Handler:    _LoggerAdmin_GetStatus_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "Tail",
+			Handler:       _LoggerAdmin_Tail_Handler,
+			ServerStreams: true,
+		},
+	},
+	Metadata: "admin.proto",
+}
@@ -0,0 +1,96 @@
+package grpclog
+
+import (
+	"context"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+
+	"speedlog"
+	"speedlog/httplog"
+)
+
+// Metadata keys carrying the request ID and the W3C trace context.
+const (
+	RequestIDKey   = "x-request-id"
+	TraceparentKey = "traceparent"
+)
+
+// PropagateUnary is the client side of request correlation: like
+// httplog.Propagate, it adds the request ID and traceparent found in the
+// call's context to the outgoing metadata. Metadata already set wins.
+func PropagateUnary() grpc.UnaryClientInterceptor {
+	return func(ctx context.Context, method string, req, reply any, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+		return invoker(outgoing(ctx), method, req, reply, cc, opts...)
+	}
+}
+
+func PropagateStream() grpc.StreamClientInterceptor {
+	return func(ctx context.Context, desc *grpc.StreamDesc, cc *grpc.ClientConn, method string, streamer grpc.Streamer, opts ...grpc.CallOption) (grpc.ClientStream, error) {
+		return streamer(outgoing(ctx), desc, cc, method, opts...)
+	}
+}
+
+func outgoing(ctx context.Context) context.Context {
+	md, _ := metadata.FromOutgoingContext(ctx)
+	var kv []string
+	if id := httplog.RequestIDFrom(ctx); id != "" && len(md.Get(RequestIDKey)) == 0 {
+		kv = append(kv, RequestIDKey, id)
+	}
+	if tc, ok := httplog.TraceFrom(ctx); ok && len(md.Get(TraceparentKey)) == 0 {
+		kv = append(kv, TraceparentKey, tc.String())
+	}
+	if len(kv) == 0 {
+		return ctx
+	}
+	return metadata.AppendToOutgoingContext(ctx, kv...)
+}
+
+// RequestIDUnary is the server side, httplog.RequestID and httplog.Trace
+// in one: it reuses the caller's x-request-id (or generates one), echoes
+// it in the response header, and puts l with request_id, trace_id and
+// span_id fields into the context, where PropagateUnary finds them for
+// the next hop.
+func RequestIDUnary(l *speedlog.Logger) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, _ *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		ctx, id := incoming(ctx, l)
+		_ = grpc.SetHeader(ctx, metadata.Pairs(RequestIDKey, id))
+		return handler(ctx, req)
+	}
+}
+
+func RequestIDStream(l *speedlog.Logger) grpc.StreamServerInterceptor {
+	return func(srv any, ss grpc.ServerStream, _ *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		ctx, id := incoming(ss.Context(), l)
+		_ = ss.SetHeader(metadata.Pairs(RequestIDKey, id))
+		return handler(srv, serverStream{ss, ctx})
+	}
+}
+
+func incoming(ctx context.Context, l *speedlog.Logger) (context.Context, string) {
+	md, _ := metadata.FromIncomingContext(ctx)
+	id := first(md, RequestIDKey)
+	if !httplog.ValidID(id) {
+		id = httplog.NewID()
+	}
+	ctx = httplog.ContextWithRequestID(speedlog.NewContext(ctx, l), httplog.DefaultHeader, "request_id", id)
+	if tc, err := httplog.ParseTraceparent(first(md, TraceparentKey)); err == nil {
+		ctx = httplog.ContextWithTrace(ctx, tc)
+	}
+	return ctx, id
+}
+
+func first(md metadata.MD, key string) string {
+	if v := md.Get(key); len(v) > 0 {
+		return v[0]
+	}
+	return ""
+}
+
+// serverStream replaces the context of a stream.
+type serverStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s serverStream) Context() context.Context { return s.ctx }
@@ -0,0 +1,62 @@
+package speedlog
+
+import (
+	"errors"
+	"time"
+)
+
+const (
+	stallAfter    = 5 * time.Second
+	saturateAfter = 5 * time.Second
+)
+
+var (
+	ErrClosed         = errors.New("speedlog: logger closed")
+	ErrWriterStalled  = errors.New("speedlog: writer goroutine is not making progress")
+	ErrQueueSaturated = errors.New("speedlog: queue has been nearly full for a while")
+)
+
+// Healthy reports problems with the pipeline, suitable for a readiness
+// probe: a closed logger, a writer stuck in a write or sitting on queued
+// work for a while, a queue that stays at least 90% full, and sinks that are
+// ejected or whose last write failed (as *SinkError). nil means healthy.
+func (l *Logger) Healthy() error {
+	l.lifeMu.Lock()
+	closed, running := l.closed, l.running
+	l.lifeMu.Unlock()
+	if closed {
+		return ErrClosed
+	}
+	var errs []error
+	busy := l.busySince.Load()
+	switch {
+	case busy != 0 && time.Since(time.Unix(0, busy)) > stallAfter:
+		errs = append(errs, ErrWriterStalled)
+	case running && l.queueLen() > 0 && time.Since(time.Unix(0, l.beat.Load())) > stallAfter+l.flushMax:
+		errs = append(errs, ErrWriterStalled)
+	}
+	if since := l.saturatedSince.Load(); since != 0 && time.Since(time.Unix(0, since)) > saturateAfter {
+		errs = append(errs, ErrQueueSaturated)
+	}
+	for _, s := range l.sinkList() {
+		switch {
+		case s.ejected.Load():
+			errs = append(errs, &SinkError{Sink: s.id, Err: ErrSinkEjected})
+		case s.failing.Load() != nil:
+			errs = append(errs, s.failing.Load())
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func Healthy() error { return defaultLogger().Healthy() }
+
+// heartbeat is called by the writer on every tick and batch.
+func (l *Logger) heartbeat(now time.Time) {
+	l.beat.Store(now.UnixNano())
+	if c := l.queueCap(); c > 0 && l.queueLen()*10 >= c*9 {
+		l.saturatedSince.CompareAndSwap(0, now.UnixNano())
+	} else {
+		l.saturatedSince.Store(0)
+	}
+}
@@ -0,0 +1,38 @@
+package speedlog
+
+import (
+	"encoding/hex"
+	"strconv"
+	"strings"
+)
+
+// HexDump renders at most limit bytes of b as a `hexdump -C` style block,
+// headed by name and the total length. limit <= 0 means 256.
+func HexDump(name string, b []byte, limit int) string {
+	if limit <= 0 {
+		limit = 256
+	}
+	var sb strings.Builder
+	sb.WriteString(name)
+	sb.WriteString(" (")
+	sb.WriteString(strconv.Itoa(len(b)))
+	sb.WriteString(" bytes):\n")
+	shown := b[:min(len(b), limit)]
+	sb.WriteString(strings.TrimSuffix(hex.Dump(shown), "\n"))
+	if rest := len(b) - len(shown); rest > 0 {
+		sb.WriteString("\n... ")
+		sb.WriteString(strconv.Itoa(rest))
+		sb.WriteString(" more bytes")
+	}
+	return sb.String()
+}
+
+// Hex is a field with b hex-encoded on a single line. Only the first 64
+// bytes are kept; longer values end in "...".
+func Hex(key string, b []byte) Field {
+	const max = 64
+	if len(b) <= max {
+		return String(key, hex.EncodeToString(b))
+	}
+	return String(key, hex.EncodeToString(b[:max])+"...")
+}
@@ -0,0 +1,207 @@
+package speedlog
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// honeycombMaxBatch is the API's limit on one batch request body.
+const honeycombMaxBatch = 5 << 20
+
+// HoneycombConfig describes the dataset a HoneycombWriter sends events to.
+type HoneycombConfig struct {
+	APIKey  string
+	Dataset string
+	APIHost string // defaults to https://api.honeycomb.io
+
+	// LevelKey and MessageKey name the columns for the level and message;
+	// they default to level and message.
+	LevelKey   string
+	MessageKey string
+	// Rename maps field keys to column names. Group members are flattened
+	// to dotted keys (http.status) before renaming.
+	Rename map[string]string
+
+	// Codec compresses request bodies, e.g. Gzip(gzip.BestSpeed) or
+	// Zstd(); nil sends them as is.
+	Codec Codec
+
+	Client *http.Client // defaults to a client with a 10s timeout
+}
+
+// HoneycombWriter is a sink that sends every line as one Honeycomb event,
+// each sink write as one /1/batch request (split at 5 MiB), so the sink
+// buffer sets the batch size. Lines are parsed back with ParseLine; use
+// JSONEncoder to keep field types.
+type HoneycombWriter struct {
+	mu     sync.Mutex
+	cfg    HoneycombConfig
+	url    string
+	client *http.Client
+	body   []byte
+	zbody  bytes.Buffer
+	event  []byte
+	events int
+	lines  partialLines
+}
+
+func NewHoneycombWriter(cfg HoneycombConfig) (*HoneycombWriter, error) {
+	if cfg.APIKey == "" || cfg.Dataset == "" {
+		return nil, errors.New("speedlog: Honeycomb API key and dataset are required")
+	}
+	if cfg.APIHost == "" {
+		cfg.APIHost = "https://api.honeycomb.io"
+	}
+	if cfg.LevelKey == "" {
+		cfg.LevelKey = "level"
+	}
+	if cfg.MessageKey == "" {
+		cfg.MessageKey = "message"
+	}
+	if _, err := url.Parse(cfg.APIHost); err != nil {
+		return nil, err
+	}
+	client := cfg.Client
+	if client == nil {
+		client = &http.Client{Timeout: 10 * time.Second}
+	}
+	return &HoneycombWriter{
+		cfg:    cfg,
+		url:    strings.TrimRight(cfg.APIHost, "/") + "/1/batch/" + url.PathEscape(cfg.Dataset),
+		client: client,
+	}, nil
+}
+
+func (w *HoneycombWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.lines.write(p, w.send)
+}
+
+func (w *HoneycombWriter) send(lines []byte) error {
+	w.body, w.events = append(w.body[:0], '['), 0
+	var errs []error
+	err := eachLine(lines, func(line []byte) error {
+		e, err := ParseLine(line)
+		if err != nil {
+			e = Entry{Time: time.Now(), Level: INFO, Message: string(line)}
+		}
+		w.event = w.appendEvent(w.event[:0], &e)
+		if w.events > 0 && len(w.body)+len(w.event)+2 > honeycombMaxBatch {
+			errs = append(errs, w.post())
+			w.body, w.events = append(w.body[:0], '['), 0
+		}
+		if w.events > 0 {
+			w.body = append(w.body, ',')
+		}
+		w.body = append(w.body, w.event...)
+		w.events++
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	if w.events > 0 {
+		errs = append(errs, w.post())
+	}
+	return errors.Join(errs...)
+}
+
+// post sends the batch in body. The API answers 200 with one status per
+// event, so a partly rejected batch is only visible in the response.
+func (w *HoneycombWriter) post() error {
+	w.body = append(w.body, ']')
+	body := w.body
+	if w.cfg.Codec != nil {
+		var err error
+		if body, err = compressBody(w.cfg.Codec, &w.zbody, w.body); err != nil {
+			return err
+		}
+	}
+	req, err := http.NewRequest(http.MethodPost, w.url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if w.cfg.Codec != nil {
+		req.Header.Set("Content-Encoding", w.cfg.Codec.Name())
+	}
+	req.Header.Set("X-Honeycomb-Team", w.cfg.APIKey)
+	resp, err := w.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("speedlog: honeycomb batch: %s: %s", resp.Status, bytes.TrimSpace(msg))
+	}
+	var statuses []struct {
+		Status int    `json:"status"`
+		Error  string `json:"error"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&statuses); err != nil {
+		return nil
+	}
+	rejected, first := 0, ""
+	for _, st := range statuses {
+		if st.Status/100 != 2 {
+			if rejected++; first == "" {
+				first = st.Error
+			}
+		}
+	}
+	if rejected > 0 {
+		return fmt.Errorf("speedlog: honeycomb rejected %d of %d events: %s", rejected, w.events, first)
+	}
+	return nil
+}
+
+func (w *HoneycombWriter) appendEvent(buf []byte, e *Entry) []byte {
+	buf = append(buf, `{"time":"`...)
+	buf = e.Time.UTC().AppendFormat(buf, time.RFC3339Nano)
+	buf = append(buf, `","data":{`...)
+	buf = appendJSONString(buf, w.cfg.LevelKey)
+	buf = append(buf, ':')
+	buf = appendJSONString(buf, LevelName(e.Level))
+	buf = append(buf, ',')
+	buf = appendJSONString(buf, w.cfg.MessageKey)
+	buf = append(buf, ':')
+	buf = appendJSONString(buf, e.Message)
+	buf = w.appendData(buf, "", e.Fields)
+	return append(buf, "}}"...)
+}
+
+func (w *HoneycombWriter) appendData(buf []byte, prefix string, fields []Field) []byte {
+	for i := range fields {
+		f := &fields[i]
+		key := prefix + f.Key
+		if f.kind == groupKind {
+			buf = w.appendData(buf, key+".", f.val.([]Field))
+			continue
+		}
+		if to, ok := w.cfg.Rename[key]; ok {
+			key = to
+		}
+		buf = append(buf, ',')
+		buf = appendJSONString(buf, key)
+		buf = append(buf, ':')
+		buf = appendJSONValue(buf, f)
+	}
+	return buf
+}
+
+// Close sends a trailing line that lacked a newline.
+func (w *HoneycombWriter) Close() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.lines.flush(w.send)
+}
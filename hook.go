@@ -0,0 +1,97 @@
+package speedlog
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+)
+
+// ErrDropEntry, returned by a hook, vetoes the entry: later hooks don't
+// run and nothing is logged.
+var ErrDropEntry = errors.New("speedlog: entry dropped by hook")
+
+// Hook runs on the logging goroutine before the entry is encoded. It may
+// change the message, level, time and fields in place: e.Fields is the
+// entry's own copy, so overwriting an element (redaction) or appending is
+// safe. Any error other than ErrDropEntry is reported, and the entry goes
+// on to the next hook as the failing one left it.
+type Hook func(e *Entry) error
+
+// HookError is what the error handler gets when a hook fails or panics.
+type HookError struct {
+	Hook string
+	Err  error
+}
+
+func (e *HookError) Error() string { return fmt.Sprintf("hook %s: %v", e.Hook, e.Err) }
+
+func (e *HookError) Unwrap() error { return e.Err }
+
+type hook struct {
+	name  string
+	level int
+	fn    Hook
+}
+
+// WithHook adds a hook. Hooks run in the order they were added, each
+// seeing the previous ones' changes, on every entry from the level
+// methods, LogBatch and Ingest (not raw lines, which have no fields).
+func WithHook(name string, fn Hook) Option {
+	return func(l *Logger) {
+		if fn == nil {
+			l.invalid("WithHook(%q, nil)", name)
+			return
+		}
+		l.hooks = append(l.hooks, hook{name: name, level: DEBUG, fn: fn})
+	}
+}
+
+// WithLevelHook adds a hook that only runs on entries at level or above,
+// as they stand when it's reached, so an expensive hook (error reporting,
+// alerting) costs nothing on DEBUG and INFO traffic. It keeps its place
+// in the order among WithHook hooks.
+func WithLevelHook(name string, level int, fn Hook) Option {
+	return func(l *Logger) {
+		if fn == nil {
+			l.invalid("WithLevelHook(%q, %d, nil)", name, level)
+			return
+		}
+		l.hooks = append(l.hooks, hook{name: name, level: level, fn: fn})
+	}
+}
+
+// runHooks reports whether e survived the hooks.
+func (l *Logger) runHooks(e *Entry) bool {
+	cloned, t := false, e.Time
+	for i := range l.hooks {
+		if e.Level < l.hooks[i].level {
+			continue
+		}
+		if !cloned {
+			e.Fields, cloned = slices.Clone(e.Fields), true
+		}
+		if err := l.hooks[i].call(e); err != nil {
+			if errors.Is(err, ErrDropEntry) {
+				return false
+			}
+			if l.onError != nil {
+				l.onError(&HookError{Hook: l.hooks[i].name, Err: err})
+			}
+		}
+	}
+	if !e.Time.Equal(t) {
+		e.ts = nil
+	}
+	return true
+}
+
+// call turns a panic into an error so one broken hook can't take the
+// caller down with it.
+func (h *hook) call(e *Entry) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("panic: %v", r)
+		}
+	}()
+	return h.fn(e)
+}
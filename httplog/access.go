@@ -0,0 +1,125 @@
+package httplog
+
+import (
+	"net/http"
+	"sync/atomic"
+	"time"
+
+	"speedlog"
+)
+
+type AccessOption func(*access)
+
+type access struct {
+	slow     time.Duration
+	sample   uint64
+	errorAt  int
+	ordinary atomic.Uint64
+}
+
+// WithSlowThreshold logs requests that take d or longer at WARN, whatever
+// the sampling (default 1s; 0 turns it off).
+func WithSlowThreshold(d time.Duration) AccessOption {
+	return func(a *access) {
+		if d >= 0 {
+			a.slow = d
+		}
+	}
+}
+
+// WithSampling logs one in n of the ordinary requests (fast, no error
+// status) at INFO, with a sample_rate=n field to scale counts back up.
+// 1, the default, logs them all; 0 logs none.
+func WithSampling(n int) AccessOption {
+	return func(a *access) {
+		if n >= 0 {
+			a.sample = uint64(n)
+		}
+	}
+}
+
+// WithErrorStatus sets the lowest status logged at WARN as an error
+// (default 500; 400 includes client errors).
+func WithErrorStatus(code int) AccessOption {
+	return func(a *access) {
+		if code > 0 {
+			a.errorAt = code
+		}
+	}
+}
+
+// AccessLog logs one "request" entry per request with method, path,
+// status, bytes and duration. Slow requests and error statuses are always
+// logged, at WARN, the rest at INFO as sampled, which keeps access logs
+// useful at high request rates. Put it inside RequestID and Trace so the
+// entries carry their fields.
+func AccessLog(l *speedlog.Logger, opts ...AccessOption) func(http.Handler) http.Handler {
+	a := &access{slow: time.Second, sample: 1, errorAt: http.StatusInternalServerError}
+	for _, opt := range opts {
+		opt(a)
+	}
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			start := time.Now()
+			rec := &recorder{ResponseWriter: w}
+			next.ServeHTTP(rec, r)
+			d := time.Since(start)
+			if rec.status == 0 {
+				rec.status = http.StatusOK
+			}
+			level, rate := speedlog.WARN, uint64(0)
+			if (a.slow == 0 || d < a.slow) && rec.status < a.errorAt {
+				if a.sample == 0 || (a.ordinary.Add(1)-1)%a.sample != 0 {
+					return
+				}
+				level, rate = speedlog.INFO, a.sample
+			}
+			if !l.IsLevelEnabled(level) {
+				return
+			}
+			fields := []speedlog.Field{
+				speedlog.String("method", r.Method),
+				speedlog.String("path", r.URL.Path),
+				speedlog.Int("status", rec.status),
+				speedlog.Int64("bytes", rec.bytes),
+				speedlog.Duration("duration", d),
+			}
+			if rate > 1 {
+				fields = append(fields, speedlog.Uint64("sample_rate", rate))
+			}
+			l.Ctx(r.Context()).Log(level, "request", fields...)
+		})
+	}
+}
+
+// recorder notes the status and body size; Unwrap keeps
+// http.ResponseController (flushing, deadlines, hijacking) working.
+type recorder struct {
+	http.ResponseWriter
+	status int
+	bytes  int64
+}
+
+func (r *recorder) WriteHeader(code int) {
+	// 1xx responses other than 101 Switching Protocols come before the
+	// final status.
+	if r.status == 0 && (code >= 200 || code == http.StatusSwitchingProtocols) {
+		r.status = code
+	}
+	r.ResponseWriter.WriteHeader(code)
+}
+
+func (r *recorder) Write(p []byte) (int, error) {
+	if r.status == 0 {
+		r.status = http.StatusOK
+	}
+	n, err := r.ResponseWriter.Write(p)
+	r.bytes += int64(n)
+	return n, err
+}
+
+func (r *recorder) Flush() {
+	_ = http.NewResponseController(r.ResponseWriter).Flush()
+}
+
+func (r *recorder) Unwrap() http.ResponseWriter { return r.ResponseWriter }
@@ -0,0 +1,111 @@
+// Package httplog wires speedlog into net/http.
+package httplog
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"net/http"
+
+	"speedlog"
+)
+
+const DefaultHeader = "X-Request-ID"
+
+type Option func(*config)
+
+type config struct {
+	header string
+	field  string
+	gen    func() string
+}
+
+// WithHeader changes the request/response header (default X-Request-ID).
+func WithHeader(name string) Option {
+	return func(c *config) {
+		if name != "" {
+			c.header = http.CanonicalHeaderKey(name)
+		}
+	}
+}
+
+// WithField changes the log field name (default "request_id").
+func WithField(key string) Option {
+	return func(c *config) {
+		if key != "" {
+			c.field = key
+		}
+	}
+}
+
+func WithGenerator(gen func() string) Option {
+	return func(c *config) {
+		if gen != nil {
+			c.gen = gen
+		}
+	}
+}
+
+type requestIDKey struct{}
+
+// NewID returns 16 random bytes, hex-encoded.
+func NewID() string {
+	var b [16]byte
+	_, _ = rand.Read(b[:])
+	return hex.EncodeToString(b[:])
+}
+
+// RequestID takes the request ID from the incoming header (or generates
+// one), echoes it in the response header and stores it in the request
+// context, both as a field for speedlog.FromContext and for
+// RequestIDFrom.
+func RequestID(l *speedlog.Logger, opts ...Option) func(http.Handler) http.Handler {
+	c := config{header: DefaultHeader, field: "request_id", gen: NewID}
+	for _, opt := range opts {
+		opt(&c)
+	}
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			id := r.Header.Get(c.header)
+			if !ValidID(id) {
+				id = c.gen()
+			}
+			w.Header().Set(c.header, id)
+			ctx := speedlog.NewContext(r.Context(), l)
+			next.ServeHTTP(w, r.WithContext(ContextWithRequestID(ctx, c.header, c.field, id)))
+		})
+	}
+}
+
+type requestID struct {
+	header string
+	id     string
+}
+
+// ContextWithRequestID does what RequestID does to the request context,
+// for servers outside net/http: id becomes the field key of the context
+// logger and is returned by RequestIDFrom, and Propagate sends it on under
+// header.
+func ContextWithRequestID(ctx context.Context, header, key, id string) context.Context {
+	ctx = context.WithValue(ctx, requestIDKey{}, requestID{header: header, id: id})
+	return speedlog.ContextWith(ctx, speedlog.String(key, id))
+}
+
+func RequestIDFrom(ctx context.Context) string {
+	rid, _ := ctx.Value(requestIDKey{}).(requestID)
+	return rid.id
+}
+
+// ValidID accepts up to 128 printable ASCII characters from the client;
+// anything else is replaced rather than written to the logs.
+func ValidID(id string) bool {
+	if id == "" || len(id) > 128 {
+		return false
+	}
+	for i := 0; i < len(id); i++ {
+		if id[i] <= ' ' || id[i] > '~' {
+			return false
+		}
+	}
+	return true
+}
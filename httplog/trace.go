@@ -0,0 +1,93 @@
+package httplog
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"speedlog"
+)
+
+var ErrTraceparent = errors.New("httplog: malformed traceparent")
+
+// TraceContext is the part of a W3C traceparent header that matters for
+// log correlation.
+type TraceContext struct {
+	TraceID string // 32 lowercase hex digits
+	SpanID  string // 16 lowercase hex digits, the caller's span
+	Flags   byte
+}
+
+func (tc TraceContext) Sampled() bool { return tc.Flags&1 == 1 }
+
+func (tc TraceContext) String() string {
+	return "00-" + tc.TraceID + "-" + tc.SpanID + "-" + hex2(tc.Flags)
+}
+
+func hex2(b byte) string {
+	const digits = "0123456789abcdef"
+	return string([]byte{digits[b>>4], digits[b&0xf]})
+}
+
+// ParseTraceparent parses "version-traceid-parentid-flags" as defined
+// by W3C Trace Context, including the rules for all-zero IDs and the
+// forward-compatible handling of versions after 00.
+func ParseTraceparent(s string) (TraceContext, error) {
+	s = strings.TrimSpace(s)
+	if len(s) < 55 || s[2] != '-' || s[35] != '-' || s[52] != '-' {
+		return TraceContext{}, ErrTraceparent
+	}
+	version, trace, span, flags := s[:2], s[3:35], s[36:52], s[53:55]
+	if !isHex(version) || version == "ff" || !isHex(trace) || !isHex(span) || !isHex(flags) {
+		return TraceContext{}, ErrTraceparent
+	}
+	if version == "00" && len(s) != 55 || version != "00" && len(s) > 55 && s[55] != '-' {
+		return TraceContext{}, ErrTraceparent
+	}
+	if strings.Trim(trace, "0") == "" || strings.Trim(span, "0") == "" {
+		return TraceContext{}, ErrTraceparent
+	}
+	f, _ := strconv.ParseUint(flags, 16, 8)
+	return TraceContext{TraceID: trace, SpanID: span, Flags: byte(f)}, nil
+}
+
+func isHex(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if c := s[i]; (c < '0' || c > '9') && (c < 'a' || c > 'f') {
+			return false
+		}
+	}
+	return true
+}
+
+type traceKey struct{}
+
+// Trace reads the traceparent header and, when it is valid, adds
+// trace_id and span_id fields to the request's context logger. No tracing
+// SDK involved.
+func Trace() func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			tc, err := ParseTraceparent(r.Header.Get("Traceparent"))
+			if err != nil {
+				next.ServeHTTP(w, r)
+				return
+			}
+			next.ServeHTTP(w, r.WithContext(ContextWithTrace(r.Context(), tc)))
+		})
+	}
+}
+
+// ContextWithTrace does what Trace does to the request context, for
+// servers outside net/http.
+func ContextWithTrace(ctx context.Context, tc TraceContext) context.Context {
+	ctx = context.WithValue(ctx, traceKey{}, tc)
+	return speedlog.ContextWith(ctx, speedlog.String("trace_id", tc.TraceID), speedlog.String("span_id", tc.SpanID))
+}
+
+func TraceFrom(ctx context.Context) (TraceContext, bool) {
+	tc, ok := ctx.Value(traceKey{}).(TraceContext)
+	return tc, ok
+}
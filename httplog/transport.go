@@ -0,0 +1,39 @@
+package httplog
+
+import "net/http"
+
+// Propagate wraps base (http.DefaultTransport when nil) so outgoing
+// requests carry the request ID and traceparent found in their context,
+// under the same header the inbound middleware read them from. Headers
+// already set on the request win. speedlog/grpclog has the same for gRPC
+// clients.
+func Propagate(base http.RoundTripper) http.RoundTripper {
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	return propagator{base}
+}
+
+type propagator struct {
+	base http.RoundTripper
+}
+
+func (p propagator) RoundTrip(r *http.Request) (*http.Response, error) {
+	ctx := r.Context()
+	rid, _ := ctx.Value(requestIDKey{}).(requestID)
+	tc, traced := TraceFrom(ctx)
+	setID := rid.id != "" && r.Header.Get(rid.header) == ""
+	setTrace := traced && r.Header.Get("Traceparent") == ""
+	if !setID && !setTrace {
+		return p.base.RoundTrip(r)
+	}
+	// A RoundTripper must not modify the caller's request.
+	r = r.Clone(ctx)
+	if setID {
+		r.Header.Set(rid.header, rid.id)
+	}
+	if setTrace {
+		r.Header.Set("Traceparent", tc.String())
+	}
+	return p.base.RoundTrip(r)
+}
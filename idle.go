@@ -0,0 +1,63 @@
+package speedlog
+
+import "time"
+
+// With an idle timeout the writer and timestamp goroutines exit after a
+// quiet period and the next log call starts them again. Producers bump
+// queued before checking sleeping and the writer sets sleeping before
+// checking queued (both under lifeMu on the writer side), so an entry can
+// never be left in the queue with nobody running to drain it.
+func WithIdleTimeout(d time.Duration) Option {
+	return func(l *Logger) {
+		if d <= 0 {
+			l.invalid("WithIdleTimeout(%v): timeout must be positive", d)
+			return
+		}
+		l.idleAfter = d
+	}
+}
+
+func (l *Logger) startWriter() {
+	l.running = true
+	l.sleeping.Store(false)
+	l.beat.Store(time.Now().UnixNano())
+	l.wg.Add(1)
+	go l.writerLoop()
+}
+
+func (l *Logger) wake() {
+	l.lifeMu.Lock()
+	defer l.lifeMu.Unlock()
+	if l.running || l.closed {
+		return
+	}
+	l.ts.Store(newTSCache())
+	l.startWriter()
+}
+
+func (l *Logger) park() bool {
+	l.lifeMu.Lock()
+	defer l.lifeMu.Unlock()
+	l.sleeping.Store(true)
+	if l.queued.Load() > 0 || l.closed {
+		l.sleeping.Store(false)
+		return false
+	}
+	l.running = false
+	return true
+}
+
+func (l *Logger) enter() {
+	if l.idleAfter > 0 {
+		l.queued.Add(1)
+		if l.sleeping.Load() {
+			l.wake()
+		}
+	}
+}
+
+func (l *Logger) dropped() {
+	if l.idleAfter > 0 {
+		l.queued.Add(-1)
+	}
+}
@@ -0,0 +1,203 @@
+package speedlog
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"strings"
+	"time"
+)
+
+type IngestFormat int
+
+const (
+	// IngestPlain logs every line as its message.
+	IngestPlain IngestFormat = iota
+	// IngestLevelPrefix reads a leading level such as "WARN", "[error]",
+	// "INF:" or "warning:" and strips it from the message.
+	IngestLevelPrefix
+	// IngestJSON reads one object per line, taking level, msg and time
+	// from the usual keys and the rest as fields in order. Lines that
+	// aren't JSON objects are logged as plain text.
+	IngestJSON
+)
+
+type IngestOptions struct {
+	Format IngestFormat
+	// Level applies to lines that don't name one; the zero value is
+	// DEBUG, so set it explicitly (usually INFO).
+	Level int
+	// Fields are added to every entry, e.g. String("source", "sidecar").
+	Fields []Field
+}
+
+// Ingest reads lines from r until EOF and logs each one through l, so
+// speedlog can forward a sidecar pipe or a journal export. Lines longer
+// than 1 MiB stop it with bufio.ErrTooLong. Suppression and Deferred don't
+// apply; a parsed time is kept.
+func (l *Logger) Ingest(r io.Reader, opts IngestOptions) error {
+	sc := bufio.NewScanner(r)
+	sc.Buffer(make([]byte, 0, 64<<10), 1<<20)
+	for sc.Scan() {
+		line := bytes.TrimSuffix(sc.Bytes(), []byte{'\r'})
+		if len(bytes.TrimSpace(line)) == 0 {
+			continue
+		}
+		level, msg, t, fields := opts.Level, "", time.Time{}, []Field(nil)
+		switch opts.Format {
+		case IngestLevelPrefix:
+			level, msg = splitLevelPrefix(string(line), opts.Level)
+		case IngestJSON:
+			var ok bool
+			if level, msg, t, fields, ok = parseJSONLine(line, opts.Level); !ok {
+				level, msg, fields = opts.Level, string(line), nil
+			}
+		default:
+			msg = string(line)
+		}
+		if !l.IsLevelEnabled(level) {
+			continue
+		}
+		if len(opts.Fields) > 0 {
+			fields = append(opts.Fields[:len(opts.Fields):len(opts.Fields)], fields...)
+		}
+		e := l.entry(level, msg, fields)
+		if !t.IsZero() {
+			e.Time, e.ts = t, nil
+		}
+		if l.hooks != nil && !l.runHooks(&e) {
+			continue
+		}
+		l.emit(&e)
+	}
+	return sc.Err()
+}
+
+// levelAliases covers the short and syslog-ish names other tools print.
+var levelAliases = map[string]int{
+	"TRACE": DEBUG, "DBG": DEBUG, "INF": INFO, "NOTICE": INFO, "WRN": WARN,
+	"ERR": ERROR, "CRIT": ERROR, "CRITICAL": ERROR, "ALERT": FATAL, "EMERG": FATAL,
+}
+
+func parseLevelName(name string) (int, bool) {
+	if level, err := ParseLevel(name); err == nil {
+		return level, true
+	}
+	level, ok := levelAliases[strings.ToUpper(name)]
+	return level, ok
+}
+
+func splitLevelPrefix(line string, def int) (int, string) {
+	s := strings.TrimLeft(line, " \t")
+	word, rest := s, ""
+	if i := strings.IndexAny(s, " \t"); i >= 0 {
+		word, rest = s[:i], s[i+1:]
+	}
+	word = strings.TrimSuffix(word, ":")
+	if len(word) > 2 && (word[0] == '[' && word[len(word)-1] == ']' || word[0] == '<' && word[len(word)-1] == '>') {
+		word = word[1 : len(word)-1]
+	}
+	level, ok := parseLevelName(word)
+	if !ok {
+		return def, line
+	}
+	return level, strings.TrimLeft(rest, " \t")
+}
+
+var errNotObject = errors.New("not a JSON object")
+
+// parseJSONLine keeps the object's key order, which a map would lose.
+func parseJSONLine(line []byte, def int) (level int, msg string, t time.Time, fields []Field, ok bool) {
+	dec := json.NewDecoder(bytes.NewReader(line))
+	dec.UseNumber()
+	fields, err := decodeObject(dec)
+	if err != nil || dec.More() {
+		return 0, "", time.Time{}, nil, false
+	}
+	level = def
+	kept := fields[:0]
+	for _, f := range fields {
+		switch s, isStr := f.Value().(string); {
+		case isStr && (f.Key == "level" || f.Key == "lvl" || f.Key == "severity"):
+			if lv, known := parseLevelName(s); known {
+				level = lv
+				continue
+			}
+		case f.kind == intKind && f.num >= DEBUG && f.num <= FATAL && (f.Key == "level" || f.Key == "lvl"):
+			level = int(f.num)
+			continue
+		case isStr && (f.Key == "msg" || f.Key == "message"):
+			msg = s
+			continue
+		case isStr && (f.Key == "time" || f.Key == "ts" || f.Key == "timestamp"):
+			if pt, perr := time.Parse(time.RFC3339Nano, s); perr == nil {
+				t = pt
+				continue
+			}
+		}
+		kept = append(kept, f)
+	}
+	return level, msg, t, kept, true
+}
+
+func decodeObject(dec *json.Decoder) ([]Field, error) {
+	tok, err := dec.Token()
+	if err != nil {
+		return nil, err
+	}
+	if d, ok := tok.(json.Delim); !ok || d != '{' {
+		return nil, errNotObject
+	}
+	var fields []Field
+	for dec.More() {
+		tok, err := dec.Token()
+		if err != nil {
+			return nil, err
+		}
+		key, _ := tok.(string)
+		f, err := decodeValue(dec, key)
+		if err != nil {
+			return nil, err
+		}
+		fields = append(fields, f)
+	}
+	_, err = dec.Token()
+	return fields, err
+}
+
+func decodeValue(dec *json.Decoder, key string) (Field, error) {
+	if !dec.More() {
+		return Field{}, errNotObject
+	}
+	var raw json.RawMessage
+	if err := dec.Decode(&raw); err != nil {
+		return Field{}, err
+	}
+	switch raw[0] {
+	case '{':
+		sub := json.NewDecoder(bytes.NewReader(raw))
+		sub.UseNumber()
+		fields, err := decodeObject(sub)
+		return Group(key, fields...), err
+	case '"':
+		var s string
+		err := json.Unmarshal(raw, &s)
+		return String(key, s), err
+	case 't', 'f':
+		return Bool(key, raw[0] == 't'), nil
+	case 'n':
+		return Any(key, nil), nil
+	case '[':
+		var v []any
+		err := json.Unmarshal(raw, &v)
+		return Any(key, v), err
+	}
+	n := json.Number(raw)
+	if i, err := n.Int64(); err == nil {
+		return Int64(key, i), nil
+	}
+	f, err := n.Float64()
+	return Float64(key, f), err
+}
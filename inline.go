@@ -0,0 +1,53 @@
+package speedlog
+
+// WithSyncLevel writes entries at level and above (e.g. ERROR) inline in
+// the logging call instead of queueing them: the line is written to every
+// sink and flushed (and fsynced, under an fsync policy) before the call
+// returns, so neither backpressure nor a crash right after can lose it.
+// Sinks with their own queue (WithQueue) get it queued and a flush
+// requested. Entries still in the queue may be written after it.
+func WithSyncLevel(level int) Option {
+	return func(l *Logger) {
+		l.inline = true
+		l.inlineLevel = level
+	}
+}
+
+func (l *Logger) writeInline(e *Entry, raw []byte, mask uint64) {
+	var line []byte
+	if raw != nil {
+		line = append(l.bufPool.get(len(raw)), raw...)
+	} else {
+		line = l.enc.Encode(l.bufPool.get(sizeHint(e)), *e)
+	}
+	if l.volume != nil {
+		l.volume.record(e.Level, e.Time, len(line))
+	}
+	batch := []record{{line: line, mask: mask, level: e.Level}}
+	l.inlineMu.Lock()
+	if !l.inlineDone {
+		l.entries.Add(1)
+		for _, s := range l.sinkList() {
+			if !s.wants(batch[0]) {
+				continue
+			}
+			if s.async() {
+				s.enqueue(batch[0])
+				s.requestFlush()
+				continue
+			}
+			s.writeRecords(batch, false)
+			s.flush()
+			if s.dirty && s.fsync.mode != fsyncNever {
+				s.sync()
+			}
+		}
+		if l.tail.n.Load() > 0 {
+			l.tail.publish(batch, len(line))
+		}
+	} else {
+		l.late(line)
+	}
+	l.inlineMu.Unlock()
+	l.bufPool.put(line)
+}
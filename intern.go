@@ -0,0 +1,68 @@
+package speedlog
+
+import (
+	"sync"
+	"sync/atomic"
+	"unsafe"
+)
+
+// jsonKeys is a direct-mapped cache of encoded `"key":` prefixes, indexed
+// by the address of the key's bytes. Keys are nearly always literals or
+// interned strings whose address never changes, so a hit is a pointer
+// compare and a copy instead of rescanning the key for characters to
+// escape. A slot is only claimed while empty, except by Intern, so keys
+// built on the fly can't churn it; the cached pointer keeps the key's
+// memory alive, so its address can't be reused by a different string.
+var jsonKeys [1024]atomic.Pointer[cachedKey]
+
+type cachedKey struct {
+	p   *byte
+	n   int
+	enc []byte
+}
+
+var interned sync.Map // string -> string
+
+// Intern returns a canonical copy of key and reserves a cache slot for
+// its JSON encoding. Use it for keys that come from config or other
+// runtime input so they share one string and one cached encoding.
+func Intern(key string) string {
+	if v, ok := interned.Load(key); ok {
+		return v.(string)
+	}
+	v, _ := interned.LoadOrStore(key, key)
+	key = v.(string)
+	if key != "" {
+		jsonKeys[keySlot(key)].Store(encodeKey(key))
+	}
+	return key
+}
+
+func keySlot(key string) uintptr {
+	p := uintptr(unsafe.Pointer(unsafe.StringData(key)))
+	return (p>>3 ^ uintptr(len(key))) % uintptr(len(jsonKeys))
+}
+
+func encodeKey(key string) *cachedKey {
+	enc := appendJSONString(nil, key)
+	return &cachedKey{p: unsafe.StringData(key), n: len(key), enc: append(enc, ':')}
+}
+
+func appendJSONKey(buf []byte, key string) []byte {
+	if key == "" {
+		return append(buf, `"":`...)
+	}
+	slot := &jsonKeys[keySlot(key)]
+	c := slot.Load()
+	if c == nil {
+		c = encodeKey(key)
+		if !slot.CompareAndSwap(nil, c) {
+			c = slot.Load()
+		}
+	}
+	if c.p == unsafe.StringData(key) && c.n == len(key) {
+		return append(buf, c.enc...)
+	}
+	buf = appendJSONString(buf, key)
+	return append(buf, ':')
+}
@@ -0,0 +1,219 @@
+package speedlog
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"reflect"
+	"strconv"
+	"time"
+	"unicode/utf8"
+)
+
+const jsonTimeLayout = "2006-01-02T15:04:05.000Z07:00"
+
+// JSONEncoder writes one object per line:
+// {"time":"...","level":"INFO","msg":"...",<fields>}. Groups become nested
+// objects.
+type JSONEncoder struct {
+	// ErrorChain adds "<key>_chain" after every error field: one
+	// {"type":...,"msg":...} object per layer of the errors.Unwrap chain,
+	// outermost first.
+	ErrorChain bool
+
+	// LevelNames overrides the "level" value per level; see
+	// LowercaseLevelNames.
+	LevelNames map[int]string
+
+	// NumericLevel writes the level as its number (DEBUG is 0) instead of
+	// its name, ignoring LevelNames.
+	NumericLevel bool
+
+	// TimeKey, LevelKey and MessageKey rename "time", "level" and "msg".
+	// CallerKey and StackKey rename the top-level "caller" and "stack"
+	// fields that WithCaller and stack capture add. Empty keeps the
+	// default. ParseLine only reads these back under the names it knows
+	// (ts, timestamp, lvl, severity, message and the defaults; a number
+	// only as level or lvl).
+	TimeKey, LevelKey, MessageKey, CallerKey, StackKey string
+}
+
+func (j JSONEncoder) Encode(buf []byte, e Entry) []byte {
+	buf = append(buf, '{')
+	buf = appendCoreKey(buf, j.TimeKey, `"time":`)
+	buf = append(buf, '"')
+	buf = e.Time.AppendFormat(buf, jsonTimeLayout)
+	buf = append(buf, `",`...)
+	buf = appendCoreKey(buf, j.LevelKey, `"level":`)
+	if j.NumericLevel {
+		buf = AppendInt(buf, int64(e.Level))
+	} else {
+		buf = appendJSONString(buf, levelName(j.LevelNames, e.Level))
+	}
+	buf = append(buf, ',')
+	buf = appendCoreKey(buf, j.MessageKey, `"msg":`)
+	buf = appendJSONString(buf, e.Message)
+	renamed := j.CallerKey != "" || j.StackKey != ""
+	for i := range e.Fields {
+		f := &e.Fields[i]
+		buf = append(buf, ',')
+		if renamed {
+			buf = appendJSONKey(buf, j.rename(f.Key))
+			buf = appendJSONValue(buf, f)
+		} else {
+			buf = appendJSONField(buf, f)
+		}
+		if j.ErrorChain && f.kind == errorKind && f.val != nil {
+			buf = append(buf, ',')
+			buf = appendJSONString(buf, f.Key+"_chain")
+			buf = append(buf, ':')
+			buf = appendErrorChain(buf, f.val.(error))
+		}
+	}
+	return append(buf, "}\n"...)
+}
+
+// appendCoreKey writes key, or the pre-encoded def when key is empty.
+func appendCoreKey(buf []byte, key, def string) []byte {
+	if key == "" {
+		return append(buf, def...)
+	}
+	buf = appendJSONString(buf, key)
+	return append(buf, ':')
+}
+
+func (j JSONEncoder) rename(key string) string {
+	switch {
+	case key == "caller" && j.CallerKey != "":
+		return j.CallerKey
+	case key == "stack" && j.StackKey != "":
+		return j.StackKey
+	}
+	return key
+}
+
+func appendJSONField(buf []byte, f *Field) []byte {
+	buf = appendJSONKey(buf, f.Key)
+	return appendJSONValue(buf, f)
+}
+
+func appendJSONValue(buf []byte, f *Field) []byte {
+	switch f.kind {
+	case stringKind:
+		return appendJSONString(buf, f.str)
+	case intKind:
+		return AppendInt(buf, f.num)
+	case uintKind:
+		return AppendUint(buf, uint64(f.num))
+	case floatKind:
+		v := math.Float64frombits(uint64(f.num))
+		if math.IsNaN(v) || math.IsInf(v, 0) {
+			return appendJSONString(buf, strconv.FormatFloat(v, 'g', -1, 64))
+		}
+		return AppendFloat(buf, v)
+	case boolKind:
+		return AppendBool(buf, f.num == 1)
+	case durationKind:
+		return appendJSONString(buf, time.Duration(f.num).String())
+	case timeKind:
+		buf = append(buf, '"')
+		buf = f.val.(time.Time).AppendFormat(buf, time.RFC3339Nano)
+		return append(buf, '"')
+	case errorKind:
+		if f.val == nil {
+			return append(buf, "null"...)
+		}
+		return appendJSONString(buf, f.val.(error).Error())
+	case groupKind:
+		buf = append(buf, '{')
+		for i, g := range f.val.([]Field) {
+			if i > 0 {
+				buf = append(buf, ',')
+			}
+			buf = appendJSONField(buf, &g)
+		}
+		return append(buf, '}')
+	}
+	if b, err := json.Marshal(f.val); err == nil {
+		return append(buf, b...)
+	}
+	return appendJSONString(buf, fmt.Sprint(f.val))
+}
+
+// appendErrorChain walks err depth-first, following both Unwrap() error
+// and the Unwrap() []error of errors.Join.
+func appendErrorChain(buf []byte, err error) []byte {
+	buf = append(buf, '[')
+	buf, _ = appendCauses(buf, err, 0)
+	return append(buf, ']')
+}
+
+// appendCauses stops after 32 layers in case of a cyclic Unwrap.
+func appendCauses(buf []byte, err error, n int) ([]byte, int) {
+	for err != nil && n < 32 {
+		if n > 0 {
+			buf = append(buf, ',')
+		}
+		n++
+		buf = append(buf, `{"type":`...)
+		buf = appendJSONString(buf, reflect.TypeOf(err).String())
+		buf = append(buf, `,"msg":`...)
+		buf = appendJSONString(buf, err.Error())
+		buf = append(buf, '}')
+		switch u := err.(type) {
+		case interface{ Unwrap() error }:
+			err = u.Unwrap()
+		case interface{ Unwrap() []error }:
+			for _, e := range u.Unwrap() {
+				buf, n = appendCauses(buf, e, n)
+			}
+			return buf, n
+		default:
+			return buf, n
+		}
+	}
+	return buf, n
+}
+
+const hexDigits = "0123456789abcdef"
+
+func appendJSONString(buf []byte, s string) []byte {
+	buf = append(buf, '"')
+	start := 0
+	for i := 0; i < len(s); {
+		c := s[i]
+		if c >= utf8.RuneSelf {
+			r, size := utf8.DecodeRuneInString(s[i:])
+			if r == utf8.RuneError && size == 1 {
+				buf = append(buf, s[start:i]...)
+				buf = append(buf, `\ufffd`...)
+				i += size
+				start = i
+				continue
+			}
+			i += size
+			continue
+		}
+		if c >= ' ' && c != '"' && c != '\\' {
+			i++
+			continue
+		}
+		buf = append(buf, s[start:i]...)
+		switch c {
+		case '"', '\\':
+			buf = append(buf, '\\', c)
+		case '\n':
+			buf = append(buf, '\\', 'n')
+		case '\r':
+			buf = append(buf, '\\', 'r')
+		case '\t':
+			buf = append(buf, '\\', 't')
+		default:
+			buf = append(buf, '\\', 'u', '0', '0', hexDigits[c>>4], hexDigits[c&0xf])
+		}
+		i++
+		start = i
+	}
+	buf = append(buf, s[start:]...)
+	return append(buf, '"')
+}
@@ -0,0 +1,62 @@
+package speedlog
+
+import "bytes"
+
+// partialLines adapts line-oriented writers to sink output, which may end
+// mid-line when a line doesn't fit the buffer: the tail is kept until its
+// newline arrives.
+type partialLines struct {
+	tail []byte
+}
+
+// write hands fn every complete line in tail+p, newlines included.
+func (pl *partialLines) write(p []byte, fn func(lines []byte) error) (int, error) {
+	data, kept := p, len(pl.tail)
+	if kept > 0 {
+		pl.tail = append(pl.tail, p...)
+		data = pl.tail
+	}
+	end := bytes.LastIndexByte(data, '\n') + 1
+	if end == 0 {
+		if kept == 0 {
+			pl.tail = append(pl.tail, p...)
+		}
+		return len(p), nil
+	}
+	if err := fn(data[:end]); err != nil {
+		// p wasn't taken, so a retry mustn't find it in the tail.
+		pl.tail = pl.tail[:kept]
+		return 0, err
+	}
+	pl.tail = append(pl.tail[:0], data[end:]...)
+	return len(p), nil
+}
+
+// flush hands fn the kept tail, if any, with a newline added.
+func (pl *partialLines) flush(fn func(lines []byte) error) error {
+	if len(pl.tail) == 0 {
+		return nil
+	}
+	err := fn(append(pl.tail, '\n'))
+	pl.tail = nil
+	return err
+}
+
+// eachLine calls fn for every non-blank line, without its newline.
+func eachLine(lines []byte, fn func(line []byte) error) error {
+	for len(lines) > 0 {
+		line := lines
+		if i := bytes.IndexByte(lines, '\n'); i >= 0 {
+			line, lines = lines[:i], lines[i+1:]
+		} else {
+			lines = nil
+		}
+		if len(bytes.TrimSpace(line)) == 0 {
+			continue
+		}
+		if err := fn(line); err != nil {
+			return err
+		}
+	}
+	return nil
+}
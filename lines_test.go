@@ -0,0 +1,43 @@
+package speedlog
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestPartialLinesSplitWrites(t *testing.T) {
+	var pl partialLines
+	var got []byte
+	collect := func(lines []byte) error {
+		got = append(got, lines...)
+		return nil
+	}
+	for _, p := range []string{"aaa", "bbb", "ccc\nd", "dd", "\n", "eee"} {
+		if n, err := pl.write([]byte(p), collect); n != len(p) || err != nil {
+			t.Fatalf("write(%q) = %d, %v", p, n, err)
+		}
+	}
+	if err := pl.flush(collect); err != nil {
+		t.Fatal(err)
+	}
+	if want := "aaabbbccc\nddd\neee\n"; string(got) != want {
+		t.Fatalf("got %q, want %q", got, want)
+	}
+}
+
+func TestPartialLinesFailedWriteKeepsTail(t *testing.T) {
+	var pl partialLines
+	fail := func([]byte) error { return errors.New("down") }
+	pl.write([]byte("aa"), fail)
+	if n, err := pl.write([]byte("b\n"), fail); n != 0 || err == nil {
+		t.Fatalf("write = %d, %v; want 0 and an error", n, err)
+	}
+	var got []byte
+	pl.write([]byte("b\n"), func(lines []byte) error {
+		got = append(got, lines...)
+		return nil
+	})
+	if string(got) != "aab\n" {
+		t.Fatalf("got %q, want %q", got, "aab\n")
+	}
+}
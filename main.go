@@ -1,7 +1,7 @@
 package speedlog
 
 import (
-	"bufio"
+	"errors"
 	"fmt"
 	"io"
 	"os"
@@ -15,137 +15,595 @@ const (
 	INFO
 	WARN
 	ERROR
+	PANIC
+	FATAL
+)
+
+const (
+	lightFlushBytes = 4 * 1024
+	timeLayout      = "2006-01-02 15:04:05.000"
 )
 
 var (
-	levelNames = [...]string{"DEBUG", "INFO", "WARN", "ERROR"}
-	std        *Logger
+	levelNames = [...]string{"DEBUG", "INFO", "WARN", "ERROR", "PANIC", "FATAL"}
+	stdOnce    sync.Once
+	std        atomic.Pointer[Logger]
 )
 
+// Logger is a handle on a shared pipeline; With returns handles that add
+// fields but write through the same queue and sinks.
 type Logger struct {
-	level     int32
-	writers   []io.Writer
-	bufs      []*bufio.Writer
-	ch        chan []byte
-	bufPool   sync.Pool
-	done      chan struct{}
-	wg        sync.WaitGroup
-	closeOnce sync.Once
-	ts        atomic.Value
+	*core
+	level    *atomic.Int32
+	enc      Encoder
+	fields   []Field
+	ns       []string
+	deferred *deferBuf
+	skip     int
+	name     string
+}
+
+type core struct {
+	root           *Logger // as New returned it, for the logger's own entries
+	configured     []*sink // by options, until New publishes them
+	set            atomic.Pointer[sinkSet]
+	sinkMu         sync.Mutex
+	sinksClosed    bool
+	lateW          io.Writer
+	lateSet        bool
+	lateMu         sync.Mutex
+	lateN          atomic.Uint64
+	hooks          []hook
+	ttl            time.Duration
+	ch             chan record
+	bufPool        bufPool
+	entries        atomic.Uint64
+	arena          *arena
+	done           chan struct{}
+	wg             sync.WaitGroup
+	closeOnce      sync.Once
+	closeErr       error
+	ts             atomic.Pointer[tsCache]
+	signing        bool
+	signKey        []byte
+	ring           *ring
+	shardN         int
+	shards         *shards
+	batchN         int
+	batchBytes     int
+	flushMin       time.Duration
+	flushMax       time.Duration
+	pending        int
+	sinkWG         sync.WaitGroup
+	onError        func(error)
+	bufSize        int
+	tsRes          time.Duration
+	exactTS        bool
+	idleAfter      time.Duration
+	lifeMu         sync.Mutex
+	running        bool
+	closed         bool
+	closing        atomic.Bool
+	sending        atomic.Int64
+	sleeping       atomic.Bool
+	queued         atomic.Int64
+	exit           atomic.Pointer[func(int)]
+	barrierReq     chan chan struct{}
+	counters       counters
+	suppress       *suppressor
+	volume         *volume
+	caller         bool
+	callerFunc     bool
+	stacks         bool
+	stackAt        int
+	beat           atomic.Int64
+	saturatedSince atomic.Int64
+	busySince      atomic.Int64
+	tail           tailHub
+	routes         []Rule
+	nop            bool
+	shed           *shedder
+	fallback       *fallback
+	statsd         *statsdEmitter
+	encSet         bool
+	configErrs     []error
+	hi             chan record
+	hiLevel        int
+	hiBatch        bool
+	inline         bool
+	inlineLevel    int
+	inlineMu       sync.Mutex
+	inlineDone     bool
 }
 
 type Option func(*Logger)
 
-func init() {
-	std = New(
-		WithWriter(os.Stdout),
-	)
+// record is one queued line plus the set of sinks (bit i = sink i) that
+// should get it; a zero mask means every sink.
+type record struct {
+	line  []byte
+	mask  uint64
+	level int
+	at    int64 // enqueue time in ns, under WithEntryTTL
+	chunk *chunk
+}
+
+type tsCache struct {
+	t    time.Time
+	text []byte
+}
+
+func newTSCache() *tsCache {
+	now := time.Now()
+	return &tsCache{t: now, text: now.AppendFormat(make([]byte, 0, 32), timeLayout)}
+}
+
+type flusher interface {
+	Flush() error
+}
+
+func defaultLogger() *Logger {
+	stdOnce.Do(func() {
+		std.Store(MustNew(
+			WithWriter(os.Stdout),
+			WithIdleTimeout(time.Second),
+		))
+	})
+	return std.Load()
+}
+
+// ErrInvalidOption is wrapped by every configuration error New reports.
+var ErrInvalidOption = errors.New("speedlog: invalid option")
+
+func optionError(format string, a ...any) error {
+	return fmt.Errorf("%w: "+format, append([]any{ErrInvalidOption}, a...)...)
+}
+
+// invalid records a configuration error for New to return.
+func (l *Logger) invalid(format string, a ...any) {
+	l.configErrs = append(l.configErrs, optionError(format, a...))
 }
 
 func WithWriter(w io.Writer) Option {
+	return WithSink(w)
+}
+
+// WithStdSplit sends WARN and above to stderr and everything else to stdout.
+func WithStdSplit() Option {
 	return func(l *Logger) {
-		if w != nil {
-			l.writers = append(l.writers, w)
-		}
+		WithSink(os.Stdout, WithLevels(DEBUG, INFO))(l)
+		WithSink(os.Stderr, WithLevels(WARN, FATAL))(l)
 	}
 }
 
 func WithChannelSize(n int) Option {
 	return func(l *Logger) {
-		if n > 0 {
-			l.ch = make(chan []byte, n)
+		if n <= 0 {
+			l.invalid("WithChannelSize(%d): size must be positive", n)
+			return
 		}
+		l.ch = make(chan record, n)
 	}
 }
 
 func WithLevel(level int) Option {
 	return func(l *Logger) {
-		atomic.StoreInt32(&l.level, int32(level))
+		l.level.Store(int32(level))
+	}
+}
+
+// WithBatchSize caps writer batches; 0 keeps that limit's default.
+func WithBatchSize(entries, bytes int) Option {
+	return func(l *Logger) {
+		if entries < 0 || bytes < 0 {
+			l.invalid("WithBatchSize(%d, %d): limits can't be negative", entries, bytes)
+			return
+		}
+		if entries > 0 {
+			l.batchN = entries
+		}
+		if bytes > 0 {
+			l.batchBytes = bytes
+		}
+	}
+}
+
+func WithFlushInterval(d time.Duration) Option {
+	return func(l *Logger) {
+		if d <= 0 {
+			l.invalid("WithFlushInterval(%v): interval must be positive", d)
+			return
+		}
+		l.flushMin, l.flushMax = d, d
+	}
+}
+
+func WithWriterBufferSize(n int) Option {
+	return func(l *Logger) {
+		if n <= 0 {
+			l.invalid("WithWriterBufferSize(%d): size must be positive", n)
+			return
+		}
+		l.bufSize = n
+	}
+}
+
+func WithTimestampResolution(d time.Duration) Option {
+	return func(l *Logger) {
+		if d <= 0 {
+			l.invalid("WithTimestampResolution(%v): resolution must be positive", d)
+			return
+		}
+		l.tsRes = d
+	}
+}
+
+func WithExactTimestamps() Option {
+	return func(l *Logger) {
+		l.exactTS = true
 	}
 }
 
-func New(opts ...Option) *Logger {
-	l := &Logger{
-		done: make(chan struct{}),
+func WithAdaptiveFlush(min, max time.Duration) Option {
+	return func(l *Logger) {
+		if min <= 0 || max < min {
+			l.invalid("WithAdaptiveFlush(%v, %v): need 0 < min <= max", min, max)
+			return
+		}
+		l.flushMin, l.flushMax = min, max
 	}
-	atomic.StoreInt32(&l.level, int32(INFO))
-	l.ch = make(chan []byte, 1024)
-	l.bufPool = sync.Pool{
-		New: func() interface{} {
-			b := make([]byte, 0, 512)
-			return b
-		},
+}
+
+func WithErrorHandler(fn func(error)) Option {
+	return func(l *Logger) {
+		l.onError = fn
 	}
+}
+
+func WithExitFunc(fn func(code int)) Option {
+	return func(l *Logger) {
+		if fn == nil {
+			l.invalid("WithExitFunc(nil)")
+			return
+		}
+		l.exit.Store(&fn)
+	}
+}
+
+// New builds a logger and starts its goroutines. Invalid options (a nil
+// writer, a non-positive size or interval, a second WithEncoder, ...) make
+// it return nil and every problem found, each wrapping ErrInvalidOption.
+func New(opts ...Option) (*Logger, error) {
+	l := &Logger{core: &core{
+		done:       make(chan struct{}),
+		barrierReq: make(chan chan struct{}),
+		batchN:     256,
+		batchBytes: 1 << 20,
+		flushMin:   10 * time.Millisecond,
+		flushMax:   500 * time.Millisecond,
+		bufSize:    64 * 1024,
+		tsRes:      100 * time.Millisecond,
+		onError:    func(err error) { fmt.Fprintln(os.Stderr, err) },
+		counters:   counters{interval: 10 * time.Second},
+	}, level: new(atomic.Int32), enc: TextEncoder{}}
+	l.root = l
+	l.level.Store(int32(INFO))
+	exit := os.Exit
+	l.exit.Store(&exit)
+	l.ch = make(chan record, 1024)
 	for _, opt := range opts {
 		opt(l)
 	}
-	if len(l.writers) == 0 {
-		l.writers = []io.Writer{os.Stdout}
+	if !l.lateSet {
+		l.lateW = os.Stderr
 	}
-	l.bufs = make([]*bufio.Writer, len(l.writers))
-	for i, w := range l.writers {
-		l.bufs[i] = bufio.NewWriterSize(w, 64*1024)
+	if l.ring != nil && l.shardN > 0 {
+		l.invalid("WithRingBuffer and WithShards are alternative queues")
+	}
+	if len(l.configErrs) > 0 {
+		return nil, errors.Join(l.configErrs...)
+	}
+	if l.ring == nil && l.shardN > 0 {
+		l.shards = newShards(l.shardN, max(cap(l.ch)/l.shardN, 16))
+	}
+	if l.ring != nil {
+		l.arena = nil
+	}
+	if l.ring != nil || l.shards != nil {
+		l.ch = nil
+	}
+	if len(l.configured) == 0 {
+		WithWriter(os.Stdout)(l)
+	}
+	l.checkRoutes()
+	for i, s := range l.configured {
+		l.initSink(s, i)
+	}
+	l.set.Store(newSinkSet(l.configured))
+	l.configured = nil
+	l.ts.Store(newTSCache())
+	l.startWriter()
+	if l.statsd != nil {
+		l.wg.Add(1)
+		go l.statsdLoop()
+	}
+	return l, nil
+}
+
+// MustNew is New for configurations known to be valid; it panics on an
+// invalid option.
+func MustNew(opts ...Option) *Logger {
+	l, err := New(opts...)
+	if err != nil {
+		panic(err)
 	}
-	now := time.Now()
-	ts := make([]byte, 0, 32)
-	ts = now.AppendFormat(ts, "2006-01-02 15:04:05.000")
-	l.ts.Store(ts)
-	l.wg.Add(2)
-	go l.writerLoop()
-	go l.timestampLoop()
 	return l
 }
 
 func (l *Logger) writerLoop() {
 	defer l.wg.Done()
-	ticker := time.NewTicker(500 * time.Millisecond)
+	if !l.exactTS {
+		stop := make(chan struct{})
+		defer close(stop)
+		l.wg.Add(1)
+		go l.timestampLoop(stop)
+	}
+	interval := l.flushMax
+	ticker := time.NewTicker(interval)
 	defer ticker.Stop()
-	flushAll := func() {
-		for _, bw := range l.bufs {
-			_ = bw.Flush()
+	var wake chan struct{}
+	if l.ring != nil {
+		wake = l.ring.wake
+	} else if l.shards != nil {
+		wake = l.shards.wake
+	}
+	setInterval := func(d time.Duration) {
+		if d != interval {
+			interval = d
+			ticker.Reset(d)
 		}
 	}
+	idle := true
+	lastWork := time.Now()
+	batch := make([]record, 0, l.batchN)
 	for {
 		select {
-		case line := <-l.ch:
-			if line != nil {
-				for _, bw := range l.bufs {
-					_, _ = bw.Write(line)
-				}
-				l.bufPool.Put(line)
-			}
+		case rec := <-l.hi:
+			l.hiBatch = true
+			batch = l.consume(append(batch[:0], rec), wake)
+		case rec := <-l.ch:
+			batch = l.drainUrgent(batch)
+			batch = l.consume(append(batch[:0], rec), wake)
+		case <-wake:
+			batch = l.drainUrgent(batch)
+			batch = l.consume(batch[:0], wake)
 		case <-ticker.C:
-			flushAll()
-		case <-l.done:
-			for {
-				select {
-				case line := <-l.ch:
-					if line != nil {
-						for _, bw := range l.bufs {
-							_, _ = bw.Write(line)
-						}
-						l.bufPool.Put(line)
-					}
-				default:
-					flushAll()
+			l.heartbeat(time.Now())
+			if l.fallback != nil {
+				l.degraded()
+			}
+			n := l.pending
+			l.pending = 0
+			l.busySince.Store(time.Now().UnixNano())
+			l.tickAll()
+			l.busySince.Store(0)
+			// Idle: sleep long. Light traffic: flush sooner for latency.
+			// Heavy traffic: flush less often, bufio flushes itself anyway.
+			switch {
+			case n == 0:
+				idle = true
+				setInterval(l.flushMax)
+				if l.idleAfter > 0 && time.Since(lastWork) >= l.idleAfter && l.park() {
 					return
 				}
+			case n < lightFlushBytes:
+				setInterval(max(interval/2, l.flushMin))
+			default:
+				setInterval(min(interval*2, l.flushMax))
 			}
+			continue
+		case ack := <-l.barrierReq:
+			batch = l.drainN(batch, l.queueLen()+len(l.hi))
+			l.flushAll()
+			close(ack)
+		case <-l.done:
+			l.drainAll(batch)
+			return
+		}
+		lastWork = time.Now()
+		l.heartbeat(lastWork)
+		if idle {
+			idle = false
+			setInterval(l.flushMin)
+		}
+	}
+}
+
+func (l *Logger) drainAll(batch []record) {
+	for {
+		batch = l.next(batch[:0])
+		if len(batch) == 0 {
+			return
+		}
+		l.writeBatch(batch)
+		l.release(batch)
+	}
+}
+
+func (l *Logger) drainN(batch []record, n int) []record {
+	for n > 0 {
+		batch = l.next(batch[:0])
+		if len(batch) == 0 {
+			break
+		}
+		n -= len(batch)
+		l.writeBatch(batch)
+		l.release(batch)
+	}
+	return batch[:0]
+}
+
+func (l *Logger) queueLen() int {
+	switch {
+	case l.ring != nil:
+		return l.ring.len()
+	case l.shards != nil:
+		n := 0
+		for _, q := range l.shards.qs {
+			n += len(q)
+		}
+		return n
+	default:
+		return len(l.ch)
+	}
+}
+
+// barrier returns once everything enqueued before the call has been
+// written and the inline sinks flushed.
+func (l *Logger) barrier() {
+	l.enter()
+	defer l.dropped()
+	ack := make(chan struct{})
+	select {
+	case l.barrierReq <- ack:
+		<-ack
+	case <-l.done:
+	}
+}
+
+func (l *Logger) consume(batch []record, wake chan struct{}) []record {
+	batch = l.next(batch)
+	if len(batch) == 0 {
+		return batch
+	}
+	l.writeBatch(batch)
+	full := l.batchFull(batch) || l.hiBatch
+	l.release(batch)
+	// Ring and shard producers only signal once; if the budget cut this
+	// batch short (or the priority queue went first), come back for the
+	// rest after servicing the ticker.
+	if wake != nil && full {
+		select {
+		case wake <- struct{}{}:
+		default:
+		}
+	}
+	return batch[:0]
+}
+
+func (l *Logger) batchFull(batch []record) bool {
+	if len(batch) >= l.batchN {
+		return true
+	}
+	n := 0
+	for _, rec := range batch {
+		n += len(rec.line)
+	}
+	return n >= l.batchBytes
+}
+
+func (l *Logger) next(batch []record) []record {
+	if l.hi != nil && (l.hiBatch || len(batch) == 0 && len(l.hi) > 0) {
+		l.hiBatch = true
+		return l.nextUrgent(batch)
+	}
+	if l.ring != nil {
+		return l.ring.take(batch, l.batchN, l.batchBytes)
+	}
+	if l.shards != nil {
+		return l.shards.take(batch, l.batchN, l.batchBytes)
+	}
+	n := 0
+	for _, rec := range batch {
+		n += len(rec.line)
+	}
+	for len(batch) < l.batchN && n < l.batchBytes {
+		select {
+		case rec := <-l.ch:
+			batch = append(batch, rec)
+			n += len(rec.line)
+		default:
+			return batch
+		}
+	}
+	return batch
+}
+
+func (l *Logger) release(batch []record) {
+	if l.idleAfter > 0 {
+		l.queued.Add(-int64(len(batch)))
+	}
+	if l.ring != nil && !l.hiBatch {
+		l.ring.release(batch)
+	} else {
+		l.putLines(batch)
+	}
+	l.hiBatch = false
+	clear(batch)
+}
+
+func (l *Logger) flushAll() {
+	if l.inline {
+		l.inlineMu.Lock()
+		defer l.inlineMu.Unlock()
+	}
+	for _, s := range l.sinkList() {
+		if s.async() {
+			s.requestFlush()
+		} else {
+			s.tick()
+		}
+	}
+}
+
+// tickAll is the writer's periodic flush. Sinks with a WithFlush policy
+// decide for themselves; queued ones run their own timer.
+func (l *Logger) tickAll() {
+	if l.inline {
+		l.inlineMu.Lock()
+		defer l.inlineMu.Unlock()
+	}
+	for _, s := range l.sinkList() {
+		switch {
+		case s.async() && s.customFlush:
+		case s.async():
+			s.requestFlush()
+		default:
+			s.periodic()
 		}
 	}
 }
 
-func (l *Logger) timestampLoop() {
+func (l *Logger) writeBatch(batch []record) {
+	size := 0
+	for _, rec := range batch {
+		size += len(rec.line)
+	}
+	l.pending += size
+	if l.inline {
+		l.inlineMu.Lock()
+		defer l.inlineMu.Unlock()
+	}
+	l.busySince.Store(time.Now().UnixNano())
+	for _, s := range l.sinkList() {
+		s.write(batch, size)
+	}
+	if l.tail.n.Load() > 0 {
+		l.tail.publish(batch, size)
+	}
+	l.busySince.Store(0)
+}
+
+func (l *Logger) timestampLoop(stop chan struct{}) {
 	defer l.wg.Done()
-	ticker := time.NewTicker(100 * time.Millisecond)
+	ticker := time.NewTicker(l.tsRes)
 	defer ticker.Stop()
 	for {
 		select {
 		case <-ticker.C:
-			now := time.Now()
-			buf := make([]byte, 0, 32)
-			buf = now.AppendFormat(buf, "2006-01-02 15:04:05.000")
-			l.ts.Store(buf)
+			l.ts.Store(newTSCache())
+		case <-stop:
+			return
 		case <-l.done:
 			return
 		}
@@ -153,108 +611,405 @@ func (l *Logger) timestampLoop() {
 }
 
 func (l *Logger) IsLevelEnabled(level int) bool {
-	return level >= int(atomic.LoadInt32(&l.level))
+	return level >= int(l.level.Load())
 }
 
 func (l *Logger) SetLevel(level int) {
-	atomic.StoreInt32(&l.level, int32(level))
+	if l.nop {
+		return
+	}
+	l.level.Store(int32(level))
 }
 
 func (l *Logger) GetLevel() int {
-	return int(atomic.LoadInt32(&l.level))
+	return int(l.level.Load())
+}
+
+func (l *Logger) enabled(level int) bool {
+	return l.IsLevelEnabled(level) || l.deferred != nil && l.deferred.holds(level)
+}
+
+func (l *Logger) log(level int, msg string, fields ...Field) {
+	if !l.enabled(level) {
+		return
+	}
+	if l.suppress != nil && !l.allow(level, msg) {
+		return
+	}
+	e := l.entry(level, msg, fields)
+	if l.caller {
+		l.appendCaller(&e)
+	}
+	if l.stacks && level >= l.stackAt {
+		e.Fields = append(e.Fields[:len(e.Fields):len(e.Fields)], String("stack", stackFor(e.Fields)))
+	}
+	if l.hooks != nil && !l.runHooks(&e) {
+		return
+	}
+	if l.deferred != nil && !l.deferred.admit(l, &e) {
+		return
+	}
+	l.emit(&e)
 }
 
-func (l *Logger) log(level int, msg string) {
-	if !l.IsLevelEnabled(level) {
+func (l *Logger) emit(e *Entry) { l.emitLine(e, nil) }
+
+// emitLine queues e, encoded, or raw verbatim when it is non-nil; e then
+// only carries the level and time for routing and stats.
+func (l *Logger) emitLine(e *Entry, raw []byte) {
+	if l.shed != nil && e.Level < WARN && l.shedding(e.Level) {
+		return
+	}
+	if !l.beginSend() {
+		l.lateEntry(e, raw)
+		return
+	}
+	defer l.endSend()
+	var mask uint64
+	if set := l.set.Load(); set != nil && set.routed {
+		if mask = set.maskFor(e); mask == 0 {
+			return
+		}
+	}
+	if l.fallback != nil && e.Level >= WARN && l.degraded() {
+		l.toStderr(e, raw)
+	}
+	if l.inline && e.Level >= l.inlineLevel {
+		l.writeInline(e, raw, mask)
+		return
+	}
+	l.enter()
+	l.entries.Add(1)
+	if l.ring != nil && !l.urgent(e.Level) {
+		s, pos, ok := l.ring.claim(l.done)
+		if !ok {
+			l.lateEntry(e, raw)
+			l.dropped()
+			return
+		}
+		if raw != nil {
+			s.buf = append(s.buf[:0], raw...)
+		} else {
+			s.buf = l.enc.Encode(s.buf[:0], *e)
+		}
+		s.mask = mask
+		s.level = e.Level
+		s.at = l.stamp()
+		if l.volume != nil {
+			l.volume.record(e.Level, e.Time, len(s.buf))
+		}
+		l.ring.publish(s, pos)
 		return
 	}
-	buf := l.bufPool.Get().([]byte)
-	buf = buf[:0]
-	ts := l.ts.Load().([]byte)
-	buf = append(buf, ts...)
-	buf = append(buf, ' ')
-	if level >= 0 && level < len(levelNames) {
-		buf = append(buf, levelNames[level]...)
+	var rec record
+	if raw != nil {
+		rec = record{line: append(l.bufPool.get(len(raw)), raw...), mask: mask, level: e.Level}
 	} else {
-		buf = append(buf, "UNK"...)
+		rec = record{line: l.enc.Encode(l.bufPool.get(sizeHint(e)), *e), mask: mask, level: e.Level}
+	}
+	if l.volume != nil {
+		l.volume.record(e.Level, e.Time, len(rec.line))
+	}
+	l.enqueue(rec)
+}
+
+// enqueue hands a record with a pooled line to the channel or shards,
+// moving the line into the arena first when there is one.
+func (l *Logger) enqueue(rec record) {
+	rec.at = l.stamp()
+	if l.arena != nil {
+		if line, c := l.arena.alloc(rec.line); c != nil {
+			l.bufPool.put(rec.line)
+			rec.line, rec.chunk = line, c
+		}
+	}
+	if l.urgent(rec.level) {
+		select {
+		case l.hi <- rec:
+		case <-l.done:
+			l.late(rec.line)
+			l.putLines([]record{rec})
+			l.dropped()
+		}
+		return
+	}
+	if l.shards != nil {
+		if !l.shards.push(rec, l.done) {
+			l.late(rec.line)
+			l.putLines([]record{rec})
+			l.dropped()
+		}
+		return
 	}
-	buf = append(buf, ' ')
-	buf = append(buf, msg...)
-	buf = append(buf, '\n')
 	select {
-	case l.ch <- buf:
+	case l.ch <- rec:
 	case <-l.done:
-		l.bufPool.Put(buf)
+		l.late(rec.line)
+		l.putLines([]record{rec})
+		l.dropped()
+	}
+}
+
+// putLines hands line buffers back to the pool or the arena.
+func (l *Logger) putLines(batch []record) {
+	for _, rec := range batch {
+		if rec.chunk == nil {
+			l.bufPool.put(rec.line)
+		}
+	}
+	if l.arena != nil {
+		l.arena.release(batch)
+	}
+}
+
+func (l *Logger) entry(level int, msg string, fields []Field) Entry {
+	e := Entry{Level: level, Message: msg}
+	fields = flatten(fields)
+	switch {
+	case len(l.ns) > 0 && len(fields) > 0:
+		e.Fields = nest(l.fields, l.ns, fields)
+	case len(l.fields) == 0:
+		e.Fields = fields
+	case len(fields) == 0:
+		e.Fields = l.fields
+	default:
+		e.Fields = append(l.fields[:len(l.fields):len(l.fields)], fields...)
+	}
+	if l.exactTS {
+		e.Time = time.Now()
+	} else {
+		ts := l.ts.Load()
+		e.Time, e.ts = ts.t, ts.text
 	}
+	return e
 }
 
 func (l *Logger) logf(level int, format string, args ...interface{}) {
-	if !l.IsLevelEnabled(level) {
+	if !l.enabled(level) {
 		return
 	}
 	msg := fmt.Sprintf(format, args...)
 	l.log(level, msg)
 }
 
-func (l *Logger) Sync() {
-	for _, bw := range l.bufs {
-		_ = bw.Flush()
+func (l *Logger) logln(level int, args ...any) {
+	if !l.enabled(level) {
+		return
+	}
+	l.log(level, sprintln(args...))
+}
+
+func sprintln(args ...any) string {
+	msg := fmt.Sprintln(args...)
+	return msg[:len(msg)-1]
+}
+
+func (l *Logger) SetExitFunc(fn func(code int)) {
+	if fn != nil {
+		l.exit.Store(&fn)
+	}
+}
+
+// fatal drains and flushes everything before exiting, so the FATAL line
+// itself is never lost. The logger is closed afterwards.
+func (l *Logger) fatal(msg string) {
+	l.log(FATAL, msg)
+	l.Close()
+	(*l.exit.Load())(1)
+}
+
+func (l *Logger) panic(msg string) {
+	l.log(PANIC, msg)
+	l.syncAll()
+	panic(msg)
+}
+
+// Sync writes out everything logged before it and flushes the sinks, then
+// returns the first error each sink hit since the previous Sync, joined.
+// The flushes run on the writer goroutine and, for queued sinks, on
+// theirs, so Sync is safe to call from anywhere. After Close it returns
+// ErrClosed.
+func (l *Logger) Sync() error {
+	if l.nop {
+		return nil
 	}
+	l.lifeMu.Lock()
+	closed := l.closed
+	l.lifeMu.Unlock()
+	if closed {
+		return ErrClosed
+	}
+	l.syncAll()
+	return l.sinkErrs()
+}
+
+// syncAll is the part of Sync shared with Panic and the control socket:
+// the writer goroutine flushes, then every queued sink.
+func (l *Logger) syncAll() {
+	l.barrier()
+	syncQueued(l.sinkList())
+}
+
+func (l *Logger) sinkErrs() error {
+	var errs []error
+	for _, s := range l.sinkList() {
+		if err := s.takeErr(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
 }
 
-func (l *Logger) Close() {
+// Close drains the queue, flushes and closes the sinks. It reports the same
+// errors as Sync plus any from closing the writers; later calls return the
+// same result.
+func (l *Logger) Close() error {
 	l.closeOnce.Do(func() {
+		l.flushCounters()
+		if l.suppress != nil {
+			l.flushSuppressed(true)
+		}
+		if l.shed != nil {
+			l.shedSummary()
+		}
+		// Waits out a RemoveSink in progress, and fails later ones.
+		l.sinkMu.Lock()
+		l.sinksClosed = true
+		l.sinkMu.Unlock()
+		l.stopSends()
+		l.lifeMu.Lock()
+		l.closed = true
+		l.lifeMu.Unlock()
 		close(l.done)
 		l.wg.Wait()
-		for _, bw := range l.bufs {
-			_ = bw.Flush()
+		l.drainAll(make([]record, 0, l.batchN))
+		l.inlineMu.Lock()
+		l.inlineDone = true
+		l.inlineMu.Unlock()
+		sinks := l.sinkList()
+		for _, s := range sinks {
+			if s.async() {
+				close(s.ch)
+			}
 		}
-		for _, w := range l.writers {
-			if c, ok := w.(io.Closer); ok {
-				_ = c.Close()
+		l.sinkWG.Wait()
+		var errs []error
+		for _, s := range sinks {
+			if err := s.close(); err != nil {
+				errs = append(errs, err)
 			}
 		}
+		l.closeErr = errors.Join(append([]error{l.sinkErrs()}, errs...)...)
 	})
+	return l.closeErr
+}
+
+func SetLevel(level int) { defaultLogger().SetLevel(level) }
+
+func GetLevel() int { return defaultLogger().GetLevel() }
+
+func IsLevelEnabled(level int) bool { return defaultLogger().IsLevelEnabled(level) }
+
+func Sync() error {
+	if l := std.Load(); l != nil {
+		return l.Sync()
+	}
+	return nil
 }
 
-func SetLevel(level int) { std.SetLevel(level) }
+func Close() error {
+	if l := std.Load(); l != nil {
+		return l.Close()
+	}
+	return nil
+}
+
+func SetExitFunc(fn func(code int)) { defaultLogger().SetExitFunc(fn) }
+
+func With(fields ...Field) *Logger { return defaultLogger().With(fields...) }
 
-func GetLevel() int { return std.GetLevel() }
+func Log(level int, msg string, fields ...Field) { defaultLogger().log(level, msg, fields...) }
 
-func IsLevelEnabled(level int) bool { return std.IsLevelEnabled(level) }
+func Print(msg string) { defaultLogger().log(INFO, msg) }
 
-func Sync() { std.Sync() }
+func Printf(format string, a ...any) { defaultLogger().logf(INFO, format, a...) }
 
-func Close() { std.Close() }
+func Println(a ...any) { defaultLogger().logln(INFO, a...) }
 
-func Debug(msg string) { std.log(DEBUG, msg) }
+func Infoln(a ...any) { defaultLogger().logln(INFO, a...) }
 
-func Debugf(format string, a ...any) { std.logf(DEBUG, format, a...) }
+func Warn(msg string) { defaultLogger().log(WARN, msg) }
 
-func Print(msg string) { std.log(INFO, msg) }
+func Warnf(format string, a ...any) { defaultLogger().logf(WARN, format, a...) }
 
-func Printf(format string, a ...any) { std.logf(INFO, format, a...) }
+func Warnln(a ...any) { defaultLogger().logln(WARN, a...) }
 
-func Warn(msg string) { std.log(WARN, msg) }
+func Error(msg string) { defaultLogger().log(ERROR, msg) }
 
-func Warnf(format string, a ...any) { std.logf(WARN, format, a...) }
+func Errorf(format string, a ...any) { defaultLogger().logf(ERROR, format, a...) }
 
-func Error(msg string) { std.log(ERROR, msg) }
+func Errorln(a ...any) { defaultLogger().logln(ERROR, a...) }
 
-func Errorf(format string, a ...any) { std.logf(ERROR, format, a...) }
+func Panic(msg string) { defaultLogger().panic(msg) }
 
-func (l *Logger) Debug(msg string) { l.log(DEBUG, msg) }
+func Panicf(format string, a ...any) { defaultLogger().panic(fmt.Sprintf(format, a...)) }
 
-func (l *Logger) Debugf(format string, a ...any) { l.logf(DEBUG, format, a...) }
+func Panicln(a ...any) { defaultLogger().panic(sprintln(a...)) }
+
+func Fatal(msg string) { defaultLogger().fatal(msg) }
+
+func Fatalf(format string, a ...any) { defaultLogger().fatal(fmt.Sprintf(format, a...)) }
+
+func Fatalln(a ...any) { defaultLogger().fatal(sprintln(a...)) }
+
+// With returns a logger that adds fields to every entry.
+func (l *Logger) With(fields ...Field) *Logger {
+	if len(fields) == 0 {
+		return l
+	}
+	c := *l
+	c.fields = nest(l.fields, l.ns, fields)
+	return &c
+}
+
+// Namespace returns a logger whose later fields, from With or a log call,
+// are nested under key.
+func (l *Logger) Namespace(key string) *Logger {
+	c := *l
+	c.ns = append(l.ns[:len(l.ns):len(l.ns)], key)
+	return &c
+}
+
+func (l *Logger) Log(level int, msg string, fields ...Field) { l.log(level, msg, fields...) }
 
 func (l *Logger) Print(msg string) { l.log(INFO, msg) }
 
 func (l *Logger) Printf(format string, a ...any) { l.logf(INFO, format, a...) }
 
+func (l *Logger) Println(a ...any) { l.logln(INFO, a...) }
+
+func (l *Logger) Infoln(a ...any) { l.logln(INFO, a...) }
+
 func (l *Logger) Warn(msg string) { l.log(WARN, msg) }
 
 func (l *Logger) Warnf(format string, a ...any) { l.logf(WARN, format, a...) }
 
+func (l *Logger) Warnln(a ...any) { l.logln(WARN, a...) }
+
 func (l *Logger) Error(msg string) { l.log(ERROR, msg) }
 
 func (l *Logger) Errorf(format string, a ...any) { l.logf(ERROR, format, a...) }
+
+func (l *Logger) Errorln(a ...any) { l.logln(ERROR, a...) }
+
+func (l *Logger) Panic(msg string) { l.panic(msg) }
+
+func (l *Logger) Panicf(format string, a ...any) { l.panic(fmt.Sprintf(format, a...)) }
+
+func (l *Logger) Panicln(a ...any) { l.panic(sprintln(a...)) }
+
+func (l *Logger) Fatal(msg string) { l.fatal(msg) }
+
+func (l *Logger) Fatalf(format string, a ...any) { l.fatal(fmt.Sprintf(format, a...)) }
+
+func (l *Logger) Fatalln(a ...any) { l.fatal(sprintln(a...)) }
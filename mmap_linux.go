@@ -0,0 +1,163 @@
+package speedlog
+
+import (
+	"io"
+	"os"
+	"sync"
+	"syscall"
+	"unsafe"
+)
+
+type mmapWriter struct {
+	mu   sync.Mutex
+	f    *os.File
+	data []byte
+	base int64
+	off  int
+	size int64
+}
+
+// MmapWriter appends into a mapped window of size bytes (rounded up to the
+// page size), remapping the next window when it fills. The file is
+// truncated to the bytes actually written on Close; after a crash the tail
+// of the last window is NUL padding, which is skipped when reopening.
+func MmapWriter(path string, size int64) (io.WriteCloser, error) {
+	page := int64(os.Getpagesize())
+	if size < page {
+		size = page
+	}
+	size = (size + page - 1) &^ (page - 1)
+	f, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0o644)
+	if err != nil {
+		return nil, err
+	}
+	end, err := dataEnd(f)
+	if err != nil {
+		_ = f.Close()
+		return nil, err
+	}
+	m := &mmapWriter{f: f, size: size, base: end &^ (page - 1)}
+	m.off = int(end - m.base)
+	if err := m.mapWindow(); err != nil {
+		_ = f.Close()
+		return nil, err
+	}
+	return m, nil
+}
+
+func dataEnd(f *os.File) (int64, error) {
+	fi, err := f.Stat()
+	if err != nil {
+		return 0, err
+	}
+	end := fi.Size()
+	buf := make([]byte, 64*1024)
+	for end > 0 {
+		n := int64(len(buf))
+		if n > end {
+			n = end
+		}
+		if _, err := f.ReadAt(buf[:n], end-n); err != nil {
+			return 0, err
+		}
+		for i := n - 1; i >= 0; i-- {
+			if buf[i] != 0 {
+				return end - n + i + 1, nil
+			}
+		}
+		end -= n
+	}
+	return 0, nil
+}
+
+func (m *mmapWriter) mapWindow() error {
+	if err := m.f.Truncate(m.base + m.size); err != nil {
+		return err
+	}
+	data, err := syscall.Mmap(int(m.f.Fd()), m.base, int(m.size), syscall.PROT_READ|syscall.PROT_WRITE, syscall.MAP_SHARED)
+	if err != nil {
+		return err
+	}
+	m.data = data
+	return nil
+}
+
+func (m *mmapWriter) unmap() error {
+	if m.data == nil {
+		return nil
+	}
+	err := msync(m.data, syscall.MS_SYNC)
+	if uerr := syscall.Munmap(m.data); err == nil {
+		err = uerr
+	}
+	m.data = nil
+	return err
+}
+
+func (m *mmapWriter) Write(p []byte) (int, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.data == nil {
+		return 0, os.ErrClosed
+	}
+	written := 0
+	for len(p) > 0 {
+		n := copy(m.data[m.off:], p)
+		m.off += n
+		written += n
+		p = p[n:]
+		if m.off == len(m.data) {
+			if err := m.unmap(); err != nil {
+				return written, err
+			}
+			m.base += m.size
+			m.off = 0
+			if err := m.mapWindow(); err != nil {
+				return written, err
+			}
+		}
+	}
+	return written, nil
+}
+
+func (m *mmapWriter) Flush() error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.data == nil {
+		return nil
+	}
+	return msync(m.data, syscall.MS_ASYNC)
+}
+
+func (m *mmapWriter) Sync() error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.data == nil {
+		return nil
+	}
+	return msync(m.data, syscall.MS_SYNC)
+}
+
+func (m *mmapWriter) Close() error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.data == nil {
+		return nil
+	}
+	err := m.unmap()
+	if terr := m.f.Truncate(m.base + int64(m.off)); err == nil {
+		err = terr
+	}
+	if cerr := m.f.Close(); err == nil {
+		err = cerr
+	}
+	return err
+}
+
+func msync(b []byte, flags int) error {
+	_, _, errno := syscall.Syscall(syscall.SYS_MSYNC, uintptr(unsafe.Pointer(&b[0])), uintptr(len(b)), uintptr(flags))
+	if errno != 0 {
+		return errno
+	}
+	return nil
+}
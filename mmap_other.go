@@ -0,0 +1,12 @@
+//go:build !linux
+
+package speedlog
+
+import (
+	"errors"
+	"io"
+)
+
+func MmapWriter(path string, size int64) (io.WriteCloser, error) {
+	return nil, errors.ErrUnsupported
+}
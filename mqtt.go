@@ -0,0 +1,321 @@
+package speedlog
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// MQTTConfig describes an MQTTWriter.
+type MQTTConfig struct {
+	Addr     string // mqtt://[user:pass@]host[:port] or host:port
+	ClientID string // defaults to speedlog-<hostname>-<pid>
+
+	// Topic may reference entry fields as {key} ({http.status} for a
+	// group member) and the level as {level}, e.g.
+	// "devices/{device}/logs/{level}". Missing fields become "_".
+	Topic string
+
+	// QoS 0 is fire-and-forget; 1 and 2 wait for the broker's
+	// acknowledgements before a write returns.
+	QoS    byte
+	Retain bool
+}
+
+// MQTTWriter is a sink that publishes each line (without its newline) as
+// one MQTT 3.1.1 message. A sink write goes out as one socket write, with
+// acknowledgements for QoS 1 and 2 collected afterwards. TLS isn't
+// supported.
+type MQTTWriter struct {
+	mu      sync.Mutex
+	r       redialer
+	br      *bufio.Reader
+	cfg     MQTTConfig
+	user    string
+	pass    string
+	hasPass bool
+	topic   []topicPart
+	static  bool
+	lines   partialLines
+	buf     []byte
+	pending map[uint16]bool
+	id      uint16
+}
+
+type topicPart struct {
+	lit   string
+	field evalFunc
+	level bool
+}
+
+func NewMQTTWriter(cfg MQTTConfig) (*MQTTWriter, error) {
+	if cfg.QoS > 2 {
+		return nil, fmt.Errorf("speedlog: invalid MQTT QoS %d", cfg.QoS)
+	}
+	hostport, u, err := busAddr(cfg.Addr, "mqtt", "1883")
+	if err != nil {
+		return nil, err
+	}
+	parts, err := parseTopic(cfg.Topic)
+	if err != nil {
+		return nil, err
+	}
+	if cfg.ClientID == "" {
+		host, _ := os.Hostname()
+		cfg.ClientID = fmt.Sprintf("speedlog-%s-%d", host, os.Getpid())
+	}
+	w := &MQTTWriter{cfg: cfg, topic: parts, static: len(parts) == 1 && parts[0].field == nil && !parts[0].level, pending: map[uint16]bool{}}
+	if u.User != nil {
+		w.user = u.User.Username()
+		w.pass, w.hasPass = u.User.Password()
+	}
+	w.r = redialer{network: "tcp", addr: hostport, timeout: busTimeout, setup: w.handshake}
+	if _, err := w.r.get(); err != nil {
+		return nil, err
+	}
+	return w, nil
+}
+
+func parseTopic(s string) ([]topicPart, error) {
+	if s == "" || strings.ContainsAny(s, "+#\x00") {
+		return nil, fmt.Errorf("speedlog: invalid MQTT topic %q", s)
+	}
+	var parts []topicPart
+	for s != "" {
+		i := strings.IndexByte(s, '{')
+		if i < 0 {
+			parts = append(parts, topicPart{lit: s})
+			break
+		}
+		j := strings.IndexByte(s[i:], '}')
+		if j < 0 {
+			return nil, fmt.Errorf("speedlog: unclosed { in MQTT topic %q", s)
+		}
+		if i > 0 {
+			parts = append(parts, topicPart{lit: s[:i]})
+		}
+		switch name := s[i+1 : i+j]; name {
+		case "":
+			return nil, errors.New("speedlog: empty {} in MQTT topic")
+		case "level":
+			parts = append(parts, topicPart{level: true})
+		default:
+			parts = append(parts, topicPart{field: fieldOperand(strings.Split(name, "."))})
+		}
+		s = s[i+j+1:]
+	}
+	return parts, nil
+}
+
+func (w *MQTTWriter) appendTopic(buf []byte, line []byte) []byte {
+	if w.static {
+		return append(buf, w.topic[0].lit...)
+	}
+	e, err := ParseLine(line)
+	if err != nil {
+		e = Entry{Level: INFO}
+	}
+	for _, p := range w.topic {
+		switch {
+		case p.level:
+			buf = append(buf, strings.ToLower(LevelName(e.Level))...)
+		case p.field != nil:
+			v := p.field(&e)
+			if !v.ok || v.text() == "" {
+				buf = append(buf, '_')
+				continue
+			}
+			// A value must not add topic levels or wildcards.
+			buf = append(buf, strings.Map(func(r rune) rune {
+				if r == '/' || r == '+' || r == '#' || r == 0 {
+					return '_'
+				}
+				return r
+			}, v.text())...)
+		default:
+			buf = append(buf, p.lit...)
+		}
+	}
+	return buf
+}
+
+func appendMQTTString(buf []byte, s string) []byte {
+	buf = binary.BigEndian.AppendUint16(buf, uint16(len(s)))
+	return append(buf, s...)
+}
+
+// appendPacket writes the fixed header of a packet whose body is buf[start:]
+// and shifts the body up behind it.
+func appendPacket(buf []byte, start int, kind byte) []byte {
+	var hdr [5]byte
+	hdr[0] = kind
+	n := 1
+	for rem := len(buf) - start; ; {
+		b := byte(rem % 128)
+		rem /= 128
+		if rem > 0 {
+			b |= 0x80
+		}
+		hdr[n] = b
+		n++
+		if rem == 0 {
+			break
+		}
+	}
+	buf = append(buf, hdr[:n]...)
+	copy(buf[start+n:], buf[start:len(buf)-n])
+	copy(buf[start:], hdr[:n])
+	return buf
+}
+
+func (w *MQTTWriter) handshake(conn net.Conn) error {
+	w.br = bufio.NewReader(conn)
+	clear(w.pending)
+	var body []byte
+	body = appendMQTTString(body, "MQTT")
+	flags := byte(0x02) // clean session
+	if w.user != "" {
+		flags |= 0x80
+	}
+	if w.hasPass {
+		flags |= 0x40
+	}
+	body = append(body, 4, flags, 0, 0) // protocol level 4, no keepalive
+	body = appendMQTTString(body, w.cfg.ClientID)
+	if w.user != "" {
+		body = appendMQTTString(body, w.user)
+	}
+	if w.hasPass {
+		body = appendMQTTString(body, w.pass)
+	}
+	if _, err := conn.Write(appendPacket(body, 0, 0x10)); err != nil {
+		return err
+	}
+	kind, ack, err := readPacket(w.br)
+	if err != nil {
+		return err
+	}
+	if kind>>4 != 2 || len(ack) != 2 {
+		return errors.New("speedlog: mqtt: expected CONNACK")
+	}
+	if ack[1] != 0 {
+		return fmt.Errorf("speedlog: mqtt: connection refused (code %d)", ack[1])
+	}
+	return nil
+}
+
+func readPacket(br *bufio.Reader) (kind byte, body []byte, err error) {
+	if kind, err = br.ReadByte(); err != nil {
+		return 0, nil, err
+	}
+	n, shift := 0, 0
+	for {
+		b, err := br.ReadByte()
+		if err != nil {
+			return 0, nil, err
+		}
+		n |= int(b&0x7f) << shift
+		if b&0x80 == 0 {
+			break
+		}
+		if shift += 7; shift > 21 {
+			return 0, nil, errors.New("speedlog: mqtt: bad packet length")
+		}
+	}
+	body = make([]byte, n)
+	_, err = io.ReadFull(br, body)
+	return kind, body, err
+}
+
+func (w *MQTTWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.lines.write(p, w.publish)
+}
+
+func (w *MQTTWriter) publish(lines []byte) error {
+	return w.r.do(func(conn net.Conn) error {
+		clear(w.pending)
+		w.buf = w.buf[:0]
+		kind := 0x30 | w.cfg.QoS<<1
+		if w.cfg.Retain {
+			kind |= 1
+		}
+		_ = eachLine(lines, func(line []byte) error {
+			start := len(w.buf)
+			w.buf = append(w.buf, 0, 0)
+			w.buf = w.appendTopic(w.buf, line)
+			binary.BigEndian.PutUint16(w.buf[start:], uint16(len(w.buf)-start-2))
+			if w.cfg.QoS > 0 {
+				if w.id++; w.id == 0 {
+					w.id = 1
+				}
+				w.pending[w.id] = true
+				w.buf = binary.BigEndian.AppendUint16(w.buf, w.id)
+			}
+			w.buf = append(w.buf, line...)
+			w.buf = appendPacket(w.buf, start, kind)
+			return nil
+		})
+		if len(w.buf) == 0 {
+			return nil
+		}
+		_ = conn.SetDeadline(time.Now().Add(busTimeout))
+		defer conn.SetDeadline(time.Time{})
+		if _, err := conn.Write(w.buf); err != nil {
+			return err
+		}
+		return w.awaitAcks(conn)
+	})
+}
+
+// awaitAcks collects a PUBACK per message for QoS 1, or runs the
+// PUBREC/PUBREL/PUBCOMP exchange for QoS 2.
+func (w *MQTTWriter) awaitAcks(conn net.Conn) error {
+	want := byte(4) // PUBACK
+	if w.cfg.QoS == 2 {
+		want = 5 // PUBREC
+	}
+	for w.cfg.QoS > 0 && len(w.pending) > 0 {
+		kind, body, err := readPacket(w.br)
+		if err != nil {
+			return err
+		}
+		if len(body) < 2 {
+			continue
+		}
+		id := binary.BigEndian.Uint16(body)
+		switch kind >> 4 {
+		case want:
+			if want == 5 {
+				if _, err := conn.Write([]byte{0x62, 2, byte(id >> 8), byte(id)}); err != nil {
+					return err
+				}
+				continue
+			}
+			delete(w.pending, id)
+		case 7: // PUBCOMP
+			delete(w.pending, id)
+		}
+	}
+	return nil
+}
+
+// Close publishes a trailing line that lacked a newline, then disconnects.
+func (w *MQTTWriter) Close() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	err := w.lines.flush(w.publish)
+	if w.r.conn != nil {
+		_, _ = w.r.conn.Write([]byte{0xe0, 0}) // DISCONNECT
+	}
+	w.r.drop()
+	return err
+}
@@ -0,0 +1,34 @@
+package speedlog
+
+import (
+	"io"
+	"math"
+	"os"
+	"sync/atomic"
+)
+
+// Discard is a sink writer that drops everything, for benchmarking the
+// pipeline without I/O: MustNew(WithWriter(Discard)).
+var Discard io.Writer = io.Discard
+
+// Nop returns a logger that logs nothing and runs no goroutines, for
+// libraries that take an optional *Logger. Every level is disabled and
+// SetLevel has no effect; Fatal still exits and Panic still panics.
+// AddSink fails with ErrClosed and leaves the writer alone.
+func Nop() *Logger {
+	l := &Logger{core: &core{
+		done:        make(chan struct{}),
+		barrierReq:  make(chan chan struct{}),
+		nop:         true,
+		onError:     func(error) {},
+		sinksClosed: true,
+	}, level: new(atomic.Int32), enc: TextEncoder{}}
+	l.level.Store(math.MaxInt32)
+	exit := os.Exit
+	l.exit.Store(&exit)
+	// A nop logger starts out shut down: emit and barrier see done closed
+	// and return, and Close does nothing.
+	close(l.done)
+	l.closeOnce.Do(func() {})
+	return l
+}
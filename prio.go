@@ -0,0 +1,48 @@
+package speedlog
+
+// WithPriorityQueue gives entries at level and above (e.g. ERROR) their own
+// queue of size records, which the writer always drains before the main
+// queue. A flood of INFO that fills the main queue then never delays or
+// blocks them. Urgent entries can overtake earlier, lower-level ones.
+func WithPriorityQueue(level, size int) Option {
+	return func(l *Logger) {
+		if size <= 0 {
+			l.invalid("WithPriorityQueue(%d, %d): size must be positive", level, size)
+			return
+		}
+		l.hi = make(chan record, size)
+		l.hiLevel = level
+	}
+}
+
+func (l *Logger) urgent(level int) bool {
+	return l.hi != nil && level >= l.hiLevel
+}
+
+// nextUrgent fills batch from the priority queue only, so it is never mixed
+// with ring slots that have to be released in order.
+func (l *Logger) nextUrgent(batch []record) []record {
+	n := 0
+	for len(batch) < l.batchN && n < l.batchBytes {
+		select {
+		case rec := <-l.hi:
+			batch = append(batch, rec)
+			n += len(rec.line)
+		default:
+			return batch
+		}
+	}
+	return batch
+}
+
+// drainUrgent writes everything in the priority queue before the writer
+// turns to the main queue.
+func (l *Logger) drainUrgent(batch []record) []record {
+	for l.hi != nil && len(l.hi) > 0 {
+		l.hiBatch = true
+		batch = l.nextUrgent(batch[:0])
+		l.writeBatch(batch)
+		l.release(batch)
+	}
+	return batch[:0]
+}
@@ -0,0 +1,29 @@
+package speedlog
+
+import (
+	"bytes"
+	"errors"
+	"time"
+)
+
+var ErrRawLine = errors.New("speedlog: raw line must be one non-empty line")
+
+// WriteRaw queues an already formatted line, for bridges that receive
+// serialized entries and shouldn't re-encode them. A missing trailing
+// newline is added; a line with a newline anywhere else is rejected. The
+// level decides whether it is logged and where it is routed, but field
+// predicates see no fields. It bypasses suppression and Deferred.
+func (l *Logger) WriteRaw(level int, line []byte) error {
+	if n := bytes.IndexByte(line, '\n'); len(line) == 0 || n >= 0 && n != len(line)-1 {
+		return ErrRawLine
+	}
+	if !l.IsLevelEnabled(level) {
+		return nil
+	}
+	if line[len(line)-1] != '\n' {
+		line = append(line[:len(line):len(line)], '\n')
+	}
+	e := Entry{Level: level, Time: time.Now()}
+	l.emitLine(&e, line)
+	return nil
+}
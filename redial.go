@@ -0,0 +1,86 @@
+package speedlog
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"time"
+)
+
+var ErrReconnecting = errors.New("speedlog: connection lost, waiting to reconnect")
+
+const (
+	minRedial = 100 * time.Millisecond
+	maxRedial = 10 * time.Second
+)
+
+// redialer owns the connection of a network writer. A failed dial backs off
+// from 100ms up to 10s; writes in between fail fast with ErrReconnecting,
+// which the sink counts like any other write error. It is not safe for
+// concurrent use; writers hold their own lock.
+type redialer struct {
+	network string
+	addr    string
+	timeout time.Duration
+	// setup runs once per connection, before any write (handshakes, auth).
+	setup func(net.Conn) error
+
+	conn  net.Conn
+	wait  time.Duration
+	retry time.Time
+}
+
+func (r *redialer) get() (net.Conn, error) {
+	if r.conn != nil {
+		return r.conn, nil
+	}
+	if time.Now().Before(r.retry) {
+		return nil, ErrReconnecting
+	}
+	conn, err := net.DialTimeout(r.network, r.addr, r.timeout)
+	if err == nil && r.setup != nil {
+		_ = conn.SetDeadline(time.Now().Add(r.timeout))
+		if err = r.setup(conn); err != nil {
+			conn.Close()
+		}
+		_ = conn.SetDeadline(time.Time{})
+	}
+	if err != nil {
+		r.wait = min(max(2*r.wait, minRedial), maxRedial)
+		r.retry = time.Now().Add(r.wait)
+		return nil, fmt.Errorf("speedlog: connect %s: %w", r.addr, err)
+	}
+	r.conn, r.wait = conn, 0
+	return conn, nil
+}
+
+// do runs fn on a connection, redialing once if the current one turns out
+// to be dead, which is how a server restart usually shows up.
+func (r *redialer) do(fn func(net.Conn) error) error {
+	fresh := r.conn == nil
+	conn, err := r.get()
+	if err != nil {
+		return err
+	}
+	if err = fn(conn); err == nil || fresh {
+		if err != nil {
+			r.drop()
+		}
+		return err
+	}
+	r.drop()
+	if conn, err = r.get(); err != nil {
+		return err
+	}
+	if err = fn(conn); err != nil {
+		r.drop()
+	}
+	return err
+}
+
+func (r *redialer) drop() {
+	if r.conn != nil {
+		r.conn.Close()
+		r.conn = nil
+	}
+}
@@ -0,0 +1,167 @@
+package speedlog
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"io"
+	"os"
+	"time"
+)
+
+var ErrReplayOwnWAL = errors.New("speedlog: can't replay a sink's live WAL")
+
+type ReplayOptions struct {
+	// Rate caps the lines sent per second; 0 sends them as fast as the
+	// sink takes them.
+	Rate int
+	// Remove deletes the file once all of it was sent without the sink
+	// failing.
+	Remove bool
+}
+
+// Replay sends the lines stored at path to the sink id and returns how
+// many it sent, for backfilling a remote sink after an outage: a WAL left
+// behind by WithWAL (of a sink that no longer runs), a dead-letter file or
+// an old segment, decompressed when its extension names a registered
+// codec. Lines go out byte for byte, as inline writes under the same lock
+// as WithSyncLevel, so level, routing rules and the queue don't apply, but
+// a signing sink signs them again. The sink is flushed at the end and its
+// error, if its last write failed, is returned. Lines over 1 MiB stop the
+// replay with bufio.ErrTooLong.
+func (l *Logger) Replay(path string, id SinkID, opts ReplayOptions) (int, error) {
+	if l.isClosed() {
+		return 0, ErrClosed
+	}
+	s := l.sinkByID(id)
+	if s == nil {
+		return 0, ErrNoSink
+	}
+	if s.wal != nil && sameFile(s.wal.path, path) {
+		return 0, ErrReplayOwnWAL
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return 0, err
+	}
+	defer f.Close()
+	var r io.Reader = f
+	if c, ok := CodecForFile(path); ok {
+		zr, err := c.NewReader(f)
+		if err != nil {
+			return 0, err
+		}
+		defer zr.Close()
+		r = zr
+	}
+	sc := bufio.NewScanner(r)
+	sc.Buffer(make([]byte, 0, 64<<10), 1<<20)
+	sc.Split(scanRawLines)
+
+	per := 256
+	if opts.Rate > 0 {
+		per = max(1, min(per, opts.Rate/20))
+	}
+	var (
+		batch []record
+		buf   []byte
+		sent  int
+		start = time.Now()
+	)
+	for more := true; more; {
+		batch, buf = batch[:0], buf[:0]
+		for len(batch) < per && sc.Scan() {
+			line := sc.Bytes()
+			off := len(buf)
+			buf = append(buf, line...)
+			if line[len(line)-1] != '\n' {
+				buf = append(buf, '\n')
+			}
+			batch = append(batch, record{line: buf[off:len(buf):len(buf)]})
+		}
+		more = len(batch) == per
+		if len(batch) > 0 {
+			if err := l.replayBatch(s, batch); err != nil {
+				return sent, err
+			}
+			sent += len(batch)
+		}
+		if opts.Rate > 0 && more {
+			due := start.Add(time.Duration(sent) * time.Second / time.Duration(opts.Rate))
+			time.Sleep(time.Until(due))
+		}
+	}
+	if err := sc.Err(); err != nil {
+		return sent, err
+	}
+	if s.async() {
+		s.requestFlush()
+		syncQueued([]*sink{s})
+	}
+	var serr error
+	switch e := s.failing.Load(); {
+	case s.ejected.Load():
+		serr = &SinkError{Sink: s.id, Err: ErrSinkEjected}
+	case e != nil:
+		serr = e
+	}
+	if serr == nil && opts.Remove {
+		serr = os.Remove(path)
+	}
+	return sent, serr
+}
+
+// replayBatch writes batch to s like writeInline does, unless s was
+// removed or the logger closed meanwhile.
+func (l *Logger) replayBatch(s *sink, batch []record) error {
+	l.inlineMu.Lock()
+	defer l.inlineMu.Unlock()
+	switch {
+	case l.inlineDone:
+		return ErrClosed
+	case l.sinkByID(SinkID(s.id)) != s:
+		return ErrNoSink
+	case s.async():
+		for _, rec := range batch {
+			s.enqueue(rec)
+		}
+		return nil
+	}
+	// Flush per batch so Rate paces the sink rather than its buffer.
+	s.writeRecords(batch, false)
+	s.flush()
+	if s.dirty && s.fsync.mode != fsyncNever {
+		s.sync()
+	}
+	return nil
+}
+
+func (l *Logger) sinkByID(id SinkID) *sink {
+	for _, s := range l.sinkList() {
+		if s.id == int(id) {
+			return s
+		}
+	}
+	return nil
+}
+
+// scanRawLines is bufio.ScanLines keeping the newline and any \r, so
+// lines are replayed byte for byte.
+func scanRawLines(data []byte, atEOF bool) (int, []byte, error) {
+	if i := bytes.IndexByte(data, '\n'); i >= 0 {
+		return i + 1, data[:i+1], nil
+	}
+	if atEOF && len(data) > 0 {
+		return len(data), data, nil
+	}
+	return 0, nil, nil
+}
+
+func sameFile(a, b string) bool {
+	fa, err := os.Stat(a)
+	if err != nil {
+		return false
+	}
+	fb, err := os.Stat(b)
+	return err == nil && os.SameFile(fa, fb)
+}
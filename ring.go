@@ -0,0 +1,143 @@
+package speedlog
+
+import (
+	"sync"
+	"sync/atomic"
+)
+
+// ring is a bounded multi-producer single-consumer queue (Vyukov style).
+// Each slot owns its buffer, so producers format straight into the slot
+// and nothing is handed off through a pool.
+type ringSlot struct {
+	seq   atomic.Uint64
+	buf   []byte
+	mask  uint64
+	level int
+	at    int64
+}
+
+type ring struct {
+	slots   []ringSlot
+	mask    uint64
+	_       [64]byte
+	head    atomic.Uint64
+	_       [64]byte
+	tail    atomic.Uint64
+	wake    chan struct{}
+	waiters atomic.Int32
+	spaceMu sync.Mutex
+	space   chan struct{}
+}
+
+func newRing(size int) *ring {
+	n := 1
+	for n < size {
+		n <<= 1
+	}
+	r := &ring{
+		slots: make([]ringSlot, n),
+		mask:  uint64(n - 1),
+		wake:  make(chan struct{}, 1),
+		space: make(chan struct{}),
+	}
+	for i := range r.slots {
+		r.slots[i].seq.Store(uint64(i))
+	}
+	return r
+}
+
+func WithRingBuffer(size int) Option {
+	return func(l *Logger) {
+		if size <= 0 {
+			l.invalid("WithRingBuffer(%d): size must be positive", size)
+			return
+		}
+		l.ring = newRing(size)
+	}
+}
+
+func (r *ring) claim(done <-chan struct{}) (*ringSlot, uint64, bool) {
+	for {
+		pos := r.head.Load()
+		s := &r.slots[pos&r.mask]
+		switch dif := int64(s.seq.Load() - pos); {
+		case dif == 0:
+			if r.head.CompareAndSwap(pos, pos+1) {
+				return s, pos, true
+			}
+		case dif < 0:
+			if !r.waitSpace(done) {
+				return nil, 0, false
+			}
+		}
+	}
+}
+
+func (r *ring) publish(s *ringSlot, pos uint64) {
+	s.seq.Store(pos + 1)
+	select {
+	case r.wake <- struct{}{}:
+	default:
+	}
+}
+
+func (r *ring) full() bool {
+	pos := r.head.Load()
+	return int64(r.slots[pos&r.mask].seq.Load()-pos) < 0
+}
+
+func (r *ring) waitSpace(done <-chan struct{}) bool {
+	r.waiters.Add(1)
+	defer r.waiters.Add(-1)
+	r.spaceMu.Lock()
+	ch := r.space
+	r.spaceMu.Unlock()
+	if !r.full() {
+		return true
+	}
+	select {
+	case <-ch:
+		return true
+	case <-done:
+		return false
+	}
+}
+
+func (r *ring) len() int {
+	tail := r.tail.Load()
+	return int(r.head.Load() - tail)
+}
+
+func (r *ring) take(batch []record, maxN, maxBytes int) []record {
+	n := 0
+	tail := r.tail.Load()
+	for i := uint64(0); len(batch) < maxN && n < maxBytes; i++ {
+		s := &r.slots[(tail+i)&r.mask]
+		if s.seq.Load() != tail+i+1 {
+			break
+		}
+		batch = append(batch, record{line: s.buf, mask: s.mask, level: s.level, at: s.at})
+		n += len(s.buf)
+	}
+	return batch
+}
+
+func (r *ring) release(batch []record) {
+	tail := r.tail.Load()
+	for _, rec := range batch {
+		s := &r.slots[tail&r.mask]
+		s.buf = rec.line[:0]
+		if cap(s.buf) > maxPooledBuf {
+			s.buf = nil
+		}
+		s.seq.Store(tail + r.mask + 1)
+		tail++
+	}
+	r.tail.Store(tail)
+	if r.waiters.Load() > 0 {
+		r.spaceMu.Lock()
+		close(r.space)
+		r.space = make(chan struct{})
+		r.spaceMu.Unlock()
+	}
+}
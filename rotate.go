@@ -0,0 +1,606 @@
+package speedlog
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+)
+
+// WithPathTemplate treats the path given to NewFileWriter as a time
+// template: strftime verbs (%Y %y %m %d %j %H %M %S %b %a %%) when it
+// contains a '%', a Go layout otherwise (the whole path is formatted, so
+// digits in directory names change too). The writer switches to a new file
+// as soon as the name changes, checking at most once per second. A nil loc
+// means local time. Missing directories are created.
+func WithPathTemplate(loc *time.Location) FileOption {
+	return func(fw *FileWriter) {
+		if loc == nil {
+			loc = time.Local
+		}
+		fw.tmpl = parsePathTemplate(fw.path)
+		fw.loc = loc
+	}
+}
+
+// WithRotateAt rotates the file at clock boundaries: every interval from
+// midnight in loc (nil means local time), so time.Hour rotates at the top
+// of each hour and 24*time.Hour at midnight. Intervals that don't divide a
+// day leave a shorter last period before midnight; longer ones mean daily.
+// The rotation happens on the first write after the boundary, and an
+// empty file is left in place.
+func WithRotateAt(interval time.Duration, loc *time.Location) FileOption {
+	return func(fw *FileWriter) {
+		if interval <= 0 {
+			return
+		}
+		if loc == nil {
+			loc = time.Local
+		}
+		fw.every = min(interval, 24*time.Hour)
+		fw.rotLoc = loc
+	}
+}
+
+// nextBoundary returns the first boundary after now: midnight in loc plus
+// a whole number of intervals, or the next midnight.
+func nextBoundary(now time.Time, every time.Duration, loc *time.Location) time.Time {
+	t := now.In(loc)
+	midnight := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, loc)
+	next := midnight.Add((t.Sub(midnight)/every + 1) * every)
+	if tomorrow := time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, loc); !next.Before(tomorrow) {
+		return tomorrow
+	}
+	return next
+}
+
+func (fw *FileWriter) rotateDue(now time.Time) error {
+	if now.Before(fw.nextRotate) {
+		return nil
+	}
+	fw.nextRotate = nextBoundary(now, fw.every, fw.rotLoc)
+	if fw.size == 0 {
+		return nil
+	}
+	return fw.rotate()
+}
+
+// WithDiskBudget caps the bytes used by the active file plus its rotated
+// segments (and, with WithPathTemplate, the files of earlier periods),
+// deleting the oldest segments first. It is checked after every rotation
+// and each time another sixteenth of the budget has been written; the
+// active file itself is never deleted.
+func WithDiskBudget(bytes int64) FileOption {
+	return func(fw *FileWriter) {
+		if bytes > 0 {
+			fw.budget = bytes
+		}
+	}
+}
+
+type segment struct {
+	path string
+	os.FileInfo
+}
+
+// segments lists finished files, oldest first: rotated copies of the
+// current file, plus earlier template periods and their rotations. Globs
+// only propose candidates; a file counts only if its name parses back
+// through the template or is the current path with a rotation suffix, so
+// the budget never deletes files that aren't the writer's.
+func (fw *FileWriter) segments() []segment {
+	patterns := []string{globEscape(fw.path) + ".*"}
+	if fw.tmpl != nil {
+		if pat := fw.tmpl.glob(); pat != "*" {
+			patterns = append(patterns, pat, pat+".*")
+		}
+	}
+	seen := map[string]bool{fw.path: true, fw.manifest: true}
+	var segs []segment
+	for _, pat := range patterns {
+		names, _ := filepath.Glob(pat)
+		for _, name := range names {
+			if seen[name] {
+				continue
+			}
+			seen[name] = true
+			if !fw.isSegment(name) {
+				continue
+			}
+			if fi, err := os.Lstat(name); err == nil && fi.Mode().IsRegular() {
+				segs = append(segs, segment{name, fi})
+			}
+		}
+	}
+	slices.SortFunc(segs, func(a, b segment) int { return a.ModTime().Compare(b.ModTime()) })
+	return segs
+}
+
+// isSegment reports whether name is a finished file of fw: its current
+// path or a template period, possibly with the suffixes rotate and
+// WithCompression add.
+func (fw *FileWriter) isSegment(name string) bool {
+	if fw.codec != nil {
+		name = strings.TrimSuffix(name, fw.codec.Ext())
+	}
+	if fw.tmpl != nil && fw.tmpl.match(name) {
+		return true
+	}
+	base := unrotate(name)
+	return base != "" && (base == fw.path || fw.tmpl != nil && fw.tmpl.match(base))
+}
+
+// unrotate strips the .YYYYMMDD-HHMMSS[.N] suffix rotate adds, or returns
+// "" if name has none.
+func unrotate(name string) string {
+	if i := strings.LastIndexByte(name, '.'); i >= 0 && i+1 < len(name) &&
+		strings.Trim(name[i+1:], "0123456789") == "" {
+		name = name[:i]
+	}
+	i := strings.LastIndexByte(name, '.')
+	if i < 0 {
+		return ""
+	}
+	if _, err := time.Parse("20060102-150405", name[i+1:]); err != nil {
+		return ""
+	}
+	return name[:i]
+}
+
+func globEscape(s string) string {
+	var b strings.Builder
+	for _, c := range s {
+		if c == '*' || c == '?' || c == '[' {
+			b.WriteByte('[')
+			b.WriteRune(c)
+			b.WriteByte(']')
+			continue
+		}
+		b.WriteRune(c)
+	}
+	return b.String()
+}
+
+// enforceBudget removes the oldest segments until the total fits, except
+// those the finisher hasn't got to or is working on (including the
+// compressed copy it is writing): they count, but stay.
+func (fw *FileWriter) enforceBudget() {
+	fw.unchecked = 0
+	total := fw.size
+	segs := fw.segments()
+	for _, seg := range segs {
+		total += seg.Size()
+	}
+	fw.finishMu.Lock()
+	busy := make(map[string]bool, len(fw.finishQ))
+	for _, path := range fw.finishQ {
+		busy[path] = true
+	}
+	fw.finishMu.Unlock()
+	for _, seg := range segs {
+		if total <= fw.budget {
+			return
+		}
+		path := seg.path
+		if fw.codec != nil {
+			path = strings.TrimSuffix(path, fw.codec.Ext())
+		}
+		if busy[path] {
+			continue
+		}
+		if os.Remove(seg.path) == nil {
+			total -= seg.Size()
+		}
+	}
+}
+
+// WithArchive calls fn for every finished file: a rotated segment, or the
+// file of a template period that ended. Calls run one at a time on their
+// own goroutine, so slow uploads never hold up writes; Close waits for
+// them. With remove, the local file is deleted once fn succeeds. A failure
+// is returned by the next Write (after its data was written) or by Close.
+// S3Uploader and GCSUploader provide fn for object storage.
+func WithArchive(fn func(path string) error, remove bool) FileOption {
+	return func(fw *FileWriter) {
+		fw.archive = fn
+		fw.archiveDel = remove
+	}
+}
+
+// WithCompression compresses every finished file to path+c.Ext() and
+// removes the original, before WithManifest and WithArchive see it. It
+// runs on their goroutine, so writes never wait for it.
+func WithCompression(c Codec) FileOption {
+	return func(fw *FileWriter) {
+		fw.codec = c
+	}
+}
+
+// startFinisher runs the compression, manifest and archive steps for
+// finished files, in that order so the checksum is taken before an upload
+// removes the file. Files queue up without bound, so a slow upload never
+// blocks the writes that finish more files.
+func (fw *FileWriter) startFinisher() {
+	fw.finishSig = make(chan struct{}, 1)
+	fw.finishWG.Add(1)
+	go func() {
+		defer fw.finishWG.Done()
+		for {
+			// A path leaves the queue only once it was finished, so
+			// enforceBudget can tell which files are still in use.
+			fw.finishMu.Lock()
+			if len(fw.finishQ) == 0 {
+				done := fw.finishDone
+				fw.finishMu.Unlock()
+				if done {
+					return
+				}
+				<-fw.finishSig
+				continue
+			}
+			path := fw.finishQ[0]
+			fw.finishMu.Unlock()
+			fw.finish(path)
+			fw.finishMu.Lock()
+			fw.finishQ = fw.finishQ[1:]
+			fw.finishMu.Unlock()
+		}
+	}()
+}
+
+func (fw *FileWriter) finish(path string) {
+	if fw.codec != nil {
+		if zpath, err := compressFile(fw.codec, path); err != nil {
+			err = fmt.Errorf("speedlog: compress %s: %w", path, err)
+			fw.finishErr.Store(&err)
+		} else {
+			path = zpath
+		}
+	}
+	if fw.manifest != "" {
+		if err := fw.recordSegment(path); err != nil {
+			err = fmt.Errorf("speedlog: manifest %s: %w", path, err)
+			fw.finishErr.Store(&err)
+		}
+	}
+	if fw.archive == nil {
+		return
+	}
+	err := fw.archive(path)
+	if err == nil && fw.archiveDel {
+		err = os.Remove(path)
+	}
+	if err != nil {
+		err = fmt.Errorf("speedlog: archive %s: %w", path, err)
+		fw.finishErr.Store(&err)
+	}
+}
+
+// stopFinisher waits until every queued file was finished.
+func (fw *FileWriter) stopFinisher() {
+	fw.finishMu.Lock()
+	fw.finishDone = true
+	fw.finishMu.Unlock()
+	fw.signalFinisher()
+	fw.finishWG.Wait()
+}
+
+func (fw *FileWriter) signalFinisher() {
+	select {
+	case fw.finishSig <- struct{}{}:
+	default:
+	}
+}
+
+// WithManifest appends one JSON line per finished file to path:
+//
+//	{"file":"app.log.20240102-000000","size":1048576,"lines":5120,
+//	 "first":"2024-01-01T00:00:00.012Z","last":"2024-01-01T23:59:59.870Z",
+//	 "sha256":"..."}
+//
+// first and last are the timestamps of its first and last entries (omitted
+// when those lines don't parse). For a file compressed by WithCompression,
+// size and sha256 are those of the compressed file. Entries are written
+// from the same goroutine as WithArchive, before the upload, and fsynced.
+func WithManifest(path string) FileOption {
+	return func(fw *FileWriter) {
+		fw.manifest = path
+	}
+}
+
+type manifestEntry struct {
+	File   string     `json:"file"`
+	Size   int64      `json:"size"`
+	Lines  int64      `json:"lines"`
+	First  *time.Time `json:"first,omitempty"`
+	Last   *time.Time `json:"last,omitempty"`
+	SHA256 string     `json:"sha256"`
+}
+
+func (fw *FileWriter) recordSegment(path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	m := manifestEntry{File: filepath.Base(path)}
+	h := sha256.New()
+	var r io.Reader = f
+	zipped := fw.codec != nil && strings.HasSuffix(path, fw.codec.Ext())
+	if zipped {
+		zr, err := fw.codec.NewReader(io.TeeReader(f, h))
+		if err != nil {
+			return err
+		}
+		defer zr.Close()
+		r = zr
+	}
+	br := bufio.NewReaderSize(r, 64<<10)
+	var first, last []byte
+	for {
+		line, err := br.ReadSlice('\n')
+		if len(line) > 0 {
+			if !zipped {
+				h.Write(line)
+				m.Size += int64(len(line))
+			}
+			if first == nil {
+				first = append([]byte{}, line...)
+			}
+			if err != bufio.ErrBufferFull {
+				m.Lines++
+				if len(bytes.TrimSpace(line)) > 0 {
+					last = append(last[:0], line...)
+				}
+			}
+		}
+		if err == io.EOF {
+			break
+		}
+		if err != nil && err != bufio.ErrBufferFull {
+			return err
+		}
+	}
+	if zipped {
+		// The decompressor may stop short of the trailer.
+		if _, err := io.Copy(h, f); err != nil {
+			return err
+		}
+		fi, err := f.Stat()
+		if err != nil {
+			return err
+		}
+		m.Size = fi.Size()
+	}
+	m.SHA256 = hex.EncodeToString(h.Sum(nil))
+	if e, err := ParseLine(bytes.TrimRight(first, "\n")); err == nil {
+		m.First = &e.Time
+	}
+	if e, err := ParseLine(bytes.TrimRight(last, "\n")); err == nil {
+		m.Last = &e.Time
+	}
+	b, _ := json.Marshal(m)
+	mf, err := os.OpenFile(fw.manifest, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return err
+	}
+	if _, err = mf.Write(append(b, '\n')); err == nil {
+		err = mf.Sync()
+	}
+	if cerr := mf.Close(); err == nil {
+		err = cerr
+	}
+	return err
+}
+
+func (fw *FileWriter) finished(path string) {
+	if fw.finishSig == nil {
+		return
+	}
+	fw.finishMu.Lock()
+	fw.finishQ = append(fw.finishQ, path)
+	fw.finishMu.Unlock()
+	fw.signalFinisher()
+}
+
+// pathTemplate alternates literal text with Go layout chunks, so literal
+// parts of a strftime path are never read as layout tokens.
+type pathTemplate []pathPart
+
+type pathPart struct {
+	lit    string
+	layout string
+	width  int // formatted length of a strftime verb; 0 for a Go layout
+}
+
+var strftimeVerbs = map[byte]string{
+	'Y': "2006", 'y': "06", 'm': "01", 'd': "02", 'j': "002",
+	'H': "15", 'M': "04", 'S': "05", 'b': "Jan", 'a': "Mon",
+}
+
+func parsePathTemplate(path string) pathTemplate {
+	if !strings.Contains(path, "%") {
+		return pathTemplate{{layout: path}}
+	}
+	var t pathTemplate
+	var lit strings.Builder
+	for i := 0; i < len(path); i++ {
+		c := path[i]
+		if c != '%' || i+1 == len(path) {
+			lit.WriteByte(c)
+			continue
+		}
+		i++
+		layout, ok := strftimeVerbs[path[i]]
+		if !ok {
+			// %% and unknown verbs are kept literally, minus the %.
+			lit.WriteByte(path[i])
+			continue
+		}
+		if lit.Len() > 0 {
+			t = append(t, pathPart{lit: lit.String()})
+			lit.Reset()
+		}
+		t = append(t, pathPart{layout: layout, width: len(layout)})
+	}
+	if lit.Len() > 0 {
+		t = append(t, pathPart{lit: lit.String()})
+	}
+	return t
+}
+
+func (t pathTemplate) format(now time.Time) string {
+	var b []byte
+	for _, p := range t {
+		if p.layout != "" {
+			b = now.AppendFormat(b, p.layout)
+		} else {
+			b = append(b, p.lit...)
+		}
+	}
+	return string(b)
+}
+
+// match reports whether name is a file the template names for some time:
+// literal parts must match exactly and each layout chunk must parse.
+func (t pathTemplate) match(name string) bool {
+	for _, p := range t {
+		switch {
+		case p.layout == "":
+			var ok bool
+			if name, ok = strings.CutPrefix(name, p.lit); !ok {
+				return false
+			}
+		case p.width == 0:
+			_, err := time.Parse(p.layout, name)
+			return err == nil
+		default:
+			if len(name) < p.width {
+				return false
+			}
+			if _, err := time.Parse(p.layout, name[:p.width]); err != nil {
+				return false
+			}
+			name = name[p.width:]
+		}
+	}
+	return name == ""
+}
+
+// glob returns a pattern matching every name of the template (and some
+// others, which match rejects). Strftime verbs have a fixed width, so they
+// become runs of '?'. A Go layout is globbed per path element: elements
+// without layout tokens are kept, the others become '*' between the
+// literal prefix and suffix (cut at a '-', '_' or '.') they always have.
+func (t pathTemplate) glob() string {
+	var b strings.Builder
+	for _, p := range t {
+		switch {
+		case p.layout == "":
+			b.WriteString(globEscape(p.lit))
+		case p.width > 0:
+			b.WriteString(strings.Repeat("?", p.width))
+		default:
+			b.WriteString(layoutGlob(p.layout))
+		}
+	}
+	return b.String()
+}
+
+// Two times that format differently in nearly every layout token, so the
+// text they share is mostly literal.
+var (
+	globProbeA = time.Date(2001, 1, 1, 1, 1, 1, 0, time.UTC)
+	globProbeB = time.Date(1998, 12, 29, 14, 58, 59, 0, time.UTC)
+)
+
+func layoutGlob(layout string) string {
+	var b strings.Builder
+	for len(layout) > 0 {
+		i := strings.IndexFunc(layout, isPathSep)
+		if i < 0 {
+			i = len(layout)
+		}
+		b.WriteString(elemGlob(layout[:i]))
+		if i < len(layout) {
+			b.WriteByte(layout[i])
+			i++
+		}
+		layout = layout[i:]
+	}
+	return b.String()
+}
+
+func isPathSep(c rune) bool { return c == '/' || c == filepath.Separator }
+
+func elemGlob(e string) string {
+	a, b := globProbeA.Format(e), globProbeB.Format(e)
+	switch {
+	case a == e && b == e:
+		return globEscape(e)
+	case a == b:
+		return "*"
+	}
+	n := 0
+	for n < len(a) && n < len(b) && a[n] == b[n] {
+		n++
+	}
+	prefix := a[:strings.LastIndexAny(a[:n], "-_.")+1]
+	m := 0
+	for m < min(len(a), len(b))-n && a[len(a)-1-m] == b[len(b)-1-m] {
+		m++
+	}
+	suffix := a[len(a)-m:]
+	if j := strings.IndexAny(suffix, "-_."); j >= 0 {
+		suffix = suffix[j:]
+	} else {
+		suffix = ""
+	}
+	return globEscape(prefix) + "*" + globEscape(suffix)
+}
+
+// switchPath moves to the file the template names for now, if that
+// changed. If the new file can't be opened, writes stay on the previous
+// one and the switch is retried a second later.
+func (fw *FileWriter) switchPath(now time.Time) error {
+	if now.Before(fw.nextName) {
+		return nil
+	}
+	fw.nextName = now.Truncate(time.Second).Add(time.Second)
+	name := fw.tmpl.format(now.In(fw.loc))
+	if name == fw.path && fw.f != nil {
+		return nil
+	}
+	if fw.f != nil {
+		fw.trim()
+	}
+	old, prev := fw.f, fw.path
+	fw.path = name
+	err := os.MkdirAll(filepath.Dir(name), 0o755)
+	if err == nil {
+		err = fw.open()
+	}
+	if err != nil {
+		fw.f, fw.path = old, prev
+		if old != nil {
+			return nil
+		}
+		return err
+	}
+	if old != nil {
+		_ = old.Close()
+		fw.finished(prev)
+	}
+	if fw.budget > 0 {
+		fw.enforceBudget()
+	}
+	return nil
+}
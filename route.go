@@ -0,0 +1,201 @@
+package speedlog
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path"
+	"slices"
+)
+
+// WithSinkName names a sink so routing rules can refer to it.
+func WithSinkName(name string) SinkOption {
+	return func(s *sink) {
+		s.name = name
+	}
+}
+
+// Named returns a logger whose entries carry a logger=name field, for
+// routing by Rule.Logger. Names nest with dots: l.Named("db").Named("pool")
+// is "db.pool".
+func (l *Logger) Named(name string) *Logger {
+	if name == "" {
+		return l
+	}
+	c := *l
+	if l.name != "" {
+		name = l.name + "." + name
+	}
+	c.name = name
+	c.fields = make([]Field, 0, len(l.fields)+1)
+	for _, f := range l.fields {
+		if f.Key != "logger" {
+			c.fields = append(c.fields, f)
+		}
+	}
+	c.fields = append(c.fields, String("logger", name))
+	return &c
+}
+
+func Named(name string) *Logger { return defaultLogger().Named(name) }
+
+// Rule sends the entries it matches to the named sinks. All of the set
+// conditions must hold: level in [MinLevel, *MaxLevel] (a nil MaxLevel
+// means no upper bound), the logger name matching Logger as a path.Match
+// pattern, and Match, if given.
+//
+// An entry goes to the union of the sinks of every rule it matches. Sinks
+// that no rule mentions keep getting everything.
+type Rule struct {
+	MinLevel int
+	MaxLevel *int
+	Logger   string
+	Match    Predicate
+	Sinks    []string
+}
+
+// WithRoutes installs routing rules. The sinks they name must be
+// registered with WithSinkName; unknown names are reported to the error
+// handler.
+func WithRoutes(rules ...Rule) Option {
+	return func(l *Logger) {
+		l.routes = append(l.routes, rules...)
+	}
+}
+
+func (r Rule) predicate() Predicate {
+	var ps []Predicate
+	if r.MinLevel > 0 || r.MaxLevel != nil {
+		maxLevel := int(^uint(0) >> 1)
+		if r.MaxLevel != nil {
+			maxLevel = *r.MaxLevel
+		}
+		ps = append(ps, LevelRange(r.MinLevel, maxLevel))
+	}
+	if r.Logger != "" {
+		pattern := r.Logger
+		ps = append(ps, func(e Entry) bool {
+			f, ok := e.Field("logger")
+			if !ok {
+				return false
+			}
+			matched, _ := path.Match(pattern, f.str)
+			return matched
+		})
+	}
+	if r.Match != nil {
+		ps = append(ps, r.Match)
+	}
+	return And(ps...)
+}
+
+// routeSink folds the rules naming s into its predicate, so routing goes
+// through the same mask as Tee and WithMatch.
+func (l *Logger) routeSink(s *sink) {
+	if s.name == "" {
+		return
+	}
+	var ps []Predicate
+	for _, r := range l.routes {
+		if slices.Contains(r.Sinks, s.name) {
+			ps = append(ps, r.predicate())
+		}
+	}
+	if len(ps) == 0 {
+		return
+	}
+	p := Or(ps...)
+	if s.match != nil {
+		p = And(s.match, p)
+	}
+	WithMatch(p)(s)
+}
+
+// checkRoutes reports rules naming sinks that New wasn't given; AddSink
+// can still supply them later.
+func (l *Logger) checkRoutes() {
+	reported := map[string]bool{}
+	for _, r := range l.routes {
+		for _, name := range r.Sinks {
+			if !reported[name] && !slices.ContainsFunc(l.configured, func(s *sink) bool { return s.name == name }) {
+				reported[name] = true
+				l.onError(fmt.Errorf("speedlog: routing rule names unknown sink %q", name))
+			}
+		}
+	}
+}
+
+// LoadRoutes reads rules from a JSON file:
+//
+//	[{"sinks": ["alerts"], "min": "WARN", "logger": "billing.*",
+//	  "fields": {"region": "eu"}}]
+//
+// "min" and "max" are level names; "fields" match on the rendered value;
+// "when" is an expression for Compile.
+func LoadRoutes(name string) ([]Rule, error) {
+	data, err := os.ReadFile(name)
+	if err != nil {
+		return nil, err
+	}
+	var specs []struct {
+		Sinks  []string          `json:"sinks"`
+		Min    string            `json:"min"`
+		Max    string            `json:"max"`
+		Logger string            `json:"logger"`
+		Fields map[string]string `json:"fields"`
+		When   string            `json:"when"`
+	}
+	if err := json.Unmarshal(data, &specs); err != nil {
+		return nil, fmt.Errorf("speedlog: %s: %w", name, err)
+	}
+	rules := make([]Rule, 0, len(specs))
+	for i, sp := range specs {
+		r := Rule{Logger: sp.Logger, Sinks: sp.Sinks}
+		if sp.Min != "" {
+			if r.MinLevel, err = ParseLevel(sp.Min); err != nil {
+				return nil, fmt.Errorf("speedlog: %s: rule %d: %w", name, i, err)
+			}
+		}
+		if sp.Max != "" {
+			maxLevel, err := ParseLevel(sp.Max)
+			if err != nil {
+				return nil, fmt.Errorf("speedlog: %s: rule %d: %w", name, i, err)
+			}
+			r.MaxLevel = &maxLevel
+		}
+		if _, err := path.Match(r.Logger, ""); err != nil {
+			return nil, fmt.Errorf("speedlog: %s: rule %d: %w", name, i, err)
+		}
+		var ps []Predicate
+		for key, want := range sp.Fields {
+			ps = append(ps, fieldText(key, want))
+		}
+		if sp.When != "" {
+			p, err := Compile(sp.When)
+			if err != nil {
+				return nil, fmt.Errorf("speedlog: %s: rule %d: %w", name, i, err)
+			}
+			ps = append(ps, p)
+		}
+		if len(ps) > 0 {
+			r.Match = And(ps...)
+		}
+		rules = append(rules, r)
+	}
+	return rules, nil
+}
+
+// fieldText matches a field by its unquoted rendering, for values that
+// come from config as strings.
+func fieldText(key, want string) Predicate {
+	return func(e Entry) bool {
+		f, ok := e.Field(key)
+		if !ok {
+			return false
+		}
+		if f.kind == stringKind {
+			return f.str == want
+		}
+		return fmt.Sprint(f.Value()) == want
+	}
+}
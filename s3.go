@@ -0,0 +1,145 @@
+package speedlog
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// S3Config locates a bucket for S3Uploader.
+type S3Config struct {
+	Bucket string
+	Prefix string // prepended to the file's base name to form the key
+	Region string // defaults to us-east-1
+
+	// Endpoint is the service URL for S3-compatible stores (MinIO, GCS's
+	// XML API, ...), addressed path-style. Empty means AWS, addressed as
+	// https://<bucket>.s3.<region>.amazonaws.com.
+	Endpoint string
+
+	AccessKey    string
+	SecretKey    string
+	SessionToken string
+
+	Client *http.Client // defaults to a client with a 10 minute timeout
+}
+
+// S3Uploader returns a WithArchive callback that PUTs each file to S3,
+// signed with AWS Signature Version 4. It is a single PUT, so files are
+// limited to 5 GiB.
+func S3Uploader(cfg S3Config) func(path string) error {
+	if cfg.Region == "" {
+		cfg.Region = "us-east-1"
+	}
+	if cfg.Client == nil {
+		cfg.Client = &http.Client{Timeout: 10 * time.Minute}
+	}
+	return func(path string) error { return s3Put(&cfg, path) }
+}
+
+// GCSUploader uploads to Google Cloud Storage through its S3-compatible XML
+// API, using an HMAC key pair from the bucket's interoperability settings.
+func GCSUploader(bucket, prefix, accessKey, secretKey string) func(path string) error {
+	return S3Uploader(S3Config{
+		Bucket:    bucket,
+		Prefix:    prefix,
+		Region:    "auto",
+		Endpoint:  "https://storage.googleapis.com",
+		AccessKey: accessKey,
+		SecretKey: secretKey,
+	})
+}
+
+func s3Put(cfg *S3Config, path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	h := sha256.New()
+	size, err := io.Copy(h, f)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+	payload := hex.EncodeToString(h.Sum(nil))
+
+	key := cfg.Prefix + filepath.Base(path)
+	var u *url.URL
+	if cfg.Endpoint == "" {
+		u, err = url.Parse("https://" + cfg.Bucket + ".s3." + cfg.Region + ".amazonaws.com/" + s3Escape(key))
+	} else {
+		u, err = url.Parse(strings.TrimSuffix(cfg.Endpoint, "/") + "/" + s3Escape(cfg.Bucket) + "/" + s3Escape(key))
+	}
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequest(http.MethodPut, u.String(), f)
+	if err != nil {
+		return err
+	}
+	req.ContentLength = size
+	now := time.Now().UTC()
+	amzDate := now.Format("20060102T150405Z")
+	req.Header.Set("X-Amz-Content-Sha256", payload)
+	req.Header.Set("X-Amz-Date", amzDate)
+	signed := "host;x-amz-content-sha256;x-amz-date"
+	canonHeaders := "host:" + u.Host + "\nx-amz-content-sha256:" + payload + "\nx-amz-date:" + amzDate + "\n"
+	if cfg.SessionToken != "" {
+		req.Header.Set("X-Amz-Security-Token", cfg.SessionToken)
+		signed += ";x-amz-security-token"
+		canonHeaders += "x-amz-security-token:" + cfg.SessionToken + "\n"
+	}
+	canon := "PUT\n" + u.EscapedPath() + "\n\n" + canonHeaders + "\n" + signed + "\n" + payload
+	scope := now.Format("20060102") + "/" + cfg.Region + "/s3/aws4_request"
+	sum := sha256.Sum256([]byte(canon))
+	toSign := "AWS4-HMAC-SHA256\n" + amzDate + "\n" + scope + "\n" + hex.EncodeToString(sum[:])
+	k := hmacSHA256([]byte("AWS4"+cfg.SecretKey), now.Format("20060102"))
+	k = hmacSHA256(k, cfg.Region)
+	k = hmacSHA256(k, "s3")
+	k = hmacSHA256(k, "aws4_request")
+	req.Header.Set("Authorization", "AWS4-HMAC-SHA256 Credential="+cfg.AccessKey+"/"+scope+
+		", SignedHeaders="+signed+", Signature="+hex.EncodeToString(hmacSHA256(k, toSign)))
+
+	resp, err := cfg.Client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("%s: %s", resp.Status, strings.TrimSpace(string(msg)))
+	}
+	return nil
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	m := hmac.New(sha256.New, key)
+	m.Write([]byte(data))
+	return m.Sum(nil)
+}
+
+// s3Escape percent-encodes everything but unreserved characters and '/',
+// as the canonical request requires.
+func s3Escape(s string) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if 'A' <= c && c <= 'Z' || 'a' <= c && c <= 'z' || '0' <= c && c <= '9' || strings.IndexByte("-_.~/", c) >= 0 {
+			b.WriteByte(c)
+			continue
+		}
+		fmt.Fprintf(&b, "%%%02X", c)
+	}
+	return b.String()
+}
@@ -0,0 +1,104 @@
+package speedlog
+
+import (
+	"runtime"
+	"sync"
+	"sync/atomic"
+)
+
+type seqRecord struct {
+	seq uint64
+	rec record
+}
+
+// shards spreads submissions over several channels. A pooled shard index
+// keeps a goroutine on its P's shard most of the time; entries carry a
+// global sequence number and the writer merges shard heads in sequence
+// order, so lines from one goroutine never come out reordered even when
+// it migrates between shards.
+type shards struct {
+	qs    []chan seqRecord
+	heads []seqRecord
+	has   []bool
+	seq   atomic.Uint64
+	rr    atomic.Uint32
+	pick  sync.Pool
+	wake  chan struct{}
+}
+
+func newShards(n, size int) *shards {
+	s := &shards{
+		qs:    make([]chan seqRecord, n),
+		heads: make([]seqRecord, n),
+		has:   make([]bool, n),
+		wake:  make(chan struct{}, 1),
+	}
+	for i := range s.qs {
+		s.qs[i] = make(chan seqRecord, size)
+	}
+	s.pick.New = func() interface{} {
+		i := int(s.rr.Add(1)-1) % n
+		return &i
+	}
+	return s
+}
+
+func WithShards(n int) Option {
+	return func(l *Logger) {
+		if n <= 0 {
+			n = runtime.GOMAXPROCS(0)
+		}
+		l.shardN = n
+	}
+}
+
+func (s *shards) push(rec record, done <-chan struct{}) bool {
+	i := s.pick.Get().(*int)
+	q := s.qs[*i]
+	s.pick.Put(i)
+	select {
+	case q <- seqRecord{seq: s.seq.Add(1), rec: rec}:
+	case <-done:
+		return false
+	}
+	select {
+	case s.wake <- struct{}{}:
+	default:
+	}
+	return true
+}
+
+func (s *shards) take(batch []record, maxN, maxBytes int) []record {
+	n := 0
+	for len(batch) < maxN && n < maxBytes {
+		min := -1
+		for {
+			got := false
+			for i, q := range s.qs {
+				if !s.has[i] {
+					select {
+					case e := <-q:
+						s.heads[i], s.has[i] = e, true
+						got = true
+					default:
+					}
+				}
+				if s.has[i] && (min < 0 || s.heads[i].seq < s.heads[min].seq) {
+					min = i
+				}
+			}
+			// Only trust the minimum after a pass that found nothing new:
+			// anything pushed before it is visible by then.
+			if !got {
+				break
+			}
+		}
+		if min < 0 {
+			break
+		}
+		batch = append(batch, s.heads[min].rec)
+		n += len(s.heads[min].rec.line)
+		s.heads[min], s.has[min] = seqRecord{}, false
+	}
+	return batch
+}
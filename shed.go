@@ -0,0 +1,88 @@
+package speedlog
+
+import (
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+// shedder drops DEBUG, then INFO entries while the queue stays saturated,
+// so WARN and above keep flowing instead of everyone blocking equally.
+type shedder struct {
+	after   time.Duration
+	level   atomic.Int32 // highest level being shed, -1 when off
+	since   atomic.Int64 // start of the current saturation
+	started atomic.Int64 // start of the current shedding
+	counts  [WARN]atomic.Uint64
+}
+
+// WithLoadShedding starts dropping DEBUG entries once the queue has been
+// at least 90% full for after, and INFO too after twice that. WARN and
+// above are never shed. Once the queue is below half full again, shedding
+// stops and a WARN entry reports how many entries were shed.
+func WithLoadShedding(after time.Duration) Option {
+	return func(l *Logger) {
+		if after <= 0 {
+			l.invalid("WithLoadShedding(%v): duration must be positive", after)
+			return
+		}
+		l.shed = &shedder{after: after}
+		l.shed.level.Store(-1)
+	}
+}
+
+// shedding reports whether an entry at level should be dropped, and moves
+// the shedding level with the queue fill on the way.
+func (l *Logger) shedding(level int) bool {
+	sh := l.shed
+	c := l.queueCap()
+	if c == 0 {
+		return false
+	}
+	n := l.queueLen()
+	cur := sh.level.Load()
+	switch {
+	case n*10 >= c*9:
+		now := time.Now().UnixNano()
+		sh.since.CompareAndSwap(0, now)
+		want := int32(min(time.Duration(now-sh.since.Load())/sh.after, WARN)) - 1
+		if want > cur && sh.level.CompareAndSwap(cur, want) {
+			sh.started.CompareAndSwap(0, now)
+			cur = want
+		}
+	case sh.since.Load() != 0:
+		sh.since.Store(0)
+		fallthrough
+	default:
+		if cur >= 0 && n*2 < c && sh.level.CompareAndSwap(cur, -1) {
+			go l.shedSummary()
+			cur = -1
+		}
+	}
+	if cur < 0 || int32(level) > cur {
+		return false
+	}
+	// Custom levels below DEBUG go with DEBUG.
+	sh.counts[max(level, DEBUG)].Add(1)
+	return true
+}
+
+// shedSummary logs the counts shed since the last summary, if any.
+func (l *Logger) shedSummary() {
+	sh := l.shed
+	fields := make([]Field, 0, WARN+1)
+	total := uint64(0)
+	for i := range sh.counts {
+		if n := sh.counts[i].Swap(0); n > 0 {
+			fields = append(fields, Uint64("shed_"+strings.ToLower(levelNames[i]), n))
+			total += n
+		}
+	}
+	if total == 0 {
+		return
+	}
+	if started := sh.started.Swap(0); started != 0 {
+		fields = append(fields, Duration("duration", time.Since(time.Unix(0, started)).Round(time.Millisecond)))
+	}
+	l.root.log(WARN, "load shedding stopped", fields...)
+}
@@ -0,0 +1,163 @@
+package speedlog
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"os"
+)
+
+const (
+	sigPrefix = " sig="
+	// restartPrefix marks the first line of a chain, so Verify knows where
+	// another logger instance started appending to the same file.
+	restartPrefix = " sig0="
+	// maxSignedTail bounds how far back resume looks for the last line.
+	maxSignedTail = 64 << 10
+)
+
+type signer struct {
+	h    hash.Hash
+	prev []byte
+	sum  []byte
+}
+
+func newSigner(key []byte) *signer {
+	s := &signer{}
+	if len(key) > 0 {
+		s.h = hmac.New(sha256.New, key)
+	} else {
+		s.h = sha256.New()
+	}
+	return s
+}
+
+func (s *signer) digest(prev, content []byte) []byte {
+	s.h.Reset()
+	_, _ = s.h.Write(prev)
+	_, _ = s.h.Write(content)
+	s.sum = s.h.Sum(s.sum[:0])
+	return s.sum
+}
+
+// resume continues the chain of the signed file w appends to, so a new
+// logger doesn't open a fresh one there: a fresh chain in mid-file would
+// let whole runs before it be removed unnoticed. Writers whose end can't
+// be read, and files ending in an unsigned or torn line, get a fresh one.
+func (s *signer) resume(w io.Writer) {
+	if _, sig, _, err := splitSig(lastLine(w)); err == nil {
+		s.prev = sig
+	}
+}
+
+// lastLine returns the last complete line of the regular file behind w,
+// or nil.
+func lastLine(w io.Writer) []byte {
+	var name string
+	switch w := w.(type) {
+	case *os.File:
+		name = w.Name()
+	case *FileWriter:
+		w.mu.Lock()
+		name = w.path
+		w.mu.Unlock()
+	default:
+		return nil
+	}
+	f, err := os.Open(name)
+	if err != nil {
+		return nil
+	}
+	defer f.Close()
+	fi, err := f.Stat()
+	if err != nil || !fi.Mode().IsRegular() || fi.Size() == 0 {
+		return nil
+	}
+	buf := make([]byte, min(fi.Size(), maxSignedTail))
+	if _, err := f.ReadAt(buf, fi.Size()-int64(len(buf))); err != nil || buf[len(buf)-1] != '\n' {
+		return nil
+	}
+	buf = buf[:len(buf)-1]
+	i := bytes.LastIndexByte(buf, '\n')
+	if i < 0 && int64(len(buf)+1) < fi.Size() {
+		return nil
+	}
+	return buf[i+1:]
+}
+
+// splitSig splits a line, without its newline, into the signed content
+// and its signature.
+func splitSig(line []byte) (content, sig []byte, restart bool, err error) {
+	i, prefix := bytes.LastIndex(line, []byte(sigPrefix)), sigPrefix
+	if j := bytes.LastIndex(line, []byte(restartPrefix)); j > i {
+		i, prefix = j, restartPrefix
+	}
+	if i < 0 {
+		return nil, nil, false, errors.New("missing signature")
+	}
+	sig, err = hex.DecodeString(string(line[i+len(prefix):]))
+	if err != nil {
+		return nil, nil, false, errors.New("malformed signature")
+	}
+	return line[:i], sig, prefix == restartPrefix, nil
+}
+
+func (s *signer) sign(line []byte) []byte {
+	content := bytes.TrimSuffix(line, []byte{'\n'})
+	sum := s.digest(s.prev, content)
+	if s.prev == nil {
+		line = append(content, restartPrefix...)
+	} else {
+		line = append(content, sigPrefix...)
+	}
+	line = hex.AppendEncode(line, sum)
+	line = append(line, '\n')
+	s.prev = append(s.prev[:0], sum...)
+	return line
+}
+
+func WithSigning(key []byte) Option {
+	return func(l *Logger) {
+		l.signing = true
+		l.signKey = key
+	}
+}
+
+var ErrTampered = errors.New("speedlog: log signature mismatch")
+
+func Verify(r io.Reader, key []byte) error {
+	s := newSigner(key)
+	br := bufio.NewReader(r)
+	var prev []byte
+	for n := 1; ; n++ {
+		line, err := br.ReadBytes('\n')
+		if len(line) == 0 && err == io.EOF {
+			return nil
+		}
+		if err != nil && err != io.EOF {
+			return err
+		}
+		content, want, restart, sigErr := splitSig(bytes.TrimSuffix(line, []byte{'\n'}))
+		if sigErr != nil {
+			return fmt.Errorf("line %d: %w: %v", n, ErrTampered, sigErr)
+		}
+		// Only the first line and marked restarts start a new chain; a
+		// chain accepted anywhere else would hide deleted lines.
+		if restart {
+			prev = nil
+		}
+		if !hmac.Equal(s.digest(prev, content), want) {
+			return fmt.Errorf("line %d: %w", n, ErrTampered)
+		}
+		prev = append(prev[:0], want...)
+		if err == io.EOF {
+			return nil
+		}
+	}
+}
@@ -0,0 +1,43 @@
+package speedlog
+
+import (
+	"os"
+	"os/signal"
+	"sync"
+)
+
+// levelSignals makes more/less verbose signals step the level; whatever
+// is in effect afterwards is logged regardless of the level.
+func (l *Logger) levelSignals(more, less os.Signal) (stop func()) {
+	ch := make(chan os.Signal, 1)
+	signal.Notify(ch, more, less)
+	quit := make(chan struct{})
+	go func() {
+		for {
+			select {
+			case sig := <-ch:
+				level := l.GetLevel()
+				if sig == more {
+					level = max(level-1, DEBUG)
+				} else {
+					level = min(level+1, FATAL)
+				}
+				l.SetLevel(level)
+				e := l.entry(level, "log level changed", []Field{String("level", LevelName(level))})
+				l.emit(&e)
+			case <-quit:
+				return
+			case <-l.done:
+				signal.Stop(ch)
+				return
+			}
+		}
+	}()
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			signal.Stop(ch)
+			close(quit)
+		})
+	}
+}
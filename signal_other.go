@@ -0,0 +1,9 @@
+//go:build !unix
+
+package speedlog
+
+// HandleLevelSignals is a no-op where there is no SIGUSR1/SIGUSR2
+// (Windows, Plan 9, js/wasm, wasip1).
+func (l *Logger) HandleLevelSignals() (stop func()) {
+	return func() {}
+}
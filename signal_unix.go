@@ -0,0 +1,11 @@
+//go:build unix
+
+package speedlog
+
+import "syscall"
+
+// HandleLevelSignals makes SIGUSR1 lower the level one step (more verbose)
+// and SIGUSR2 raise it. Call stop to restore default signal handling.
+func (l *Logger) HandleLevelSignals() (stop func()) {
+	return l.levelSignals(syscall.SIGUSR1, syscall.SIGUSR2)
+}
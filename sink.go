@@ -0,0 +1,600 @@
+package speedlog
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+type Overflow int
+
+const (
+	Block Overflow = iota
+	DropNewest
+	DropOldest
+)
+
+type SinkOption func(*sink)
+
+type sink struct {
+	id         int
+	w          io.Writer
+	out        io.Writer
+	bw         *bufio.Writer
+	queue      int
+	bufSize    int
+	overflow   Overflow
+	timeout    time.Duration
+	conn       net.Conn // set when timeout is enforced with write deadlines
+	ejectAfter uint64
+	ttl        time.Duration
+	onError    func(error)
+	ch         chan record
+	exited     chan struct{}
+	syncReq    chan chan struct{}
+	flushReq   chan struct{}
+	pool       bufPool
+	written    atomic.Uint64
+	dropped    atomic.Uint64
+	errors     atomic.Uint64
+	timeouts   atomic.Uint64
+	expired    atomic.Uint64
+	violations atomic.Uint64
+	ejected    atomic.Bool
+	lastErr    atomic.Pointer[SinkError]
+	failing    atomic.Pointer[SinkError]
+	vec        [][]byte
+	lines      [][]byte
+	plain      bool // can't render colors, so they're stripped
+	plainBuf   []byte
+	filtered   bool
+	leveled    bool
+	minLevel   int
+	maxLevel   int
+	match      Predicate
+	signer     *signer
+	signed     []byte
+	fsync      FsyncPolicy
+	unsynced   int
+	dirty      bool
+	wal        *wal
+	name       string
+
+	flushPol    FlushPolicy
+	customFlush bool
+	sinceFlush  int
+	lastFlush   time.Time
+
+	configErrs []error
+}
+
+var ErrSinkEjected = errors.New("speedlog: sink ejected after repeated write timeouts")
+
+type SinkError struct {
+	Sink int
+	Err  error
+}
+
+func (e *SinkError) Error() string { return fmt.Sprintf("sink %d: %v", e.Sink, e.Err) }
+
+func (e *SinkError) Unwrap() error { return e.Err }
+
+type SinkStats struct {
+	ID       SinkID
+	Name     string
+	Written  uint64
+	Dropped  uint64
+	Errors   uint64
+	Timeouts uint64
+	// Expired counts entries dropped for being older than WithEntryTTL.
+	Expired uint64
+	Ejected bool
+}
+
+func (s *sink) invalid(format string, a ...any) {
+	s.configErrs = append(s.configErrs, optionError(format, a...))
+}
+
+func WithQueue(size int) SinkOption {
+	return func(s *sink) {
+		if size <= 0 {
+			s.invalid("WithQueue(%d): size must be positive", size)
+			return
+		}
+		s.queue = size
+	}
+}
+
+func WithBufferSize(n int) SinkOption {
+	return func(s *sink) {
+		if n <= 0 {
+			s.invalid("WithBufferSize(%d): size must be positive", n)
+			return
+		}
+		s.bufSize = n
+	}
+}
+
+func WithOverflow(policy Overflow) SinkOption {
+	return func(s *sink) {
+		s.overflow = policy
+	}
+}
+
+// WithWriteTimeout bounds every write to the sink. A net.Conn gets a write
+// deadline d ahead of each batch and flush, so a stalled peer fails the
+// write itself; other writers run writes on a helper goroutine that is
+// abandoned after d (see timeoutWriter).
+func WithWriteTimeout(d time.Duration) SinkOption {
+	return func(s *sink) {
+		if d <= 0 {
+			s.invalid("WithWriteTimeout(%v): timeout must be positive", d)
+			return
+		}
+		s.timeout = d
+	}
+}
+
+func WithEjectAfter(timeouts int) SinkOption {
+	return func(s *sink) {
+		if timeouts <= 0 {
+			s.invalid("WithEjectAfter(%d): count must be positive", timeouts)
+			return
+		}
+		s.ejectAfter = uint64(timeouts)
+	}
+}
+
+// WithLevels restricts a sink to entries whose level lies in [min, max].
+func WithLevels(min, max int) SinkOption {
+	return func(s *sink) {
+		if min > max {
+			s.invalid("WithLevels(%d, %d): min is above max", min, max)
+		}
+		s.filtered, s.leveled = true, true
+		s.minLevel = min
+		s.maxLevel = max
+	}
+}
+
+func WithSink(w io.Writer, opts ...SinkOption) Option {
+	return func(l *Logger) {
+		if w == nil {
+			l.invalid("sink writer is nil")
+			return
+		}
+		s := &sink{w: w}
+		for _, opt := range opts {
+			opt(s)
+		}
+		l.configErrs = append(l.configErrs, s.configErrs...)
+		l.configured = append(l.configured, s)
+	}
+}
+
+func (s *sink) start(wg *sync.WaitGroup, size int, interval time.Duration) {
+	s.out = s.w
+	if s.timeout > 0 {
+		if c, ok := s.w.(net.Conn); ok {
+			s.conn = c
+		} else {
+			s.out = newTimeoutWriter(s.w, s.timeout)
+		}
+	}
+	if s.bufSize > 0 {
+		size = s.bufSize
+	}
+	size = max(size, s.flushPol.bytes)
+	if s.flushPol.interval > 0 {
+		interval = s.flushPol.interval
+	}
+	s.lastFlush = time.Now()
+	s.bw = bufio.NewWriterSize(s.out, size)
+	if s.wal != nil {
+		if err := s.wal.open(); err != nil {
+			s.report(err)
+			s.wal = nil
+		}
+	}
+	if s.queue == 0 {
+		return
+	}
+	s.ch = make(chan record, s.queue)
+	s.exited = make(chan struct{})
+	s.syncReq = make(chan chan struct{})
+	s.flushReq = make(chan struct{}, 1)
+	wg.Add(1)
+	go s.run(wg, interval)
+}
+
+func (s *sink) async() bool { return s.ch != nil }
+
+func (s *sink) accepts(e *Entry) bool {
+	if s.leveled && (e.Level < s.minLevel || e.Level > s.maxLevel) {
+		return false
+	}
+	return s.match == nil || s.match(*e)
+}
+
+func (s *sink) wants(rec record) bool {
+	return rec.mask == 0 || s.id >= 64 || rec.mask&(1<<s.id) != 0
+}
+
+func (s *sink) write(batch []record, size int) {
+	if s.async() {
+		for _, rec := range batch {
+			if s.wants(rec) {
+				s.enqueue(rec)
+			}
+		}
+		return
+	}
+	s.writeRecords(batch, true)
+}
+
+func (s *sink) writeRecords(batch []record, filter bool) {
+	s.lines = s.lines[:0]
+	s.plainBuf = s.plainBuf[:0]
+	n, top := 0, DEBUG
+	var cutoff int64
+	if s.ttl > 0 {
+		cutoff = time.Now().Add(-s.ttl).UnixNano()
+	}
+	for _, rec := range batch {
+		if (!filter || s.wants(rec)) && !s.stale(rec, cutoff) {
+			line := rec.line
+			if s.plain {
+				start := len(s.plainBuf)
+				s.plainBuf = appendPlain(s.plainBuf, line)
+				line = s.plainBuf[start:]
+			}
+			s.lines = append(s.lines, line)
+			n += len(line)
+			top = max(top, rec.level)
+		}
+	}
+	if len(s.lines) > 0 {
+		s.writeLines(s.lines, n)
+		s.durable(len(s.lines), top)
+		if s.customFlush && s.flushDue(len(s.lines), top) {
+			s.flush()
+		}
+	}
+	clear(s.lines)
+}
+
+// appendPlain appends line without the SGR sequences a colored encoder
+// puts around level names.
+func appendPlain(dst, line []byte) []byte {
+	for {
+		i := bytes.Index(line, []byte("\x1b["))
+		if i < 0 {
+			return append(dst, line...)
+		}
+		j := i + 2
+		for j < len(line) && (line[j] >= '0' && line[j] <= '9' || line[j] == ';') {
+			j++
+		}
+		if j == len(line) || line[j] != 'm' {
+			dst = append(dst, line[:j]...)
+		} else {
+			dst = append(dst, line[:i]...)
+			j++
+		}
+		line = line[j:]
+	}
+}
+
+func (s *sink) writeLines(batch [][]byte, size int) {
+	if s.ejected.Load() {
+		s.dropped.Add(uint64(len(batch)))
+		return
+	}
+	s.arm()
+	if s.wal != nil {
+		s.writeWAL(batch)
+		return
+	}
+	// Sockets get the whole batch in one writev once it no longer fits
+	// the buffer; everything else coalesces in bufio.
+	if _, ok := s.out.(net.Conn); ok && s.signer == nil && size > s.bw.Available() {
+		if err := s.bw.Flush(); err != nil {
+			s.fail(err)
+		}
+		s.vec = append(s.vec[:0], batch...)
+		vec := net.Buffers(s.vec)
+		if _, err := vec.WriteTo(s.out); err != nil {
+			s.fail(err)
+		} else {
+			s.written.Add(uint64(len(batch)))
+			s.recovered()
+		}
+		clear(s.vec)
+		return
+	}
+	// Only bytes that reached the writer clear a failure; a line that just
+	// sits in the buffer proves nothing.
+	ok, delivered := true, false
+	for _, line := range batch {
+		if s.signer != nil {
+			s.signed = s.signer.sign(append(s.signed[:0], line...))
+			line = s.signed
+		}
+		// Never let bufio split a line across two writes: flush first, and
+		// lines bigger than the buffer go straight through in one piece.
+		if len(line) > s.bw.Available() && s.bw.Buffered() > 0 {
+			if err := s.bw.Flush(); err != nil {
+				ok = false
+				s.fail(err)
+				if s.ejected.Load() {
+					return
+				}
+			} else {
+				delivered = true
+			}
+		}
+		before := s.bw.Buffered()
+		if _, err := s.bw.Write(line); err != nil {
+			ok = false
+			s.fail(err)
+			if s.ejected.Load() {
+				return
+			}
+			continue
+		}
+		s.written.Add(1)
+		delivered = delivered || s.bw.Buffered() < before+len(line)
+	}
+	if ok && delivered {
+		s.recovered()
+	}
+}
+
+// arm moves the write deadline of a conn sink d past now.
+func (s *sink) arm() {
+	if s.conn != nil {
+		_ = s.conn.SetWriteDeadline(time.Now().Add(s.timeout))
+	}
+}
+
+func (s *sink) recovered() {
+	if s.failing.Load() != nil {
+		s.failing.Store(nil)
+	}
+}
+
+func (s *sink) flush() {
+	if s.ejected.Load() {
+		return
+	}
+	if s.customFlush {
+		s.sinceFlush, s.lastFlush = 0, time.Now()
+	}
+	s.arm()
+	buffered := s.bw.Buffered()
+	if err := s.bw.Flush(); err != nil {
+		s.fail(err)
+		return
+	}
+	if f, ok := s.out.(flusher); ok {
+		if err := f.Flush(); err != nil {
+			s.fail(err)
+			return
+		}
+	}
+	if buffered > 0 {
+		s.recovered()
+	}
+	s.afterFlush()
+}
+
+// tick is the periodic flush, which also fsyncs under FsyncOnFlush.
+func (s *sink) tick() {
+	if s.wal != nil && s.wal.replay && !s.ejected.Load() {
+		s.resend()
+	}
+	s.flush()
+	if s.dirty && s.fsync.mode == fsyncOnFlush {
+		s.sync()
+	}
+}
+
+// fail resets the buffer (bufio errors are sticky) and reports the error.
+// Writes refused because an earlier one is still hanging are only counted,
+// but both kinds count toward ejection.
+func (s *sink) fail(err error) {
+	if errors.Is(err, os.ErrDeadlineExceeded) {
+		err = fmt.Errorf("%w: %w", ErrWriteTimeout, err)
+	}
+	s.bw.Reset(s.out)
+	if s.wal != nil {
+		s.wal.replay = true
+	}
+	switch {
+	case errors.Is(err, ErrSinkStalled):
+		s.dropped.Add(1)
+	case errors.Is(err, ErrWriteTimeout):
+		s.errors.Add(1)
+		s.timeouts.Add(1)
+		s.report(err)
+	default:
+		s.errors.Add(1)
+		s.report(err)
+		return
+	}
+	if s.violations.Add(1) == s.ejectAfter {
+		s.ejected.Store(true)
+		s.report(ErrSinkEjected)
+	}
+}
+
+// report hands err to the error handler and keeps the first one since the
+// last Sync/Close for them to return.
+func (s *sink) report(err error) {
+	e := &SinkError{Sink: s.id, Err: err}
+	s.lastErr.CompareAndSwap(nil, e)
+	s.failing.Store(e)
+	if s.onError != nil {
+		s.onError(e)
+	}
+}
+
+func (s *sink) takeErr() error {
+	if e := s.lastErr.Swap(nil); e != nil {
+		return e
+	}
+	return nil
+}
+
+func (s *sink) stats() SinkStats {
+	return SinkStats{
+		ID:       SinkID(s.id),
+		Name:     s.name,
+		Written:  s.written.Load(),
+		Dropped:  s.dropped.Load(),
+		Errors:   s.errors.Load(),
+		Timeouts: s.timeouts.Load(),
+		Expired:  s.expired.Load(),
+		Ejected:  s.ejected.Load(),
+	}
+}
+
+func (s *sink) requestFlush() {
+	select {
+	case s.flushReq <- struct{}{}:
+	default:
+	}
+}
+
+func (s *sink) enqueue(rec record) {
+	cp := rec
+	cp.line = append(s.pool.get(len(rec.line)), rec.line...)
+	cp.chunk = nil
+	switch s.overflow {
+	case DropNewest:
+		select {
+		case s.ch <- cp:
+		default:
+			s.pool.put(cp.line)
+			s.dropped.Add(1)
+		}
+	case DropOldest:
+		for {
+			select {
+			case s.ch <- cp:
+				return
+			default:
+			}
+			select {
+			case old := <-s.ch:
+				s.pool.put(old.line)
+				s.dropped.Add(1)
+			default:
+			}
+		}
+	default:
+		s.ch <- cp
+	}
+}
+
+func (s *sink) writeQueued(batch []record) {
+	s.writeRecords(batch, false)
+	for _, rec := range batch {
+		s.pool.put(rec.line)
+	}
+	clear(batch)
+}
+
+// drainQueued writes up to n queued records, fewer if someone else took
+// some (DropOldest evicts from the producer side).
+func (s *sink) drainQueued(batch []record, n int) []record {
+	for n > 0 {
+		batch = batch[:0]
+		for ; n > 0 && len(batch) < cap(batch); n-- {
+			select {
+			case rec, ok := <-s.ch:
+				if !ok {
+					n = 0
+					continue
+				}
+				batch = append(batch, rec)
+			default:
+				n = 0
+			}
+		}
+		s.writeQueued(batch)
+	}
+	return batch
+}
+
+// syncQueued waits until each queued sink has written and flushed what
+// it had been handed; sinks shutting down are skipped.
+func syncQueued(sinks []*sink) {
+	acks := make([]chan struct{}, len(sinks))
+	for i, s := range sinks {
+		if !s.async() {
+			continue
+		}
+		ack := make(chan struct{})
+		select {
+		case s.syncReq <- ack:
+			acks[i] = ack
+		case <-s.exited:
+		}
+	}
+	for i, ack := range acks {
+		if ack != nil {
+			select {
+			case <-ack:
+			case <-sinks[i].exited:
+			}
+		}
+	}
+}
+
+func (s *sink) run(wg *sync.WaitGroup, interval time.Duration) {
+	defer wg.Done()
+	defer close(s.exited)
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	batch := make([]record, 0, 64)
+	for {
+		select {
+		case rec, ok := <-s.ch:
+			if !ok {
+				s.finish()
+				return
+			}
+			batch = append(batch[:0], rec)
+		drain:
+			for len(batch) < cap(batch) {
+				select {
+				case rec, ok := <-s.ch:
+					if !ok {
+						break drain
+					}
+					batch = append(batch, rec)
+				default:
+					break drain
+				}
+			}
+			s.writeQueued(batch)
+		case <-ticker.C:
+			s.periodic()
+		case <-s.flushReq:
+			s.tick()
+		case ack := <-s.syncReq:
+			// Everything queued before the request is ahead of it.
+			batch = s.drainQueued(batch, len(s.ch))
+			s.tick()
+			close(ack)
+		}
+	}
+}
@@ -0,0 +1,145 @@
+package speedlog
+
+import (
+	"errors"
+	"io"
+	"os"
+	"slices"
+)
+
+// SinkID identifies a sink added with AddSink, and is the Sink number in
+// its SinkErrors.
+type SinkID int
+
+var ErrNoSink = errors.New("speedlog: no such sink")
+
+// sinkSet is the published list of sinks. AddSink and RemoveSink swap in
+// a new one, so the logging path reads it without locking.
+type sinkSet struct {
+	list   []*sink
+	routed bool // some sink with an id below 64 filters
+}
+
+func newSinkSet(list []*sink) *sinkSet {
+	set := &sinkSet{list: list}
+	for _, s := range list {
+		if s.filtered && s.id < 64 {
+			set.routed = true
+		}
+	}
+	return set
+}
+
+func (l *Logger) sinkList() []*sink {
+	if set := l.set.Load(); set != nil {
+		return set.list
+	}
+	return nil
+}
+
+func (set *sinkSet) maskFor(e *Entry) uint64 {
+	var mask uint64
+	for _, s := range set.list {
+		if s.id < 64 && s.accepts(e) {
+			mask |= 1 << s.id
+		}
+	}
+	return mask
+}
+
+// initSink wires s to the logger and starts it.
+func (l *Logger) initSink(s *sink, id int) {
+	s.id = id
+	s.onError = l.onError
+	s.ttl = l.ttl
+	s.plain = !enableColor(s.w)
+	if l.signing {
+		s.signer = newSigner(l.signKey)
+		s.signer.resume(s.w)
+	}
+	l.routeSink(s)
+	s.start(&l.sinkWG, l.bufSize, l.flushMax)
+}
+
+// AddSink attaches a sink to a running logger, taking the same options as
+// WithSink. Entries logged while it is being added may or may not reach
+// it. It takes the lowest free id, so routing masks keep working as long
+// as fewer than 64 sinks are attached at once.
+func (l *Logger) AddSink(w io.Writer, opts ...SinkOption) (SinkID, error) {
+	if w == nil {
+		return 0, optionError("sink writer is nil")
+	}
+	s := &sink{w: w}
+	for _, opt := range opts {
+		opt(s)
+	}
+	if len(s.configErrs) > 0 {
+		return 0, errors.Join(s.configErrs...)
+	}
+	l.sinkMu.Lock()
+	defer l.sinkMu.Unlock()
+	if l.sinksClosed {
+		return 0, ErrClosed
+	}
+	cur := l.sinkList()
+	id := 0
+	for slices.ContainsFunc(cur, func(s *sink) bool { return s.id == id }) {
+		id++
+	}
+	l.initSink(s, id)
+	if s.filtered && id < 64 {
+		// Entries already queued with a zero mask would reach s whatever
+		// its filter says: make producers compute masks, then drain.
+		l.set.Store(&sinkSet{list: cur, routed: true})
+		l.barrier()
+	}
+	l.set.Store(newSinkSet(append(cur[:len(cur):len(cur)], s)))
+	return SinkID(id), nil
+}
+
+// RemoveSink detaches a sink, whether added by an option or AddSink: it
+// waits until the writer and any inline write are done with it, flushes
+// it, and closes its writer like Close would. It returns the sink's
+// pending errors and any from closing it.
+func (l *Logger) RemoveSink(id SinkID) error {
+	l.sinkMu.Lock()
+	defer l.sinkMu.Unlock()
+	if l.sinksClosed {
+		return ErrClosed
+	}
+	cur := l.sinkList()
+	i := slices.IndexFunc(cur, func(s *sink) bool { return s.id == int(id) })
+	if i < 0 {
+		return ErrNoSink
+	}
+	s := cur[i]
+	l.set.Store(newSinkSet(slices.Delete(slices.Clone(cur), i, i+1)))
+	// The writer picks up the set per batch and the inline path under
+	// inlineMu; once both have gone round, nobody holds s any more.
+	l.barrier()
+	l.inlineMu.Lock()
+	if s.async() {
+		close(s.ch)
+		<-s.exited
+	}
+	l.inlineMu.Unlock()
+	return errors.Join(s.takeErr(), s.close())
+}
+
+// close is the last flush and the release of everything s owns.
+func (s *sink) close() error {
+	s.finish()
+	s.closeWAL()
+	if t, ok := s.out.(*timeoutWriter); ok {
+		_ = t.Close()
+	}
+	if s.w == os.Stdout || s.w == os.Stderr {
+		return nil
+	}
+	if c, ok := s.w.(io.Closer); ok {
+		if err := c.Close(); err != nil {
+			return &SinkError{Sink: s.id, Err: err}
+		}
+	}
+	return nil
+}
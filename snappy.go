@@ -0,0 +1,316 @@
+package speedlog
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"hash/crc32"
+	"io"
+)
+
+// Snappy returns the snappy codec, in the framing format snappy tools
+// read as .sz files. It trades ratio for speed and has no levels.
+func Snappy() Codec {
+	return &stdCodec{
+		name: "snappy", ext: ".sz",
+		writer: func(w io.Writer, _ int) (io.WriteCloser, error) { return newSnappyWriter(w), nil },
+		reader: func(r io.Reader) (io.ReadCloser, error) { return newSnappyReader(r), nil },
+	}
+}
+
+const (
+	snappyMaxChunk   = 65536
+	snappyCompressed = 0x00
+	snappyRaw        = 0x01
+	snappyStreamID   = 0xff
+	snappyMagic      = "sNaPpY"
+)
+
+var (
+	errSnappyCorrupt = errors.New("speedlog: corrupt snappy stream")
+	snappyCRCTable   = crc32.MakeTable(crc32.Castagnoli)
+)
+
+// snappyCRC is the masked CRC-32C every data chunk carries.
+func snappyCRC(p []byte) uint32 {
+	c := crc32.Checksum(p, snappyCRCTable)
+	return (c>>15 | c<<17) + 0xa282ead8
+}
+
+type snappyWriter struct {
+	w       io.Writer
+	buf     []byte
+	out     []byte
+	table   [1 << 14]uint16
+	started bool
+	err     error
+}
+
+func newSnappyWriter(w io.Writer) *snappyWriter {
+	return &snappyWriter{w: w, buf: make([]byte, 0, snappyMaxChunk)}
+}
+
+func (sw *snappyWriter) Write(p []byte) (int, error) {
+	n := 0
+	for len(p) > 0 && sw.err == nil {
+		k := min(len(p), snappyMaxChunk-len(sw.buf))
+		sw.buf = append(sw.buf, p[:k]...)
+		p, n = p[k:], n+k
+		if len(sw.buf) == snappyMaxChunk {
+			sw.chunk()
+		}
+	}
+	return n, sw.err
+}
+
+// Flush writes the buffered data as a chunk of its own.
+func (sw *snappyWriter) Flush() error {
+	if len(sw.buf) > 0 {
+		sw.chunk()
+	}
+	return sw.err
+}
+
+// Close flushes; an empty stream still gets its identifier chunk.
+func (sw *snappyWriter) Close() error {
+	if err := sw.Flush(); err != nil || sw.started {
+		return err
+	}
+	sw.out = snappyHeader(sw.out[:0])
+	sw.started = true
+	_, sw.err = sw.w.Write(sw.out)
+	return sw.err
+}
+
+func snappyHeader(dst []byte) []byte {
+	dst = append(dst, snappyStreamID, byte(len(snappyMagic)), 0, 0)
+	return append(dst, snappyMagic...)
+}
+
+func (sw *snappyWriter) chunk() {
+	out := sw.out[:0]
+	if !sw.started {
+		out = snappyHeader(out)
+		sw.started = true
+	}
+	start := len(out)
+	out = append(out, snappyCompressed, 0, 0, 0)
+	out = binary.LittleEndian.AppendUint32(out, snappyCRC(sw.buf))
+	clear(sw.table[:])
+	out = snappyEncodeBlock(out, sw.buf, &sw.table)
+	// Incompressible data goes out as it is.
+	if len(out)-start-8 >= len(sw.buf)-len(sw.buf)/8 {
+		out = append(out[:start+8], sw.buf...)
+		out[start] = snappyRaw
+	}
+	n := len(out) - start - 4
+	out[start+1], out[start+2], out[start+3] = byte(n), byte(n>>8), byte(n>>16)
+	sw.out = out
+	sw.buf = sw.buf[:0]
+	_, sw.err = sw.w.Write(out)
+}
+
+func snappyHash(u uint32) uint32 { return (u * 0x1e35a7bd) >> 18 }
+
+// snappyEncodeBlock appends src, at most snappyMaxChunk bytes, in the
+// snappy block format: greedy matches found through a hash of the next
+// four bytes, skipping ahead faster the longer nothing matches.
+func snappyEncodeBlock(dst, src []byte, table *[1 << 14]uint16) []byte {
+	dst = binary.AppendUvarint(dst, uint64(len(src)))
+	lit, s := 0, 0
+	for s+4 <= len(src) {
+		cur := binary.LittleEndian.Uint32(src[s:])
+		h := snappyHash(cur)
+		c := int(table[h])
+		table[h] = uint16(s)
+		if c >= s || binary.LittleEndian.Uint32(src[c:]) != cur {
+			s += 1 + (s-lit)>>5
+			continue
+		}
+		n := 4
+		for s+n < len(src) && src[c+n] == src[s+n] {
+			n++
+		}
+		dst = snappyLiteral(dst, src[lit:s])
+		dst = snappyCopy(dst, s-c, n)
+		s += n
+		lit = s
+	}
+	return snappyLiteral(dst, src[lit:])
+}
+
+func snappyLiteral(dst, lit []byte) []byte {
+	switch n := len(lit) - 1; {
+	case n < 0:
+		return dst
+	case n < 60:
+		dst = append(dst, byte(n)<<2)
+	case n < 1<<8:
+		dst = append(dst, 60<<2, byte(n))
+	default:
+		dst = append(dst, 61<<2, byte(n), byte(n>>8))
+	}
+	return append(dst, lit...)
+}
+
+func snappyCopy(dst []byte, offset, n int) []byte {
+	for n >= 68 {
+		dst = append(dst, 63<<2|2, byte(offset), byte(offset>>8))
+		n -= 64
+	}
+	if n > 64 {
+		dst = append(dst, 59<<2|2, byte(offset), byte(offset>>8))
+		n -= 60
+	}
+	if n >= 12 || offset >= 2048 {
+		return append(dst, byte(n-1)<<2|2, byte(offset), byte(offset>>8))
+	}
+	return append(dst, byte(offset>>8)<<5|byte(n-4)<<2|1, byte(offset))
+}
+
+// snappyDecodeBlock decodes one snappy block into dst[:0].
+func snappyDecodeBlock(dst, src []byte) ([]byte, error) {
+	size, k := binary.Uvarint(src)
+	if k <= 0 || size > snappyMaxChunk {
+		return nil, errSnappyCorrupt
+	}
+	out := dst[:0]
+	for i := k; i < len(src); {
+		tag := src[i]
+		var n, offset int
+		switch tag & 3 {
+		case 0:
+			n = int(tag >> 2)
+			if n >= 60 {
+				m := n - 59
+				if i+1+m > len(src) {
+					return nil, errSnappyCorrupt
+				}
+				n = 0
+				for j := m - 1; j >= 0; j-- {
+					n = n<<8 | int(src[i+1+j])
+				}
+				i += m
+			}
+			n++
+			i++
+			if n > len(src)-i || len(out)+n > int(size) {
+				return nil, errSnappyCorrupt
+			}
+			out = append(out, src[i:i+n]...)
+			i += n
+			continue
+		case 1:
+			if i+2 > len(src) {
+				return nil, errSnappyCorrupt
+			}
+			n = 4 + int(tag>>2&7)
+			offset = int(tag&0xe0)<<3 | int(src[i+1])
+			i += 2
+		case 2:
+			if i+3 > len(src) {
+				return nil, errSnappyCorrupt
+			}
+			n = 1 + int(tag>>2)
+			offset = int(binary.LittleEndian.Uint16(src[i+1:]))
+			i += 3
+		case 3:
+			if i+5 > len(src) {
+				return nil, errSnappyCorrupt
+			}
+			n = 1 + int(tag>>2)
+			offset = int(binary.LittleEndian.Uint32(src[i+1:]))
+			i += 5
+		}
+		if offset <= 0 || offset > len(out) || len(out)+n > int(size) {
+			return nil, errSnappyCorrupt
+		}
+		for from := len(out) - offset; n > 0; n-- {
+			out = append(out, out[from])
+			from++
+		}
+	}
+	if len(out) != int(size) {
+		return nil, errSnappyCorrupt
+	}
+	return out, nil
+}
+
+type snappyReader struct {
+	r       *bufio.Reader
+	chunk   []byte
+	dec     []byte
+	out     []byte
+	pos     int
+	started bool
+	err     error
+}
+
+func newSnappyReader(r io.Reader) *snappyReader {
+	return &snappyReader{r: bufio.NewReader(r)}
+}
+
+func (sr *snappyReader) Read(p []byte) (int, error) {
+	for sr.pos == len(sr.out) {
+		if sr.err != nil {
+			return 0, sr.err
+		}
+		sr.err = sr.next()
+	}
+	n := copy(p, sr.out[sr.pos:])
+	sr.pos += n
+	return n, nil
+}
+
+func (sr *snappyReader) Close() error { return nil }
+
+// next reads chunks until one with data, or the end of the stream.
+func (sr *snappyReader) next() error {
+	for {
+		var hdr [4]byte
+		if _, err := io.ReadFull(sr.r, hdr[:]); err != nil {
+			if err == io.EOF {
+				return io.EOF
+			}
+			return errSnappyCorrupt
+		}
+		n := int(hdr[1]) | int(hdr[2])<<8 | int(hdr[3])<<16
+		if cap(sr.chunk) < n {
+			sr.chunk = make([]byte, n)
+		}
+		chunk := sr.chunk[:n]
+		if _, err := io.ReadFull(sr.r, chunk); err != nil {
+			return errSnappyCorrupt
+		}
+		switch t := hdr[0]; {
+		case t == snappyStreamID:
+			if string(chunk) != snappyMagic {
+				return errSnappyCorrupt
+			}
+			sr.started = true
+			continue
+		case t >= 0x80:
+			// Padding and the other skippable chunks.
+			continue
+		case !sr.started || n < 4 || t != snappyCompressed && t != snappyRaw:
+			return errSnappyCorrupt
+		}
+		data := chunk[4:]
+		if hdr[0] == snappyCompressed {
+			var err error
+			if sr.dec, err = snappyDecodeBlock(sr.dec, data); err != nil {
+				return err
+			}
+			data = sr.dec
+		} else if len(data) > snappyMaxChunk {
+			return errSnappyCorrupt
+		}
+		if snappyCRC(data) != binary.LittleEndian.Uint32(chunk) {
+			return errSnappyCorrupt
+		}
+		sr.out, sr.pos = data, 0
+		if len(data) > 0 {
+			return nil
+		}
+	}
+}
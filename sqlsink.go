@@ -0,0 +1,134 @@
+package speedlog
+
+import (
+	"bytes"
+	"database/sql"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+)
+
+// SQLWriter is a sink that stores entries as rows, one transaction per
+// write, so a default 64 KiB sink buffer becomes one batched insert:
+//
+//	db, _ := sql.Open("sqlite", "logs.db") // any SQLite driver
+//	w, err := speedlog.NewSQLiteWriter(db, "logs", 100000)
+//	logger := speedlog.MustNew(speedlog.WithWriter(w), speedlog.WithEncoder(speedlog.JSONEncoder{}))
+//
+// Lines are parsed back with ParseLine, so the JSON encoder keeps field
+// types; text output works but its fields come back as strings. The table
+// has id, ts (RFC 3339, UTC), level, message and fields (a JSON object).
+// The module ships no driver; the SQL is plain SQLite.
+type SQLWriter struct {
+	mu      sync.Mutex
+	db      *sql.DB
+	insert  string
+	trim    string
+	maxRows int64
+	lines   partialLines
+}
+
+// NewSQLiteWriter creates table if needed. With maxRows > 0 each write
+// also deletes rows beyond the newest maxRows, to cap disk use on small
+// devices.
+func NewSQLiteWriter(db *sql.DB, table string, maxRows int64) (*SQLWriter, error) {
+	if !validIdent(table) {
+		return nil, fmt.Errorf("speedlog: invalid table name %q", table)
+	}
+	schema := `CREATE TABLE IF NOT EXISTS ` + table + ` (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	ts TEXT NOT NULL,
+	level TEXT NOT NULL,
+	message TEXT NOT NULL,
+	fields TEXT NOT NULL
+);
+CREATE INDEX IF NOT EXISTS ` + table + `_ts ON ` + table + ` (ts);`
+	for _, stmt := range strings.Split(schema, ";") {
+		if strings.TrimSpace(stmt) == "" {
+			continue
+		}
+		if _, err := db.Exec(stmt); err != nil {
+			return nil, err
+		}
+	}
+	return &SQLWriter{
+		db:      db,
+		insert:  `INSERT INTO ` + table + ` (ts, level, message, fields) VALUES (?, ?, ?, ?)`,
+		trim:    `DELETE FROM ` + table + ` WHERE id <= (SELECT MAX(id) FROM ` + table + `) - ?`,
+		maxRows: maxRows,
+	}, nil
+}
+
+func validIdent(s string) bool {
+	if s == "" {
+		return false
+	}
+	for i, c := range s {
+		if c != '_' && (c < 'a' || c > 'z') && (c < 'A' || c > 'Z') && (i == 0 || c < '0' || c > '9') {
+			return false
+		}
+	}
+	return true
+}
+
+func (w *SQLWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.lines.write(p, w.store)
+}
+
+func (w *SQLWriter) store(lines []byte) error {
+	tx, err := w.db.Begin()
+	if err != nil {
+		return err
+	}
+	stmt, err := tx.Prepare(w.insert)
+	if err != nil {
+		_ = tx.Rollback()
+		return err
+	}
+	defer stmt.Close()
+	var fields []byte
+	for len(lines) > 0 {
+		i := bytes.IndexByte(lines, '\n')
+		line := lines[:i]
+		lines = lines[i+1:]
+		if len(bytes.TrimSpace(line)) == 0 {
+			continue
+		}
+		e, err := ParseLine(line)
+		if err != nil {
+			// Keep what can't be parsed rather than losing it.
+			e = Entry{Time: time.Now(), Level: INFO, Message: string(line)}
+		}
+		fields = append(fields[:0], '{')
+		for j := range e.Fields {
+			if j > 0 {
+				fields = append(fields, ',')
+			}
+			fields = appendJSONField(fields, &e.Fields[j])
+		}
+		fields = append(fields, '}')
+		ts := e.Time.UTC().Format(time.RFC3339Nano)
+		if _, err := stmt.Exec(ts, LevelName(e.Level), e.Message, string(fields)); err != nil {
+			_ = tx.Rollback()
+			return err
+		}
+	}
+	if w.maxRows > 0 {
+		if _, err := tx.Exec(w.trim, w.maxRows); err != nil {
+			_ = tx.Rollback()
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// Close stores a trailing line that lacked a newline. The database is
+// left open; it belongs to the caller.
+func (w *SQLWriter) Close() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.lines.flush(w.store)
+}
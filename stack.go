@@ -0,0 +1,86 @@
+package speedlog
+
+import (
+	"reflect"
+	"runtime"
+	"strconv"
+)
+
+// WithStacktrace adds a "stack" field to entries at level and above. When
+// one of the entry's error fields carries its own stack (a StackTrace()
+// method returning program counters, as pkg/errors does), that stack is
+// used instead of the logging call site, since it points at where the
+// error was created.
+func WithStacktrace(level int) Option {
+	return func(l *Logger) {
+		l.stacks = true
+		l.stackAt = level
+	}
+}
+
+func stackFor(fields []Field) string {
+	for i := range fields {
+		if fields[i].kind != errorKind || fields[i].val == nil {
+			continue
+		}
+		if pcs := errorStack(fields[i].val.(error)); len(pcs) > 0 {
+			return formatStack(pcs)
+		}
+	}
+	var pcs [64]uintptr
+	n := runtime.Callers(3, pcs[:])
+	i := 0
+	for i < n && isInternal(pcs[i]) {
+		i++
+	}
+	return formatStack(pcs[i:n])
+}
+
+// errorStack returns the stack of the innermost error in the Unwrap chain
+// that has one. pkg/errors' StackTrace is a []Frame of uintptr, so the
+// method is matched by shape rather than by a named type.
+func errorStack(err error) []uintptr {
+	var pcs []uintptr
+	for n := 0; err != nil && n < 32; n++ {
+		if m := reflect.ValueOf(err).MethodByName("StackTrace"); m.IsValid() {
+			t := m.Type()
+			if t.NumIn() == 0 && t.NumOut() == 1 && t.Out(0).Kind() == reflect.Slice && t.Out(0).Elem().Kind() == reflect.Uintptr {
+				v := m.Call(nil)[0]
+				pcs = make([]uintptr, v.Len())
+				for i := range pcs {
+					pcs[i] = uintptr(v.Index(i).Uint())
+				}
+			}
+		}
+		u, ok := err.(interface{ Unwrap() error })
+		if !ok {
+			break
+		}
+		err = u.Unwrap()
+	}
+	return pcs
+}
+
+// formatStack renders one "function\n\tfile:line" pair per frame, the
+// layout of runtime/debug.Stack.
+func formatStack(pcs []uintptr) string {
+	var buf []byte
+	frames := runtime.CallersFrames(pcs)
+	for {
+		f, more := frames.Next()
+		if f.Function != "" {
+			if len(buf) > 0 {
+				buf = append(buf, '\n')
+			}
+			buf = append(buf, f.Function...)
+			buf = append(buf, "\n\t"...)
+			buf = append(buf, f.File...)
+			buf = append(buf, ':')
+			buf = strconv.AppendInt(buf, int64(f.Line), 10)
+		}
+		if !more {
+			break
+		}
+	}
+	return string(buf)
+}
@@ -0,0 +1,38 @@
+package speedlog
+
+// Stats is a snapshot of the pipeline. Entries counts every entry handed
+// to the queue so far; with Pool (the logger's and the async sinks' buffer pools
+// together) it shows how many buffer allocations logging costs per entry.
+type Stats struct {
+	QueueLen int
+	QueueCap int
+	Entries  uint64
+	// AfterClose counts entries logged after Close; see WithAfterClose.
+	AfterClose uint64
+	Pool       PoolStats
+	Sinks      []SinkStats
+}
+
+func (l *Logger) Stats() Stats {
+	sinks := l.sinkList()
+	st := Stats{QueueLen: l.queueLen(), QueueCap: l.queueCap(), Entries: l.entries.Load(), AfterClose: l.lateN.Load(), Pool: l.bufPool.stats(), Sinks: make([]SinkStats, len(sinks))}
+	for i, s := range sinks {
+		st.Sinks[i] = s.stats()
+		st.Pool = st.Pool.add(s.pool.stats())
+	}
+	return st
+}
+
+func (l *Logger) queueCap() int {
+	switch {
+	case l.ring != nil:
+		return len(l.ring.slots)
+	case l.shards != nil:
+		n := 0
+		for _, q := range l.shards.qs {
+			n += cap(q)
+		}
+		return n
+	}
+	return cap(l.ch)
+}
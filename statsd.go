@@ -0,0 +1,214 @@
+package speedlog
+
+import (
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// statsdPacket keeps datagrams under a typical internet MTU; payloads are
+// split between metrics, never inside one.
+const statsdPacket = 1432
+
+// StatsdConfig describes where the logger's health counters go.
+type StatsdConfig struct {
+	Addr     string        // host:port, defaults to 127.0.0.1:8125
+	Prefix   string        // defaults to speedlog
+	Interval time.Duration // defaults to 10s
+
+	// DogStatsD tags every metric with Tags and per-sink metrics with
+	// sink:<name>; plain statsd puts the sink name in the metric name
+	// instead (speedlog.sink.<name>.written).
+	DogStatsD bool
+	Tags      []string
+}
+
+type statsdEmitter struct {
+	cfg  StatsdConfig
+	conn net.Conn
+	buf  []byte
+	pkt  []byte
+	last Stats
+	prev map[SinkID]SinkStats
+	err  error // first send error of this interval
+	fail bool  // reported, quiet until a send succeeds
+}
+
+// WithStatsd sends the numbers in Stats over UDP every interval: queue
+// depth and capacity as gauges; entries and each sink's written, dropped,
+// errors, timeouts and expired as counters (the change since the last
+// send); and each sink's ejected flag as a 0/1 gauge. The last interval is
+// sent on Close. Send failures go to the error handler once until a send succeeds.
+func WithStatsd(cfg StatsdConfig) Option {
+	return func(l *Logger) {
+		if cfg.Addr == "" {
+			cfg.Addr = "127.0.0.1:8125"
+		}
+		if cfg.Prefix == "" {
+			cfg.Prefix = "speedlog"
+		}
+		if cfg.Interval <= 0 {
+			cfg.Interval = 10 * time.Second
+		}
+		cfg.Prefix = strings.TrimSuffix(cfg.Prefix, ".")
+		l.statsd = &statsdEmitter{cfg: cfg}
+	}
+}
+
+func (l *Logger) statsdLoop() {
+	defer l.wg.Done()
+	sd := l.statsd
+	ticker := time.NewTicker(sd.cfg.Interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			sd.emit(l)
+		case <-l.done:
+			sd.emit(l)
+			if sd.conn != nil {
+				_ = sd.conn.Close()
+			}
+			return
+		}
+	}
+}
+
+func (sd *statsdEmitter) emit(l *Logger) {
+	st := l.Stats()
+	sd.pkt = sd.pkt[:0]
+	sd.metric("queue.depth", "", uint64(st.QueueLen), "g")
+	sd.metric("queue.capacity", "", uint64(st.QueueCap), "g")
+	sd.metric("entries", "", st.Entries-sd.last.Entries, "c")
+	prev := sd.prev
+	sd.prev = make(map[SinkID]SinkStats, len(st.Sinks))
+	for _, s := range st.Sinks {
+		name := s.Name
+		if name == "" {
+			name = strconv.Itoa(int(s.ID))
+		}
+		p := prev[s.ID]
+		sd.metric("sink.written", name, delta(s.Written, p.Written), "c")
+		sd.metric("sink.dropped", name, delta(s.Dropped, p.Dropped), "c")
+		sd.metric("sink.errors", name, delta(s.Errors, p.Errors), "c")
+		sd.metric("sink.timeouts", name, delta(s.Timeouts, p.Timeouts), "c")
+		sd.metric("sink.expired", name, delta(s.Expired, p.Expired), "c")
+		sd.prev[s.ID] = s
+		ejected := uint64(0)
+		if s.Ejected {
+			ejected = 1
+		}
+		sd.metric("sink.ejected", name, ejected, "g")
+	}
+	sd.last = st
+	sd.send(l)
+}
+
+// delta is the change since the last send; a counter that went down
+// belongs to a new sink that reused a removed one's id.
+func delta(cur, prev uint64) uint64 {
+	if cur < prev {
+		return cur
+	}
+	return cur - prev
+}
+
+// metric appends one line, sending the packet first if it would overflow.
+func (sd *statsdEmitter) metric(name, sink string, v uint64, typ string) {
+	b := append(sd.buf[:0], sd.cfg.Prefix...)
+	b = append(b, '.')
+	switch {
+	case sink == "":
+		b = append(b, name...)
+	case sd.cfg.DogStatsD:
+		b = append(b, name...)
+	default:
+		group, stat, _ := strings.Cut(name, ".")
+		b = append(b, group...)
+		b = append(b, '.')
+		b = append(b, statsdName(sink, ":@#.")...)
+		b = append(b, '.')
+		b = append(b, stat...)
+	}
+	b = append(b, ':')
+	b = strconv.AppendUint(b, v, 10)
+	b = append(b, '|')
+	b = append(b, typ...)
+	if sd.cfg.DogStatsD && (len(sd.cfg.Tags) > 0 || sink != "") {
+		b = append(b, "|#"...)
+		for i, t := range sd.cfg.Tags {
+			if i > 0 {
+				b = append(b, ',')
+			}
+			b = append(b, statsdName(t, ",")...)
+		}
+		if sink != "" {
+			if len(sd.cfg.Tags) > 0 {
+				b = append(b, ',')
+			}
+			b = append(b, "sink:"...)
+			b = append(b, statsdName(sink, ":,")...)
+		}
+	}
+	sd.buf = b
+	if len(sd.pkt) > 0 && len(sd.pkt)+1+len(b) > statsdPacket {
+		sd.flushPacket()
+	}
+	if len(sd.pkt) > 0 {
+		sd.pkt = append(sd.pkt, '\n')
+	}
+	sd.pkt = append(sd.pkt, b...)
+}
+
+// statsdName replaces the protocol's separators and the characters in
+// extra, so a name or tag can't break the line.
+func statsdName(s, extra string) string {
+	return strings.Map(func(r rune) rune {
+		if r == '|' || r == '\n' || strings.ContainsRune(extra, r) {
+			return '_'
+		}
+		return r
+	}, s)
+}
+
+func (sd *statsdEmitter) flushPacket() {
+	if sd.err == nil {
+		sd.err = sd.write()
+	}
+	sd.pkt = sd.pkt[:0]
+}
+
+func (sd *statsdEmitter) write() error {
+	if sd.conn == nil {
+		c, err := net.Dial("udp", sd.cfg.Addr)
+		if err != nil {
+			return err
+		}
+		sd.conn = c
+	}
+	if _, err := sd.conn.Write(sd.pkt); err != nil {
+		_ = sd.conn.Close()
+		sd.conn = nil
+		return err
+	}
+	return nil
+}
+
+func (sd *statsdEmitter) send(l *Logger) {
+	if len(sd.pkt) > 0 {
+		sd.flushPacket()
+	}
+	err := sd.err
+	sd.err = nil
+	switch {
+	case err == nil:
+		sd.fail = false
+	case !sd.fail:
+		sd.fail = true
+		if l.onError != nil {
+			l.onError(fmt.Errorf("speedlog: statsd: %w", err))
+		}
+	}
+}
@@ -0,0 +1,116 @@
+package speedlog
+
+import (
+	"path/filepath"
+	"strconv"
+	"sync"
+	"time"
+)
+
+type suppressKey struct {
+	level int
+	pc    uintptr
+}
+
+type burst struct {
+	start   time.Time
+	n       int
+	dropped int
+	msg     string
+}
+
+type suppressor struct {
+	window time.Duration
+	limit  int
+	once   sync.Once
+	mu     sync.Mutex
+	keys   map[suppressKey]*burst
+}
+
+// WithSuppression lets the first n entries per (level, call site) through
+// in each window and swallows the rest; when the window closes a
+// "suppressed N similar entries" line is logged for it.
+func WithSuppression(window time.Duration, n int) Option {
+	return func(l *Logger) {
+		if window <= 0 || n <= 0 {
+			l.invalid("WithSuppression(%v, %d): window and count must be positive", window, n)
+			return
+		}
+		l.suppress = &suppressor{window: window, limit: n, keys: map[suppressKey]*burst{}}
+	}
+}
+
+func (l *Logger) allow(level int, msg string) bool {
+	s := l.suppress
+	s.once.Do(func() { go l.suppressLoop() })
+	k := suppressKey{level: level, pc: callerPC(l.skip)}
+	now := time.Now()
+	s.mu.Lock()
+	b := s.keys[k]
+	var closed burst
+	if b == nil || now.Sub(b.start) >= s.window {
+		if b != nil {
+			closed = *b
+		}
+		b = &burst{start: now, msg: msg}
+		s.keys[k] = b
+	}
+	b.n++
+	ok := b.n <= s.limit
+	if !ok {
+		b.dropped++
+	}
+	s.mu.Unlock()
+	if closed.dropped > 0 {
+		l.summarize(k, &closed)
+	}
+	return ok
+}
+
+func (l *Logger) suppressLoop() {
+	ticker := time.NewTicker(l.suppress.window)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			l.flushSuppressed(false)
+		case <-l.done:
+			return
+		}
+	}
+}
+
+// flushSuppressed logs summaries for windows that have closed (or all of
+// them, at shutdown).
+func (l *Logger) flushSuppressed(all bool) {
+	s := l.suppress
+	now := time.Now()
+	var keys []suppressKey
+	var closed []burst
+	s.mu.Lock()
+	for k, b := range s.keys {
+		if all || now.Sub(b.start) >= s.window {
+			if b.dropped > 0 {
+				keys = append(keys, k)
+				closed = append(closed, *b)
+			}
+			delete(s.keys, k)
+		}
+	}
+	s.mu.Unlock()
+	for i := range keys {
+		l.summarize(keys[i], &closed[i])
+	}
+}
+
+func (l *Logger) summarize(k suppressKey, b *burst) {
+	if !l.IsLevelEnabled(k.level) {
+		return
+	}
+	file, line := callerLine(k.pc)
+	e := l.entry(k.level, "suppressed "+strconv.Itoa(b.dropped)+" similar entries", []Field{
+		String("caller", filepath.Base(file)+":"+strconv.Itoa(line)),
+		String("first", b.msg),
+	})
+	l.emit(&e)
+}
@@ -0,0 +1,54 @@
+package speedlog
+
+import (
+	"sync"
+	"sync/atomic"
+)
+
+type tailHub struct {
+	mu   sync.Mutex
+	subs map[chan []byte]struct{}
+	n    atomic.Int32
+}
+
+// Tail streams a copy of everything the writer writes, one chunk of whole
+// lines per batch. A subscriber that falls more than buffer chunks behind
+// misses chunks rather than slowing the logger. Call stop when done.
+func (l *Logger) Tail(buffer int) (lines <-chan []byte, stop func()) {
+	if buffer <= 0 {
+		buffer = 64
+	}
+	h := &l.tail
+	ch := make(chan []byte, buffer)
+	h.mu.Lock()
+	if h.subs == nil {
+		h.subs = map[chan []byte]struct{}{}
+	}
+	h.subs[ch] = struct{}{}
+	h.n.Add(1)
+	h.mu.Unlock()
+	var once sync.Once
+	return ch, func() {
+		once.Do(func() {
+			h.mu.Lock()
+			delete(h.subs, ch)
+			h.n.Add(-1)
+			h.mu.Unlock()
+		})
+	}
+}
+
+func (h *tailHub) publish(batch []record, size int) {
+	chunk := make([]byte, 0, size)
+	for _, rec := range batch {
+		chunk = append(chunk, rec.line...)
+	}
+	h.mu.Lock()
+	for ch := range h.subs {
+		select {
+		case ch <- chunk:
+		default:
+		}
+	}
+	h.mu.Unlock()
+}
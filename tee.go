@@ -0,0 +1,85 @@
+package speedlog
+
+import "io"
+
+// Predicate decides whether a sink gets an entry. It runs on the logging
+// goroutine, so it should be cheap and must not keep e.Fields.
+type Predicate func(e Entry) bool
+
+// SinkSpec is one branch of a Tee. A nil Match receives everything.
+type SinkSpec struct {
+	Writer  io.Writer
+	Match   Predicate
+	Options []SinkOption
+}
+
+// Tee adds one sink per branch, each only getting the entries its
+// predicate accepts.
+func Tee(branches ...SinkSpec) Option {
+	return func(l *Logger) {
+		for _, b := range branches {
+			opts := b.Options
+			if b.Match != nil {
+				opts = append(opts[:len(opts):len(opts)], WithMatch(b.Match))
+			}
+			WithSink(b.Writer, opts...)(l)
+		}
+	}
+}
+
+// WithMatch restricts a sink to entries accepted by p.
+func WithMatch(p Predicate) SinkOption {
+	return func(s *sink) {
+		if p != nil {
+			s.filtered = true
+			s.match = p
+		}
+	}
+}
+
+func LevelRange(min, max int) Predicate {
+	return func(e Entry) bool { return e.Level >= min && e.Level <= max }
+}
+
+func HasField(key string) Predicate {
+	return func(e Entry) bool {
+		_, ok := e.Field(key)
+		return ok
+	}
+}
+
+// FieldEquals matches entries whose key field equals value, compared the
+// way Any(key, value) would store it.
+func FieldEquals(key string, value any) Predicate {
+	want := Any(key, value)
+	return func(e Entry) bool {
+		f, ok := e.Field(key)
+		return ok && f.equal(want)
+	}
+}
+
+func And(ps ...Predicate) Predicate {
+	return func(e Entry) bool {
+		for _, p := range ps {
+			if !p(e) {
+				return false
+			}
+		}
+		return true
+	}
+}
+
+func Or(ps ...Predicate) Predicate {
+	return func(e Entry) bool {
+		for _, p := range ps {
+			if p(e) {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+func Not(p Predicate) Predicate {
+	return func(e Entry) bool { return !p(e) }
+}
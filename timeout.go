@@ -0,0 +1,104 @@
+package speedlog
+
+import (
+	"errors"
+	"io"
+	"sync/atomic"
+	"time"
+)
+
+var (
+	ErrWriteTimeout = errors.New("speedlog: sink write timed out")
+	ErrSinkStalled  = errors.New("speedlog: sink still blocked in a previous write")
+)
+
+const (
+	twIdle int32 = iota
+	twRunning
+	twFinished
+	twAbandoned
+)
+
+// timeoutWriter runs writes on a helper goroutine and gives up waiting
+// after d. A write that never returns (hung NFS, stuck pipe) leaves the
+// writer busy and later writes fail fast with ErrSinkStalled instead of
+// freezing the pipeline.
+type timeoutWriter struct {
+	w     io.Writer
+	d     time.Duration
+	state atomic.Int32
+	jobs  chan func() error
+	res   chan error
+	buf   []byte
+}
+
+func newTimeoutWriter(w io.Writer, d time.Duration) *timeoutWriter {
+	t := &timeoutWriter{
+		w:    w,
+		d:    d,
+		jobs: make(chan func() error),
+		res:  make(chan error, 1),
+	}
+	go t.run()
+	return t
+}
+
+func (t *timeoutWriter) run() {
+	for job := range t.jobs {
+		err := job()
+		if t.state.CompareAndSwap(twRunning, twFinished) {
+			t.res <- err
+		} else {
+			t.state.Store(twIdle)
+		}
+	}
+}
+
+func (t *timeoutWriter) do(job func() error) error {
+	t.jobs <- job
+	timer := time.NewTimer(t.d)
+	defer timer.Stop()
+	select {
+	case err := <-t.res:
+		t.state.Store(twIdle)
+		return err
+	case <-timer.C:
+		if t.state.CompareAndSwap(twRunning, twAbandoned) {
+			return ErrWriteTimeout
+		}
+		err := <-t.res
+		t.state.Store(twIdle)
+		return err
+	}
+}
+
+func (t *timeoutWriter) Write(p []byte) (int, error) {
+	if !t.state.CompareAndSwap(twIdle, twRunning) {
+		return 0, ErrSinkStalled
+	}
+	t.buf = append(t.buf[:0], p...)
+	err := t.do(func() error {
+		_, err := t.w.Write(t.buf)
+		return err
+	})
+	if err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+func (t *timeoutWriter) Flush() error {
+	f, ok := t.w.(flusher)
+	if !ok {
+		return nil
+	}
+	if !t.state.CompareAndSwap(twIdle, twRunning) {
+		return ErrSinkStalled
+	}
+	return t.do(f.Flush)
+}
+
+func (t *timeoutWriter) Close() error {
+	close(t.jobs)
+	return nil
+}
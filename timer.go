@@ -0,0 +1,47 @@
+package speedlog
+
+import "time"
+
+// Timer measures one operation; Done logs its name with an "elapsed"
+// field.
+type Timer struct {
+	l         *Logger
+	name      string
+	start     time.Time
+	fields    []Field
+	level     int
+	threshold time.Duration
+}
+
+func (l *Logger) Timer(name string, fields ...Field) *Timer {
+	return &Timer{l: l, name: name, start: time.Now(), fields: fields, level: INFO}
+}
+
+func StartTimer(name string, fields ...Field) *Timer {
+	return defaultLogger().Timer(name, fields...)
+}
+
+// Threshold makes Done stay quiet for operations faster than d.
+func (t *Timer) Threshold(d time.Duration) *Timer {
+	t.threshold = d
+	return t
+}
+
+func (t *Timer) Level(level int) *Timer {
+	t.level = level
+	return t
+}
+
+// Done logs the elapsed time (if it reached the threshold) and returns it.
+func (t *Timer) Done(fields ...Field) time.Duration {
+	elapsed := time.Since(t.start)
+	if elapsed < t.threshold || !t.l.enabled(t.level) {
+		return elapsed
+	}
+	all := make([]Field, 0, len(t.fields)+len(fields)+1)
+	all = append(all, t.fields...)
+	all = append(all, fields...)
+	all = append(all, Duration("elapsed", elapsed))
+	t.l.log(t.level, t.name, all...)
+	return elapsed
+}
@@ -0,0 +1,36 @@
+package speedlog
+
+import "time"
+
+// WithEntryTTL drops entries that waited longer than ttl between being
+// logged and being written to a sink, counting them in SinkStats.Expired.
+// After a long sink outage the backlog is then skipped instead of being
+// flushed for minutes ahead of fresh entries. The age is checked per sink,
+// so a sink with its own queue (WithQueue) drops only what it fell behind
+// on; inline writes and WAL resends are never dropped.
+func WithEntryTTL(ttl time.Duration) Option {
+	return func(l *Logger) {
+		if ttl <= 0 {
+			l.invalid("WithEntryTTL(%v): ttl must be positive", ttl)
+			return
+		}
+		l.ttl = ttl
+	}
+}
+
+// stamp is the enqueue time recorded under WithEntryTTL, 0 without it.
+func (l *Logger) stamp() int64 {
+	if l.ttl == 0 {
+		return 0
+	}
+	return time.Now().UnixNano()
+}
+
+// stale reports whether rec is past the sink's TTL at cutoff.
+func (s *sink) stale(rec record, cutoff int64) bool {
+	if rec.at == 0 || rec.at >= cutoff {
+		return false
+	}
+	s.expired.Add(1)
+	return true
+}
@@ -0,0 +1,92 @@
+package speedlog
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"sync"
+)
+
+// maxDatagram is the largest UDP payload over IPv4.
+const maxDatagram = 65507
+
+// UDPWriter is a sink that sends lines as UDP datagrams of at most mtu
+// bytes. An entry is never split: with batching, whole lines are packed
+// into each datagram until the next one wouldn't fit; a line longer than
+// mtu goes out alone, and one longer than a datagram can hold is dropped.
+type UDPWriter struct {
+	mu    sync.Mutex
+	r     redialer
+	mtu   int
+	batch bool
+	lines partialLines
+	pkt   []byte
+}
+
+// NewUDPWriter sends to addr (host:port). mtu <= 0 means 1472, the payload
+// that fits an Ethernet frame without IP fragmentation. Without batch,
+// every line is its own datagram, which suits collectors that expect one
+// message per packet.
+func NewUDPWriter(addr string, mtu int, batch bool) (*UDPWriter, error) {
+	if mtu <= 0 {
+		mtu = 1472
+	}
+	w := &UDPWriter{r: redialer{network: "udp", addr: addr, timeout: busTimeout}, mtu: min(mtu, maxDatagram), batch: batch}
+	if _, err := w.r.get(); err != nil {
+		return nil, err
+	}
+	return w, nil
+}
+
+func (w *UDPWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.lines.write(p, w.send)
+}
+
+func (w *UDPWriter) send(lines []byte) error {
+	var errs []error
+	dropped := 0
+	w.pkt = w.pkt[:0]
+	_ = eachLine(lines, func(line []byte) error {
+		n := len(line) + 1
+		if n > maxDatagram {
+			dropped++
+			return nil
+		}
+		if len(w.pkt) > 0 && (!w.batch || len(w.pkt)+n > w.mtu) {
+			if err := w.flushPacket(); err != nil {
+				errs = append(errs, err)
+			}
+		}
+		w.pkt = append(append(w.pkt, line...), '\n')
+		return nil
+	})
+	if len(w.pkt) > 0 {
+		if err := w.flushPacket(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if dropped > 0 {
+		errs = append(errs, fmt.Errorf("speedlog: udp: dropped %d lines too long for a datagram", dropped))
+	}
+	return errors.Join(errs...)
+}
+
+func (w *UDPWriter) flushPacket() error {
+	err := w.r.do(func(conn net.Conn) error {
+		_, err := conn.Write(w.pkt)
+		return err
+	})
+	w.pkt = w.pkt[:0]
+	return err
+}
+
+// Close sends a trailing line that lacked a newline and closes the socket.
+func (w *UDPWriter) Close() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	err := w.lines.flush(w.send)
+	w.r.drop()
+	return err
+}
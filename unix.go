@@ -0,0 +1,73 @@
+package speedlog
+
+import (
+	"bytes"
+	"errors"
+	"net"
+	"sync"
+)
+
+// UnixWriter is a sink for a collector listening on a unix socket. A
+// stream socket gets whole lines in one write per batch; a datagram
+// socket gets one datagram per line. The socket is redialed when the
+// collector restarts.
+type UnixWriter struct {
+	mu    sync.Mutex
+	r     redialer
+	gram  bool
+	lines partialLines
+}
+
+// NewUnixWriter connects to the socket at path, as SOCK_DGRAM when
+// datagram is set and SOCK_STREAM otherwise.
+func NewUnixWriter(path string, datagram bool) (*UnixWriter, error) {
+	network := "unix"
+	if datagram {
+		network = "unixgram"
+	}
+	w := &UnixWriter{r: redialer{network: network, addr: path, timeout: busTimeout}, gram: datagram}
+	if _, err := w.r.get(); err != nil {
+		return nil, err
+	}
+	return w, nil
+}
+
+func (w *UnixWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.lines.write(p, w.send)
+}
+
+func (w *UnixWriter) send(lines []byte) error {
+	if !w.gram {
+		return w.r.do(func(conn net.Conn) error {
+			_, err := conn.Write(lines)
+			return err
+		})
+	}
+	var errs []error
+	for len(lines) > 0 {
+		n := bytes.IndexByte(lines, '\n') + 1
+		if n == 0 {
+			n = len(lines)
+		}
+		line := lines[:n]
+		lines = lines[n:]
+		if err := w.r.do(func(conn net.Conn) error {
+			_, err := conn.Write(line)
+			return err
+		}); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Close sends a trailing line that lacked a newline and closes the socket.
+func (w *UnixWriter) Close() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	err := w.lines.flush(w.send)
+	w.r.drop()
+	return err
+}
@@ -0,0 +1,89 @@
+package speedlog
+
+import (
+	"sync/atomic"
+	"time"
+)
+
+const volumeBuckets = 60
+
+type volumeBucket struct {
+	epoch   atomic.Int64
+	entries [len(levelNames)]atomic.Uint64
+	bytes   [len(levelNames)]atomic.Uint64
+}
+
+type volume struct {
+	step    time.Duration
+	entries [len(levelNames)]atomic.Uint64
+	bytes   [len(levelNames)]atomic.Uint64
+	buckets [volumeBuckets]volumeBucket
+}
+
+type LevelVolume struct {
+	Level   int
+	Entries uint64 // since New
+	Bytes   uint64
+	// Entries and bytes in the trailing window given to WithVolumeStats.
+	WindowEntries uint64
+	WindowBytes   uint64
+}
+
+// WithVolumeStats counts entries and encoded bytes per level, in total and
+// over a sliding window (default one minute), for Volume.
+func WithVolumeStats(window time.Duration) Option {
+	return func(l *Logger) {
+		if window <= 0 {
+			window = time.Minute
+		}
+		l.volume = &volume{step: max(window/volumeBuckets, time.Millisecond)}
+	}
+}
+
+func (v *volume) record(level int, t time.Time, n int) {
+	if level < 0 || level >= len(levelNames) {
+		return
+	}
+	v.entries[level].Add(1)
+	v.bytes[level].Add(uint64(n))
+	epoch := t.UnixNano() / int64(v.step)
+	b := &v.buckets[epoch%volumeBuckets]
+	// The first writer into a recycled bucket clears it; a concurrent add
+	// can land on either side of the reset, so windows are approximate.
+	if old := b.epoch.Load(); old != epoch && b.epoch.CompareAndSwap(old, epoch) {
+		for i := range b.entries {
+			b.entries[i].Store(0)
+			b.bytes[i].Store(0)
+		}
+	}
+	b.entries[level].Add(1)
+	b.bytes[level].Add(uint64(n))
+}
+
+// Volume reports per-level counts, or nil without WithVolumeStats.
+func (l *Logger) Volume() []LevelVolume {
+	v := l.volume
+	if v == nil {
+		return nil
+	}
+	now := time.Now().UnixNano() / int64(v.step)
+	out := make([]LevelVolume, len(levelNames))
+	for level := range out {
+		out[level] = LevelVolume{
+			Level:   level,
+			Entries: v.entries[level].Load(),
+			Bytes:   v.bytes[level].Load(),
+		}
+	}
+	for i := range v.buckets {
+		b := &v.buckets[i]
+		if now-b.epoch.Load() >= volumeBuckets {
+			continue
+		}
+		for level := range out {
+			out[level].WindowEntries += b.entries[level].Load()
+			out[level].WindowBytes += b.bytes[level].Load()
+		}
+	}
+	return out
+}
@@ -0,0 +1,136 @@
+package speedlog
+
+import (
+	"io"
+	"os"
+)
+
+// wal keeps a sink's lines on local disk from just before they are sent
+// until a flush of the sink succeeds. Anything still in it when the
+// process starts (or after a failed send) is sent again first, so delivery
+// is at-least-once.
+type wal struct {
+	path   string
+	f      *os.File
+	size   int64
+	replay bool
+	buf    []byte
+}
+
+// WithWAL puts a write-ahead log at path in front of the sink. Each batch
+// is appended and fsynced before it is written to the sink, and the file
+// is emptied after every successful flush.
+func WithWAL(path string) SinkOption {
+	return func(s *sink) {
+		if path == "" {
+			s.invalid("WithWAL: empty path")
+			return
+		}
+		s.wal = &wal{path: path}
+	}
+}
+
+func (w *wal) open() error {
+	f, err := os.OpenFile(w.path, os.O_CREATE|os.O_RDWR|os.O_APPEND, 0o600)
+	if err != nil {
+		return err
+	}
+	fi, err := f.Stat()
+	if err != nil {
+		_ = f.Close()
+		return err
+	}
+	w.f, w.size, w.replay = f, fi.Size(), fi.Size() > 0
+	return nil
+}
+
+func (w *wal) append(p []byte) error {
+	n, err := w.f.Write(p)
+	w.size += int64(n)
+	if err != nil {
+		return err
+	}
+	return w.f.Sync()
+}
+
+func (w *wal) checkpoint() error {
+	if w.size == 0 {
+		return nil
+	}
+	if err := w.f.Truncate(0); err != nil {
+		return err
+	}
+	w.size = 0
+	return nil
+}
+
+// writeWAL is writeLines for a sink with a WAL.
+func (s *sink) writeWAL(batch [][]byte) {
+	w := s.wal
+	chunk := w.buf[:0]
+	for _, line := range batch {
+		if s.signer != nil {
+			s.signed = s.signer.sign(append(s.signed[:0], line...))
+			line = s.signed
+		}
+		chunk = append(chunk, line...)
+	}
+	w.buf = chunk
+	if err := w.append(chunk); err != nil {
+		s.report(err)
+	}
+	if w.replay {
+		// The chunk is in the file now, so resending the file covers it.
+		s.resend()
+		s.written.Add(uint64(len(batch)))
+		return
+	}
+	if len(chunk) > s.bw.Available() && s.bw.Buffered() > 0 {
+		if err := s.bw.Flush(); err != nil {
+			s.fail(err)
+			return
+		}
+	}
+	if _, err := s.bw.Write(chunk); err != nil {
+		s.fail(err)
+		return
+	}
+	s.written.Add(uint64(len(batch)))
+	s.recovered()
+}
+
+func (s *sink) resend() {
+	f, err := os.Open(s.wal.path)
+	if err != nil {
+		s.report(err)
+		return
+	}
+	defer f.Close()
+	s.arm()
+	if _, err := io.Copy(s.bw, f); err != nil {
+		s.fail(err)
+		return
+	}
+	if err := s.bw.Flush(); err != nil {
+		s.fail(err)
+		return
+	}
+	s.wal.replay = false
+	s.recovered()
+}
+
+// afterFlush empties the WAL once everything in it made it out.
+func (s *sink) afterFlush() {
+	if s.wal == nil || s.wal.replay {
+		return
+	}
+	if err := s.wal.checkpoint(); err != nil {
+		s.report(err)
+	}
+}
+
+func (s *sink) closeWAL() {
+	if s.wal != nil {
+		_ = s.wal.f.Close()
+	}
+}
@@ -0,0 +1,925 @@
+package speedlog
+
+import (
+	"encoding/binary"
+	"io"
+	"math"
+	"math/bits"
+	"slices"
+)
+
+// Zstd returns the zstd codec, written in Go on the standard library. The
+// encoder has one setting, close to gzip's default ratio at a faster
+// speed: hashed matches over a 512 KiB window, Huffman-coded literals
+// and FSE tables fitted to each block, with a content checksum. The
+// decoder reads any zstd stream without a dictionary, so .zst files from
+// the zstd tool work too.
+func Zstd() Codec {
+	return &stdCodec{
+		name: "zstd", ext: ".zst",
+		writer: func(w io.Writer, _ int) (io.WriteCloser, error) { return newZstdWriter(w), nil },
+		reader: func(r io.Reader) (io.ReadCloser, error) { return newZstdReader(r), nil },
+	}
+}
+
+const (
+	zstdMagic     = 0xfd2fb528
+	zstdBlockMax  = 128 << 10
+	zstdWindowLog = 19
+	zstdWindow    = 1 << zstdWindowLog
+	zstdHashLog   = 16
+)
+
+// Literal length and match length codes: the value is base[code] plus
+// bits[code] extra bits.
+var (
+	zstdLLBase = [36]uint32{
+		0, 1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15,
+		16, 18, 20, 22, 24, 28, 32, 40, 48, 64, 128, 256, 512, 1024, 2048, 4096,
+		8192, 16384, 32768, 65536,
+	}
+	zstdLLBits = [36]uint8{
+		0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0,
+		1, 1, 1, 1, 2, 2, 3, 3, 4, 6, 7, 8, 9, 10, 11, 12,
+		13, 14, 15, 16,
+	}
+	zstdMLBase = [53]uint32{
+		3, 4, 5, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15, 16, 17, 18,
+		19, 20, 21, 22, 23, 24, 25, 26, 27, 28, 29, 30, 31, 32, 33, 34,
+		35, 37, 39, 41, 43, 47, 51, 59, 67, 83, 99, 131, 259, 515, 1027, 2051,
+		4099, 8195, 16387, 32771, 65539,
+	}
+	zstdMLBits = [53]uint8{
+		0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0,
+		0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0,
+		1, 1, 1, 1, 2, 2, 3, 3, 4, 4, 5, 7, 8, 9, 10, 11,
+		12, 13, 14, 15, 16,
+	}
+)
+
+// The predefined distributions of RFC 8878 3.1.1.3.2.2; -1 is a
+// probability below one.
+var (
+	zstdLLNorm = []int16{
+		4, 3, 2, 2, 2, 2, 2, 2, 2, 2, 2, 2, 2, 1, 1, 1,
+		2, 2, 2, 2, 2, 2, 2, 2, 2, 3, 2, 1, 1, 1, 1, 1,
+		-1, -1, -1, -1,
+	}
+	zstdMLNorm = []int16{
+		1, 4, 3, 2, 2, 2, 2, 2, 2, 1, 1, 1, 1, 1, 1, 1,
+		1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1,
+		1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, -1, -1,
+		-1, -1, -1, -1, -1,
+	}
+	zstdOFNorm = []int16{
+		1, 1, 1, 1, 1, 1, 2, 2, 2, 1, 1, 1, 1, 1, 1, 1,
+		1, 1, 1, 1, 1, 1, 1, 1, -1, -1, -1, -1, -1,
+	}
+)
+
+const (
+	zstdLLLog = 6
+	zstdMLLog = 6
+	zstdOFLog = 5
+)
+
+var (
+	zstdLLEnc = zstdBuildEncoder(zstdLLNorm, zstdLLLog)
+	zstdMLEnc = zstdBuildEncoder(zstdMLNorm, zstdMLLog)
+	zstdOFEnc = zstdBuildEncoder(zstdOFNorm, zstdOFLog)
+	// Codes of the short lengths, searched once rather than per sequence.
+	zstdLLCodes = zstdCodes(zstdLLBase[:], 0, 64)
+	zstdMLCodes = zstdCodes(zstdMLBase[:], 3, 128)
+)
+
+func zstdCodes(base []uint32, min uint32, n int) []uint8 {
+	codes := make([]uint8, n)
+	c := 0
+	for v := range codes {
+		for c+1 < len(base) && base[c+1] <= uint32(v)+min {
+			c++
+		}
+		codes[v] = uint8(c)
+	}
+	return codes
+}
+
+func zstdLLCode(ll uint32) uint8 {
+	if ll < 64 {
+		return zstdLLCodes[ll]
+	}
+	return uint8(bits.Len32(ll)-1) + 19
+}
+
+func zstdMLCode(ml uint32) uint8 {
+	if ml-3 < 128 {
+		return zstdMLCodes[ml-3]
+	}
+	return uint8(bits.Len32(ml-3)-1) + 36
+}
+
+// zstdSpread lays the symbols of an FSE distribution over its table the
+// way the format requires, low-probability symbols at the top.
+func zstdSpread(norm []int16, log uint) ([]uint8, bool) {
+	size := 1 << log
+	syms := make([]uint8, size)
+	high := size - 1
+	for s, c := range norm {
+		if c == -1 {
+			syms[high] = uint8(s)
+			high--
+		}
+	}
+	step, mask, pos := size>>1+size>>3+3, size-1, 0
+	for s, c := range norm {
+		for range max(c, 0) {
+			syms[pos] = uint8(s)
+			pos = (pos + step) & mask
+			for pos > high {
+				pos = (pos + step) & mask
+			}
+		}
+	}
+	return syms, pos == 0
+}
+
+type zstdEncoder struct {
+	log    uint
+	states []uint16
+	sym    []struct {
+		deltaBits  uint32
+		deltaState int32
+	}
+}
+
+func zstdBuildEncoder(norm []int16, log uint) *zstdEncoder {
+	size := 1 << log
+	syms, _ := zstdSpread(norm, log)
+	e := &zstdEncoder{log: log, states: make([]uint16, size)}
+	e.sym = make([]struct {
+		deltaBits  uint32
+		deltaState int32
+	}, len(norm))
+	cumul := make([]int, len(norm)+1)
+	for s, c := range norm {
+		cumul[s+1] = cumul[s] + max(int(c), -int(c))
+	}
+	for u, s := range syms {
+		e.states[cumul[s]] = uint16(size + u)
+		cumul[s]++
+	}
+	total := int32(0)
+	for s, c := range norm {
+		switch c {
+		case 0:
+		case -1, 1:
+			e.sym[s].deltaBits = uint32(log<<16) - uint32(size)
+			e.sym[s].deltaState = total - 1
+			total++
+		default:
+			out := log - uint(bits.Len16(uint16(c-1))-1)
+			e.sym[s].deltaBits = uint32(out<<16) - uint32(c)<<out
+			e.sym[s].deltaState = total - int32(c)
+			total += int32(c)
+		}
+	}
+	return e
+}
+
+// zstdState encodes one symbol stream; the first symbol encoded is the
+// last one decoded.
+type zstdState struct {
+	e *zstdEncoder
+	v uint32
+}
+
+func (st *zstdState) init(e *zstdEncoder, s uint8) {
+	t := e.sym[s]
+	nb := (t.deltaBits + 1<<15) >> 16
+	v := nb<<16 - t.deltaBits
+	st.e, st.v = e, uint32(e.states[int32(v>>nb)+t.deltaState])
+}
+
+func (st *zstdState) encode(bw *zstdBitWriter, s uint8) {
+	t := st.e.sym[s]
+	nb := (st.v + t.deltaBits) >> 16
+	bw.add(uint64(st.v), uint(nb))
+	st.v = uint32(st.e.states[int32(st.v>>nb)+t.deltaState])
+}
+
+func (st *zstdState) flush(bw *zstdBitWriter) { bw.add(uint64(st.v), st.e.log) }
+
+// zstdBitWriter writes the format's backward bitstreams: bits go in low
+// to high, and close adds the end marker the decoder starts from.
+type zstdBitWriter struct {
+	out []byte
+	acc uint64
+	n   uint
+}
+
+func (bw *zstdBitWriter) add(v uint64, n uint) {
+	bw.acc |= (v & (1<<n - 1)) << bw.n
+	bw.n += n
+	for bw.n >= 8 {
+		bw.out = append(bw.out, byte(bw.acc))
+		bw.acc >>= 8
+		bw.n -= 8
+	}
+}
+
+func (bw *zstdBitWriter) close() []byte {
+	bw.add(1, 1)
+	if bw.n > 0 {
+		bw.out = append(bw.out, byte(bw.acc))
+	}
+	bw.acc, bw.n = 0, 0
+	return bw.out
+}
+
+type zstdSeq struct {
+	lit, match, offVal uint32
+	llc, mlc, ofc      uint8
+}
+
+type zstdWriter struct {
+	w       io.Writer
+	hist    []byte // the window, then input not yet written as a block
+	start   int
+	table   []int32 // hash of 4 bytes -> position in hist + 1
+	rep     [3]uint32
+	seqs    []zstdSeq
+	lits    []byte
+	out     []byte
+	xxh     zstdXXH
+	started bool
+	closed  bool
+	err     error
+}
+
+func newZstdWriter(w io.Writer) *zstdWriter {
+	zw := &zstdWriter{w: w, table: make([]int32, 1<<zstdHashLog), rep: [3]uint32{1, 4, 8}}
+	zw.xxh.reset()
+	return zw
+}
+
+func (zw *zstdWriter) Write(p []byte) (int, error) {
+	n := 0
+	for len(p) > 0 && zw.err == nil {
+		if zw.hist == nil {
+			zw.hist = make([]byte, 0, 2*zstdWindow+zstdBlockMax)
+		}
+		k := min(len(p), zstdBlockMax-(len(zw.hist)-zw.start))
+		zw.hist = append(zw.hist, p[:k]...)
+		p, n = p[k:], n+k
+		if len(zw.hist)-zw.start == zstdBlockMax {
+			zw.block(false)
+		}
+	}
+	return n, zw.err
+}
+
+// Flush writes the pending input as a block, which a reader can decode
+// without the rest of the frame.
+func (zw *zstdWriter) Flush() error {
+	if zw.err == nil && len(zw.hist) > zw.start {
+		zw.block(false)
+	}
+	return zw.err
+}
+
+// Close ends the frame with the last block and the checksum.
+func (zw *zstdWriter) Close() error {
+	if zw.closed || zw.err != nil {
+		return zw.err
+	}
+	zw.closed = true
+	zw.block(true)
+	return zw.err
+}
+
+func (zw *zstdWriter) block(last bool) {
+	src := zw.hist[zw.start:]
+	zw.xxh.write(src)
+	out := zw.out[:0]
+	if !zw.started {
+		out = binary.LittleEndian.AppendUint32(out, zstdMagic)
+		// A content checksum and the window size, no dictionary and no
+		// content size, so the input can be streamed.
+		out = append(out, 1<<2, (zstdWindowLog-10)<<3)
+		zw.started = true
+	}
+	hdr := len(out)
+	out = append(out, 0, 0, 0)
+	typ, rep := uint32(2), zw.rep
+	if len(src) > 0 {
+		out = zw.compress(out)
+	}
+	if len(out)-hdr-3 >= len(src) {
+		// The decoder never sees the sequences of a raw block.
+		out = append(out[:hdr+3], src...)
+		typ, zw.rep = 0, rep
+	}
+	h := uint32(len(out)-hdr-3)<<3 | typ<<1
+	if last {
+		h |= 1
+	}
+	out[hdr], out[hdr+1], out[hdr+2] = byte(h), byte(h>>8), byte(h>>16)
+	if last {
+		out = binary.LittleEndian.AppendUint32(out, uint32(zw.xxh.sum()))
+	}
+	zw.out = out
+	_, zw.err = zw.w.Write(out)
+	zw.start = len(zw.hist)
+	if len(zw.hist) > 2*zstdWindow {
+		zw.slide()
+	}
+}
+
+// slide drops all but the last window of history.
+func (zw *zstdWriter) slide() {
+	d := len(zw.hist) - zstdWindow
+	zw.hist = zw.hist[:copy(zw.hist, zw.hist[d:])]
+	zw.start -= d
+	for i, p := range zw.table {
+		zw.table[i] = max(p-int32(d), 0)
+	}
+}
+
+// zstdHash hashes the low six bytes of u: shorter matches found through
+// the table rarely pay for their offset.
+func zstdHash(u uint64) uint32 { return uint32((u << 16 * 0xcf1bbcdcb7a56463) >> (64 - zstdHashLog)) }
+
+// compress appends the pending input as a compressed block body.
+func (zw *zstdWriter) compress(dst []byte) []byte {
+	h, end := zw.hist, len(zw.hist)
+	zw.seqs, zw.lits = zw.seqs[:0], zw.lits[:0]
+	lit, s := zw.start, zw.start
+	for s+8 <= end {
+		cur := binary.LittleEndian.Uint64(h[s:])
+		k := zstdHash(cur)
+		c := int(zw.table[k]) - 1
+		zw.table[k] = int32(s + 1)
+		// The last offset again is the cheapest match to code, worth
+		// taking from four bytes.
+		if r := int(zw.rep[0]); s >= r && binary.LittleEndian.Uint32(h[s-r:]) == uint32(cur) {
+			c = s - r
+		} else if c < 0 || s-c >= zstdWindow || (binary.LittleEndian.Uint64(h[c:])^cur)<<16 != 0 {
+			s += 1 + (s-lit)>>6
+			continue
+		}
+		n := zstdMatchLen(h[c:], h[s:end])
+		// One step ahead may find a longer match.
+		if s+9 <= end {
+			next := binary.LittleEndian.Uint64(h[s+1:])
+			k := zstdHash(next)
+			c2 := int(zw.table[k]) - 1
+			zw.table[k] = int32(s + 2)
+			if c2 >= 0 && s+1-c2 < zstdWindow && (binary.LittleEndian.Uint64(h[c2:])^next)<<16 == 0 {
+				if n2 := zstdMatchLen(h[c2:], h[s+1:end]); n2 > n+1 {
+					s, c, n = s+1, c2, n2
+				}
+			}
+		}
+		for s > lit && c > 0 && h[s-1] == h[c-1] {
+			s--
+			c--
+			n++
+		}
+		zw.lits = append(zw.lits, h[lit:s]...)
+		zw.seqs = append(zw.seqs, zstdSeq{lit: uint32(s - lit), match: uint32(n), offVal: zw.offVal(uint32(s-c), uint32(s-lit))})
+		s += n
+		lit = s
+		if s+6 <= end {
+			zw.table[zstdHash(binary.LittleEndian.Uint64(h[s-2:]))] = int32(s - 1)
+		}
+	}
+	zw.lits = append(zw.lits, h[lit:end]...)
+	dst = zstdLiterals(dst, zw.lits)
+	return zstdSequences(dst, zw.seqs)
+}
+
+func zstdMatchLen(a, b []byte) int {
+	n := 0
+	for n+8 <= len(b) {
+		if x := binary.LittleEndian.Uint64(a[n:]) ^ binary.LittleEndian.Uint64(b[n:]); x != 0 {
+			return n + bits.TrailingZeros64(x)/8
+		}
+		n += 8
+	}
+	for n < len(b) && a[n] == b[n] {
+		n++
+	}
+	return n
+}
+
+// offVal codes offset as a repeat of a recent one where it can, keeping
+// the recent offsets the way the decoder will.
+func (zw *zstdWriter) offVal(offset, lit uint32) uint32 {
+	r := &zw.rep
+	switch {
+	case lit > 0 && offset == r[0]:
+		return 1
+	case lit > 0 && offset == r[1], lit == 0 && offset == r[1]:
+		r[0], r[1] = r[1], r[0]
+		if lit == 0 {
+			return 1
+		}
+		return 2
+	case offset == r[2]:
+		*r = [3]uint32{r[2], r[0], r[1]}
+		if lit == 0 {
+			return 2
+		}
+		return 3
+	case lit == 0 && offset == r[0]-1:
+		*r = [3]uint32{offset, r[0], r[1]}
+		return 3
+	}
+	*r = [3]uint32{offset, r[0], r[1]}
+	return offset + 3
+}
+
+func zstdLitHeader(dst []byte, typ byte, n int) []byte {
+	switch {
+	case n < 32:
+		return append(dst, typ|byte(n)<<3)
+	case n < 4096:
+		return append(dst, typ|1<<2|byte(n&15)<<4, byte(n>>4))
+	}
+	return append(dst, typ|3<<2|byte(n&15)<<4, byte(n>>4), byte(n>>12))
+}
+
+// zstdLiterals appends the literals section: Huffman-coded when that
+// pays, as they are otherwise.
+func zstdLiterals(dst, lits []byte) []byte {
+	var counts [256]int
+	for _, b := range lits {
+		counts[b]++
+	}
+	maxSym, distinct := 0, 0
+	for s, c := range counts {
+		if c > 0 {
+			maxSym = s
+			distinct++
+		}
+	}
+	switch {
+	case distinct == 1 && len(lits) > 1:
+		return append(zstdLitHeader(dst, 1, len(lits)), lits[0])
+	case len(lits) < 64 || distinct < 2:
+		return append(zstdLitHeader(dst, 0, len(lits)), lits...)
+	}
+	var lens [256]uint8
+	maxBits := huffLengths(counts[:maxSym+1], lens[:], 11)
+	var codes [256]uint16
+	pos := 0
+	for w := 1; w <= int(maxBits); w++ {
+		for s := 0; s <= maxSym; s++ {
+			if lens[s] != 0 && int(maxBits)+1-int(lens[s]) == w {
+				codes[s] = uint16(pos >> (w - 1))
+				pos += 1 << (w - 1)
+			}
+		}
+	}
+	hdr := len(dst)
+	streams := 1
+	hdrLen := 3
+	if len(lits) > 1023 {
+		streams = 4
+		hdrLen = 5
+		if len(lits) <= 16383 {
+			hdrLen = 4
+		}
+	}
+	dst = append(dst, make([]byte, hdrLen)...)
+	body := len(dst)
+	var weights [256]uint8
+	for s, n := range lens[:maxSym] {
+		if n > 0 {
+			weights[s] = maxBits + 1 - n
+		}
+	}
+	if dst = zstdWeights(dst, weights[:maxSym]); len(dst) == body {
+		return append(zstdLitHeader(dst[:hdr], 0, len(lits)), lits...)
+	}
+	encode := func(dst, src []byte) []byte {
+		bw := zstdBitWriter{out: dst}
+		for i := len(src) - 1; i >= 0; i-- {
+			bw.add(uint64(codes[src[i]]), uint(lens[src[i]]))
+		}
+		return bw.close()
+	}
+	if streams == 1 {
+		dst = encode(dst, lits)
+	} else {
+		seg := (len(lits) + 3) / 4
+		jump := len(dst)
+		dst = append(dst, 0, 0, 0, 0, 0, 0)
+		for i := range 4 {
+			from := len(dst)
+			dst = encode(dst, lits[i*seg:min((i+1)*seg, len(lits))])
+			if i < 3 {
+				binary.LittleEndian.PutUint16(dst[jump+2*i:], uint16(len(dst)-from))
+			}
+		}
+	}
+	n, comp := len(lits), len(dst)-body
+	if comp >= n-n/16 {
+		return append(zstdLitHeader(dst[:hdr], 0, n), lits...)
+	}
+	switch hdrLen {
+	case 3:
+		h := 2 | uint32(n)<<4 | uint32(comp)<<14
+		dst[hdr], dst[hdr+1], dst[hdr+2] = byte(h), byte(h>>8), byte(h>>16)
+	case 4:
+		binary.LittleEndian.PutUint32(dst[hdr:], 2|2<<2|uint32(n)<<4|uint32(comp)<<18)
+	default:
+		h := 2 | 3<<2 | uint64(n)<<4 | uint64(comp)<<22
+		binary.LittleEndian.PutUint32(dst[hdr:], uint32(h))
+		dst[hdr+4] = byte(h >> 32)
+	}
+	return dst
+}
+
+// zstdWeights appends the Huffman tree description of weights, the last
+// symbol's left out: FSE-coded when that is shorter and valid, directly
+// as nibbles when there are at most 128, or not at all.
+func zstdWeights(dst []byte, weights []uint8) []byte {
+	var direct []byte
+	if len(weights) <= 128 {
+		direct = append(direct, byte(127+len(weights)))
+		for i := 0; i < len(weights); i += 2 {
+			lo := byte(0)
+			if i+1 < len(weights) {
+				lo = weights[i+1]
+			}
+			direct = append(direct, weights[i]<<4|lo)
+		}
+	}
+	if fse := zstdFSEWeights(weights); fse != nil && (direct == nil || len(fse) < len(direct)) {
+		return append(dst, fse...)
+	}
+	return append(dst, direct...)
+}
+
+// zstdFSEWeights codes weights with two interleaved FSE states, the way
+// zstdReadWeights decodes them, or returns nil if that doesn't fit the
+// one-byte size or doesn't decode back.
+func zstdFSEWeights(weights []uint8) []byte {
+	if len(weights) < 3 {
+		return nil
+	}
+	var counts [13]int
+	for _, w := range weights {
+		counts[w]++
+	}
+	distinct := 0
+	for _, c := range counts {
+		if c > 0 {
+			distinct++
+		}
+	}
+	if distinct < 2 {
+		return nil
+	}
+	norm, log := zstdNormalize(counts[:], len(weights), 6)
+	enc := zstdBuildEncoder(norm, log)
+	bw := zstdBitWriter{out: zstdWriteNorm([]byte{0}, norm, log)}
+	// State 0 decodes the even weights, state 1 the odd ones.
+	var st [2]zstdState
+	n := len(weights)
+	st[(n-1)%2].init(enc, weights[n-1])
+	st[n%2].init(enc, weights[n-2])
+	for i := n - 3; i >= 0; i-- {
+		st[i%2].encode(&bw, weights[i])
+	}
+	st[1].flush(&bw)
+	st[0].flush(&bw)
+	out := bw.close()
+	if len(out)-1 >= 128 {
+		return nil
+	}
+	out[0] = byte(len(out) - 1)
+	var got [256]uint8
+	if nw, used, err := zstdReadWeights(out, &got); err != nil || used != len(out) || !slices.Equal(got[:nw], weights) {
+		return nil
+	}
+	return out
+}
+
+// huffLengths sets the Huffman code length of every counted symbol, at
+// most limit bits, and returns the longest.
+func huffLengths(counts []int, lens []uint8, limit uint8) uint8 {
+	freqs := slices.Clone(counts)
+	for {
+		var syms []int
+		for s, c := range freqs {
+			if c > 0 {
+				syms = append(syms, s)
+			}
+		}
+		slices.SortStableFunc(syms, func(a, b int) int { return freqs[a] - freqs[b] })
+		n := len(syms)
+		freq := make([]int, 2*n-1)
+		parent := make([]int, 2*n-1)
+		for i, s := range syms {
+			freq[i] = freqs[s]
+		}
+		leaf, inner, next := 0, n, n
+		pick := func() int {
+			if leaf < n && (inner >= next || freq[leaf] <= freq[inner]) {
+				leaf++
+				return leaf - 1
+			}
+			inner++
+			return inner - 1
+		}
+		for next < 2*n-1 {
+			a, b := pick(), pick()
+			freq[next] = freq[a] + freq[b]
+			parent[a], parent[b] = next, next
+			next++
+		}
+		depth := make([]uint8, 2*n-1)
+		for i := 2*n - 3; i >= 0; i-- {
+			depth[i] = depth[parent[i]] + 1
+		}
+		longest := uint8(0)
+		for i, s := range syms {
+			lens[s] = depth[i]
+			longest = max(longest, depth[i])
+		}
+		if longest <= limit {
+			return longest
+		}
+		for s, c := range freqs {
+			if c > 0 {
+				freqs[s] = (c + 1) / 2
+			}
+		}
+	}
+}
+
+// zstdSequences appends the sequences section. Each code stream gets a
+// table fitted to its counts or the predefined one, whichever is cheaper
+// once the table description is paid for.
+func zstdSequences(dst []byte, seqs []zstdSeq) []byte {
+	switch n := len(seqs); {
+	case n < 128:
+		dst = append(dst, byte(n))
+	case n < 0x7f00:
+		dst = append(dst, byte(n>>8)+128, byte(n))
+	default:
+		dst = append(dst, 255, byte(n-0x7f00), byte((n-0x7f00)>>8))
+	}
+	if len(seqs) == 0 {
+		return dst
+	}
+	var llCounts [36]int
+	var mlCounts [53]int
+	var ofCounts [32]int
+	for i := range seqs {
+		q := &seqs[i]
+		q.llc, q.mlc, q.ofc = zstdLLCode(q.lit), zstdMLCode(q.match), uint8(bits.Len32(q.offVal)-1)
+		llCounts[q.llc]++
+		mlCounts[q.mlc]++
+		ofCounts[q.ofc]++
+	}
+	modes := len(dst)
+	dst = append(dst, 0)
+	var llEnc, ofEnc, mlEnc *zstdEncoder
+	var mode byte
+	dst, llEnc, mode = zstdTableFor(dst, llCounts[:], len(seqs), zstdLLEnc, zstdLLNorm, 9)
+	dst[modes] |= mode << 6
+	dst, ofEnc, mode = zstdTableFor(dst, ofCounts[:], len(seqs), zstdOFEnc, zstdOFNorm, 8)
+	dst[modes] |= mode << 4
+	dst, mlEnc, mode = zstdTableFor(dst, mlCounts[:], len(seqs), zstdMLEnc, zstdMLNorm, 9)
+	dst[modes] |= mode << 2
+	var ll, ml, of zstdState
+	bw := zstdBitWriter{out: dst}
+	extra := func(q *zstdSeq) {
+		bw.add(uint64(q.lit-zstdLLBase[q.llc]), uint(zstdLLBits[q.llc]))
+		bw.add(uint64(q.match-zstdMLBase[q.mlc]), uint(zstdMLBits[q.mlc]))
+		bw.add(uint64(q.offVal), uint(q.ofc))
+	}
+	q := &seqs[len(seqs)-1]
+	ml.init(mlEnc, q.mlc)
+	of.init(ofEnc, q.ofc)
+	ll.init(llEnc, q.llc)
+	extra(q)
+	for i := len(seqs) - 2; i >= 0; i-- {
+		q = &seqs[i]
+		of.encode(&bw, q.ofc)
+		ml.encode(&bw, q.mlc)
+		ll.encode(&bw, q.llc)
+		extra(q)
+	}
+	ml.flush(&bw)
+	of.flush(&bw)
+	ll.flush(&bw)
+	return bw.close()
+}
+
+// zstdTableFor returns the encoder for one code stream and its mode,
+// appending the table description when it isn't the predefined one.
+func zstdTableFor(dst []byte, counts []int, total int, def *zstdEncoder, defNorm []int16, maxLog uint) ([]byte, *zstdEncoder, byte) {
+	if total < 64 {
+		return dst, def, 0
+	}
+	norm, log := zstdNormalize(counts, total, maxLog)
+	desc := zstdWriteNorm(nil, norm, log)
+	if zstdCost(counts, norm, log)+float64(8*len(desc)) >= zstdCost(counts, defNorm, def.log) {
+		return dst, def, 0
+	}
+	return append(dst, desc...), zstdBuildEncoder(norm, log), 2
+}
+
+// zstdCost estimates the bits counts take under a distribution, or +Inf
+// if it can't code them.
+func zstdCost(counts []int, norm []int16, log uint) float64 {
+	bits := 0.0
+	for s, c := range counts {
+		if c == 0 {
+			continue
+		}
+		if s >= len(norm) || norm[s] == 0 {
+			return math.Inf(1)
+		}
+		bits += float64(c) * (float64(log) - math.Log2(float64(max(norm[s], 1))))
+	}
+	return bits
+}
+
+// zstdNormalize scales counts to a distribution summing to a power of
+// two, every counted symbol at least 1.
+func zstdNormalize(counts []int, total int, maxLog uint) ([]int16, uint) {
+	last, distinct := 0, 0
+	for s, c := range counts {
+		if c > 0 {
+			last = s
+			distinct++
+		}
+	}
+	log := uint(min(max(bits.Len(uint(total))-2, 5), int(maxLog)))
+	for 1<<log < 2*distinct && log < maxLog {
+		log++
+	}
+	size := 1 << log
+	norm := make([]int16, last+1)
+	sum := 0
+	for s, c := range counts[:last+1] {
+		if c > 0 {
+			v := max((c*size+total/2)/total, 1)
+			norm[s] = int16(v)
+			sum += v
+		}
+	}
+	for sum != size {
+		big := 0
+		for s := range norm {
+			if norm[s] > norm[big] {
+				big = s
+			}
+		}
+		if sum < size {
+			norm[big] += int16(size - sum)
+			break
+		}
+		norm[big]--
+		sum--
+	}
+	return norm, log
+}
+
+// zstdWriteNorm appends the FSE table description of norm, the inverse
+// of zstdReadNorm.
+func zstdWriteNorm(dst []byte, norm []int16, log uint) []byte {
+	bw := zstdBitWriter{out: dst}
+	bw.add(uint64(log-5), 4)
+	remaining, threshold, nb := int32(1<<log)+1, int32(1<<log), log+1
+	zero := false
+	for sym := 0; remaining > 1; {
+		if zero {
+			z := 0
+			for norm[sym] == 0 {
+				z++
+				sym++
+			}
+			for ; z >= 3; z -= 3 {
+				bw.add(3, 2)
+			}
+			bw.add(uint64(z), 2)
+		}
+		count := int32(norm[sym])
+		sym++
+		most := 2*threshold - 1 - remaining
+		remaining -= max(count, -count)
+		count++
+		if count >= threshold {
+			count += most
+		}
+		if count < most {
+			bw.add(uint64(count), nb-1)
+		} else {
+			bw.add(uint64(count), nb)
+		}
+		zero = count == 1
+		for remaining < threshold && remaining > 1 {
+			nb--
+			threshold >>= 1
+		}
+	}
+	if bw.n > 0 {
+		bw.out = append(bw.out, byte(bw.acc))
+	}
+	return bw.out
+}
+
+// zstdXXH is the 64-bit xxHash with seed 0, of which zstd frames carry
+// the low 32 bits.
+type zstdXXH struct {
+	v     [4]uint64
+	buf   [32]byte
+	n     int
+	total uint64
+}
+
+const (
+	xxhPrime1 uint64 = 11400714785074694791
+	xxhPrime2 uint64 = 14029467366897019727
+	xxhPrime3 uint64 = 1609587929392839161
+	xxhPrime4 uint64 = 9650029242287828579
+	xxhPrime5 uint64 = 2870177450012600261
+)
+
+func xxhRound(acc, in uint64) uint64 {
+	return bits.RotateLeft64(acc+in*xxhPrime2, 31) * xxhPrime1
+}
+
+func (x *zstdXXH) reset() {
+	*x = zstdXXH{}
+	x.v[0] = xxhPrime1
+	x.v[0] += xxhPrime2
+	x.v[1] = xxhPrime2
+	x.v[3] -= xxhPrime1
+}
+
+func (x *zstdXXH) write(p []byte) {
+	x.total += uint64(len(p))
+	if x.n > 0 {
+		k := copy(x.buf[x.n:], p)
+		x.n += k
+		p = p[k:]
+		if x.n < 32 {
+			return
+		}
+		x.stripe(x.buf[:])
+		x.n = 0
+	}
+	for len(p) >= 32 {
+		x.stripe(p)
+		p = p[32:]
+	}
+	x.n = copy(x.buf[:], p)
+}
+
+func (x *zstdXXH) stripe(p []byte) {
+	for i := range x.v {
+		x.v[i] = xxhRound(x.v[i], binary.LittleEndian.Uint64(p[8*i:]))
+	}
+}
+
+func (x *zstdXXH) sum() uint64 {
+	var h uint64
+	if x.total >= 32 {
+		v := x.v
+		h = bits.RotateLeft64(v[0], 1) + bits.RotateLeft64(v[1], 7) +
+			bits.RotateLeft64(v[2], 12) + bits.RotateLeft64(v[3], 18)
+		for _, vi := range v {
+			h = (h^xxhRound(0, vi))*xxhPrime1 + xxhPrime4
+		}
+	} else {
+		h = xxhPrime5
+	}
+	h += x.total
+	p := x.buf[:x.n]
+	for ; len(p) >= 8; p = p[8:] {
+		h ^= xxhRound(0, binary.LittleEndian.Uint64(p))
+		h = bits.RotateLeft64(h, 27)*xxhPrime1 + xxhPrime4
+	}
+	if len(p) >= 4 {
+		h ^= uint64(binary.LittleEndian.Uint32(p)) * xxhPrime1
+		h = bits.RotateLeft64(h, 23)*xxhPrime2 + xxhPrime3
+		p = p[4:]
+	}
+	for _, b := range p {
+		h ^= uint64(b) * xxhPrime5
+		h = bits.RotateLeft64(h, 11) * xxhPrime1
+	}
+	h ^= h >> 33
+	h *= xxhPrime2
+	h ^= h >> 29
+	h *= xxhPrime3
+	h ^= h >> 32
+	return h
+}
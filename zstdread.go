@@ -0,0 +1,770 @@
+package speedlog
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"io"
+	"math/bits"
+	"slices"
+)
+
+var (
+	errZstdCorrupt = errors.New("speedlog: corrupt zstd stream")
+	errZstdDict    = errors.New("speedlog: zstd dictionaries aren't supported")
+	errZstdWindow  = errors.New("speedlog: zstd window too large")
+)
+
+// zstdMaxWindow is the largest window the reader takes on, the zstd
+// tool's own default limit.
+const zstdMaxWindow = 1 << 27
+
+// zstdBackBits reads a backward bitstream: from the end marker towards the
+// first byte, each value with its high bit first.
+type zstdBackBits struct {
+	in   []byte
+	off  int    // in[:off] is still to load
+	v    uint64 // the low n bits are unread, the next one at the top
+	n    uint
+	over bool // more bits were read than the stream has
+}
+
+func (br *zstdBackBits) init(in []byte) error {
+	if len(in) == 0 || in[len(in)-1] == 0 {
+		return errZstdCorrupt
+	}
+	last := in[len(in)-1]
+	*br = zstdBackBits{in: in, off: len(in) - 1, v: uint64(last), n: uint(bits.Len8(last)) - 1}
+	br.fill()
+	return nil
+}
+
+func (br *zstdBackBits) fill() {
+	for br.n <= 56 && br.off > 0 {
+		br.off--
+		br.v = br.v<<8 | uint64(br.in[br.off])
+		br.n += 8
+	}
+}
+
+// peek returns the next k bits, zeros past the start of the stream.
+func (br *zstdBackBits) peek(k uint) uint64 {
+	if br.n < k {
+		br.fill()
+		if br.n < k {
+			return br.v << (k - br.n) & (1<<k - 1)
+		}
+	}
+	return br.v >> (br.n - k) & (1<<k - 1)
+}
+
+func (br *zstdBackBits) read(k uint) uint64 {
+	v := br.peek(k)
+	if br.n < k {
+		br.n, br.over = 0, true
+	} else {
+		br.n -= k
+	}
+	return v
+}
+
+func (br *zstdBackBits) done() bool { return br.n == 0 && br.off == 0 && !br.over }
+
+type zstdFSE struct {
+	sym  uint8
+	bits uint8
+	base uint16
+}
+
+// zstdTable is a decoding table and its accuracy log.
+type zstdTable struct {
+	t   []zstdFSE
+	log uint
+}
+
+func zstdBuildTable(norm []int16, log uint) (zstdTable, error) {
+	syms, ok := zstdSpread(norm, log)
+	if !ok {
+		return zstdTable{}, errZstdCorrupt
+	}
+	size := 1 << log
+	next := make([]uint16, len(norm))
+	for s, c := range norm {
+		next[s] = uint16(max(c, 1))
+	}
+	t := make([]zstdFSE, size)
+	for u, s := range syms {
+		ns := next[s]
+		next[s]++
+		nb := log - uint(bits.Len16(ns)-1)
+		t[u] = zstdFSE{sym: s, bits: uint8(nb), base: ns<<nb - uint16(size)}
+	}
+	return zstdTable{t, log}, nil
+}
+
+var (
+	zstdLLTable, _ = zstdBuildTable(zstdLLNorm, zstdLLLog)
+	zstdMLTable, _ = zstdBuildTable(zstdMLNorm, zstdMLLog)
+	zstdOFTable, _ = zstdBuildTable(zstdOFNorm, zstdOFLog)
+)
+
+// zstdReadNorm parses an FSE table description, returning the
+// distribution, its accuracy log and the bytes used.
+func zstdReadNorm(in []byte, maxSym int, maxLog uint) ([]int16, uint, int, error) {
+	pos := uint(0)
+	peek := func(k uint) int32 {
+		var v uint32
+		for i := range uint(4) {
+			if b := pos/8 + i; b < uint(len(in)) {
+				v |= uint32(in[b]) << (8 * i)
+			}
+		}
+		return int32(v >> (pos % 8) & (1<<k - 1))
+	}
+	if len(in) == 0 {
+		return nil, 0, 0, errZstdCorrupt
+	}
+	log := uint(peek(4)) + 5
+	pos = 4
+	if log > maxLog {
+		return nil, 0, 0, errZstdCorrupt
+	}
+	norm := make([]int16, maxSym+1)
+	remaining, threshold, nb := int32(1<<log)+1, int32(1<<log), log+1
+	sym := 0
+	for remaining > 1 {
+		if sym > maxSym {
+			return nil, 0, 0, errZstdCorrupt
+		}
+		most := 2*threshold - 1 - remaining
+		v := peek(nb)
+		var count int32
+		if v&(threshold-1) < most {
+			count = v & (threshold - 1)
+			pos += nb - 1
+		} else {
+			count = v
+			if count >= threshold {
+				count -= most
+			}
+			pos += nb
+		}
+		count--
+		remaining -= max(count, -count)
+		norm[sym] = int16(count)
+		sym++
+		if count == 0 {
+			for {
+				rep := int(peek(2))
+				pos += 2
+				if sym+rep > maxSym+1 {
+					return nil, 0, 0, errZstdCorrupt
+				}
+				sym += rep
+				if rep != 3 {
+					break
+				}
+			}
+		}
+		for remaining < threshold && remaining > 1 {
+			nb--
+			threshold >>= 1
+		}
+	}
+	n := int(pos+7) / 8
+	if remaining != 1 || n > len(in) {
+		return nil, 0, 0, errZstdCorrupt
+	}
+	return norm, log, n, nil
+}
+
+type zstdHuff struct {
+	sym  uint8
+	bits uint8
+}
+
+// zstdReadWeights parses the weights of a Huffman tree description, all
+// but the last symbol's, returning the bytes used.
+func zstdReadWeights(in []byte, weights *[256]uint8) (int, int, error) {
+	if len(in) == 0 {
+		return 0, 0, errZstdCorrupt
+	}
+	hdr := int(in[0])
+	if hdr >= 128 {
+		nw, used := hdr-127, 1+(hdr-126)/2
+		if used > len(in) {
+			return 0, 0, errZstdCorrupt
+		}
+		for i := range nw {
+			weights[i] = in[1+i/2] >> (4 * (1 - i%2)) & 15
+		}
+		return nw, used, nil
+	}
+	used := 1 + hdr
+	if used > len(in) || hdr == 0 {
+		return 0, 0, errZstdCorrupt
+	}
+	data := in[1:used]
+	norm, log, n, err := zstdReadNorm(data, 255, 6)
+	if err != nil {
+		return 0, 0, err
+	}
+	tab, err := zstdBuildTable(norm, log)
+	if err != nil {
+		return 0, 0, err
+	}
+	var br zstdBackBits
+	if err := br.init(data[n:]); err != nil {
+		return 0, 0, err
+	}
+	// Two interleaved states, until the stream runs out.
+	nw := 0
+	states := [2]uint64{br.read(log), br.read(log)}
+	for i := 0; ; i ^= 1 {
+		if nw >= 254 {
+			return 0, 0, errZstdCorrupt
+		}
+		e := tab.t[states[i]]
+		weights[nw] = e.sym
+		nw++
+		states[i] = uint64(e.base) + br.read(uint(e.bits))
+		if br.over {
+			weights[nw] = tab.t[states[i^1]].sym
+			return nw + 1, used, nil
+		}
+	}
+}
+
+// zstdReadHuff parses a Huffman tree description into a decoding table,
+// returning the bytes used.
+func zstdReadHuff(in []byte) ([]zstdHuff, uint, int, error) {
+	var weights [256]uint8
+	nw, used, err := zstdReadWeights(in, &weights)
+	if err != nil {
+		return nil, 0, 0, err
+	}
+	total := 0
+	for _, w := range weights[:nw] {
+		if w > 11 {
+			return nil, 0, 0, errZstdCorrupt
+		}
+		if w > 0 {
+			total += 1 << (w - 1)
+		}
+	}
+	if total == 0 {
+		return nil, 0, 0, errZstdCorrupt
+	}
+	maxBits := uint(bits.Len(uint(total)))
+	rest := 1<<maxBits - total
+	if maxBits > 11 || rest&(rest-1) != 0 {
+		return nil, 0, 0, errZstdCorrupt
+	}
+	weights[nw] = uint8(bits.Len(uint(rest)))
+	nw++
+	// Lower weights take the lower codes, each symbol 2^(weight-1) slots.
+	var start [13]int
+	for _, w := range weights[:nw] {
+		if w > 0 {
+			start[w+1] += 1 << (w - 1)
+		}
+	}
+	for w := 2; w < len(start); w++ {
+		start[w] += start[w-1]
+	}
+	t := make([]zstdHuff, 1<<maxBits)
+	for s, w := range weights[:nw] {
+		if w == 0 {
+			continue
+		}
+		n := 1 << (w - 1)
+		e := zstdHuff{sym: uint8(s), bits: uint8(maxBits + 1 - uint(w))}
+		for i := range n {
+			t[start[w]+i] = e
+		}
+		start[w] += n
+	}
+	return t, maxBits, used, nil
+}
+
+func zstdHuffDecode(dst, in []byte, t []zstdHuff, maxBits uint) error {
+	var br zstdBackBits
+	if err := br.init(in); err != nil {
+		return err
+	}
+	for i := range dst {
+		e := t[br.peek(maxBits)]
+		if uint(e.bits) > br.n {
+			return errZstdCorrupt
+		}
+		br.n -= uint(e.bits)
+		dst[i] = e.sym
+	}
+	if !br.done() {
+		return errZstdCorrupt
+	}
+	return nil
+}
+
+type zstdReader struct {
+	r      *bufio.Reader
+	hist   []byte // the window, then output not yet read
+	pos    int
+	window int
+	last   bool
+	inside bool
+	check  bool
+	size   int64 // content size from the header, or -1
+	total  int64
+	xxh    zstdXXH
+	block  []byte
+	lits   []byte
+	huff   []zstdHuff
+	hbits  uint
+	ll     zstdTable
+	of     zstdTable
+	ml     zstdTable
+	rep    [3]int
+	err    error
+}
+
+func newZstdReader(r io.Reader) *zstdReader {
+	return &zstdReader{r: bufio.NewReader(r)}
+}
+
+func (zr *zstdReader) Read(p []byte) (int, error) {
+	for zr.pos == len(zr.hist) {
+		if zr.err != nil {
+			return 0, zr.err
+		}
+		zr.err = zr.next()
+	}
+	n := copy(p, zr.hist[zr.pos:])
+	zr.pos += n
+	return n, nil
+}
+
+func (zr *zstdReader) Close() error { return nil }
+
+func (zr *zstdReader) readFull(p []byte) error {
+	if _, err := io.ReadFull(zr.r, p); err != nil {
+		if err == io.EOF {
+			return io.ErrUnexpectedEOF
+		}
+		return err
+	}
+	return nil
+}
+
+// next decodes a block, moving through frame headers and trailers.
+func (zr *zstdReader) next() error {
+	if !zr.inside {
+		return zr.frame()
+	}
+	if zr.last {
+		if zr.check {
+			var sum [4]byte
+			if err := zr.readFull(sum[:]); err != nil {
+				return err
+			}
+			if binary.LittleEndian.Uint32(sum[:]) != uint32(zr.xxh.sum()) {
+				return errZstdCorrupt
+			}
+		}
+		if zr.size >= 0 && zr.total != zr.size {
+			return errZstdCorrupt
+		}
+		zr.inside = false
+		return nil
+	}
+	if len(zr.hist) > 2*zr.window {
+		d := len(zr.hist) - zr.window
+		zr.hist = zr.hist[:copy(zr.hist, zr.hist[d:])]
+		zr.pos = len(zr.hist)
+	}
+	var hdr [3]byte
+	if err := zr.readFull(hdr[:]); err != nil {
+		return err
+	}
+	h := uint32(hdr[0]) | uint32(hdr[1])<<8 | uint32(hdr[2])<<16
+	zr.last = h&1 == 1
+	size, from := int(h>>3), len(zr.hist)
+	if size > zstdBlockMax {
+		return errZstdCorrupt
+	}
+	switch h >> 1 & 3 {
+	case 0:
+		zr.hist = slices.Grow(zr.hist, size)[:from+size]
+		if err := zr.readFull(zr.hist[from:]); err != nil {
+			return err
+		}
+	case 1:
+		b, err := zr.r.ReadByte()
+		if err != nil {
+			return io.ErrUnexpectedEOF
+		}
+		for range size {
+			zr.hist = append(zr.hist, b)
+		}
+	case 2:
+		if cap(zr.block) < size {
+			zr.block = make([]byte, size)
+		}
+		zr.block = zr.block[:size]
+		if err := zr.readFull(zr.block); err != nil {
+			return err
+		}
+		if err := zr.decompress(zr.block); err != nil {
+			return err
+		}
+		if len(zr.hist)-from > zstdBlockMax {
+			return errZstdCorrupt
+		}
+	default:
+		return errZstdCorrupt
+	}
+	zr.total += int64(len(zr.hist) - from)
+	if zr.check {
+		zr.xxh.write(zr.hist[from:])
+	}
+	return nil
+}
+
+// frame reads the next frame header, skipping skippable frames; a clean
+// end of input between frames is io.EOF.
+func (zr *zstdReader) frame() error {
+	var magic [4]byte
+	if _, err := io.ReadFull(zr.r, magic[:]); err != nil {
+		if err == io.EOF {
+			return io.EOF
+		}
+		return io.ErrUnexpectedEOF
+	}
+	if m := binary.LittleEndian.Uint32(magic[:]); m&^0xf == 0x184d2a50 {
+		if err := zr.readFull(magic[:]); err != nil {
+			return err
+		}
+		_, err := zr.r.Discard(int(binary.LittleEndian.Uint32(magic[:])))
+		if err != nil {
+			return io.ErrUnexpectedEOF
+		}
+		return nil
+	} else if m != zstdMagic {
+		return errZstdCorrupt
+	}
+	fhd, err := zr.r.ReadByte()
+	if err != nil {
+		return io.ErrUnexpectedEOF
+	}
+	if fhd&8 != 0 {
+		return errZstdCorrupt
+	}
+	single := fhd&0x20 != 0
+	n := 0
+	if !single {
+		n = 1
+	}
+	dictLen := [4]int{0, 1, 2, 4}[fhd&3]
+	fcsLen := [4]int{0, 2, 4, 8}[fhd>>6]
+	if fcsLen == 0 && single {
+		fcsLen = 1
+	}
+	var buf [15]byte
+	if err := zr.readFull(buf[:n+dictLen+fcsLen]); err != nil {
+		return err
+	}
+	window := 0
+	if !single {
+		exp, mant := (buf[0]>>3)+10, int(buf[0]&7)
+		if exp > 30 {
+			return errZstdWindow
+		}
+		window = 1<<exp + (1<<exp)/8*mant
+	}
+	dict := buf[n : n+dictLen]
+	for _, b := range dict {
+		if b != 0 {
+			return errZstdDict
+		}
+	}
+	zr.size = -1
+	if fcsLen > 0 {
+		var fcs [8]byte
+		copy(fcs[:], buf[n+dictLen:n+dictLen+fcsLen])
+		zr.size = int64(binary.LittleEndian.Uint64(fcs[:]))
+		if fcsLen == 2 {
+			zr.size += 256
+		}
+		if single {
+			window = int(min(zr.size, zstdMaxWindow+1))
+		}
+	}
+	if window > zstdMaxWindow {
+		return errZstdWindow
+	}
+	zr.window = max(window, 1<<10)
+	zr.check = fhd&4 != 0
+	zr.xxh.reset()
+	zr.hist, zr.pos = zr.hist[:0], 0
+	zr.total, zr.last, zr.inside = 0, false, true
+	zr.huff = nil
+	zr.ll, zr.of, zr.ml = zstdTable{}, zstdTable{}, zstdTable{}
+	zr.rep = [3]int{1, 4, 8}
+	return nil
+}
+
+// decompress decodes a compressed block onto hist.
+func (zr *zstdReader) decompress(in []byte) error {
+	lits, n, err := zr.literals(in)
+	if err != nil {
+		return err
+	}
+	in = in[n:]
+	if len(in) == 0 {
+		return errZstdCorrupt
+	}
+	nseq := int(in[0])
+	switch {
+	case nseq == 0:
+		if len(in) != 1 {
+			return errZstdCorrupt
+		}
+		zr.hist = append(zr.hist, lits...)
+		return nil
+	case nseq < 128:
+		in = in[1:]
+	case nseq < 255:
+		if len(in) < 2 {
+			return errZstdCorrupt
+		}
+		nseq = (nseq-128)<<8 + int(in[1])
+		in = in[2:]
+	default:
+		if len(in) < 3 {
+			return errZstdCorrupt
+		}
+		nseq = int(in[1]) + int(in[2])<<8 + 0x7f00
+		in = in[3:]
+	}
+	if len(in) == 0 || in[0]&3 != 0 {
+		return errZstdCorrupt
+	}
+	modes := in[0]
+	in = in[1:]
+	for _, t := range []struct {
+		tab    *zstdTable
+		def    zstdTable
+		mode   byte
+		maxSym int
+		maxLog uint
+	}{
+		{&zr.ll, zstdLLTable, modes >> 6, 35, 9},
+		{&zr.of, zstdOFTable, modes >> 4 & 3, 31, 8},
+		{&zr.ml, zstdMLTable, modes >> 2 & 3, 52, 9},
+	} {
+		switch t.mode {
+		case 0:
+			*t.tab = t.def
+		case 1:
+			if len(in) == 0 || int(in[0]) > t.maxSym {
+				return errZstdCorrupt
+			}
+			*t.tab = zstdTable{t: []zstdFSE{{sym: in[0]}}}
+			in = in[1:]
+		case 2:
+			norm, log, n, err := zstdReadNorm(in, t.maxSym, t.maxLog)
+			if err != nil {
+				return err
+			}
+			if *t.tab, err = zstdBuildTable(norm, log); err != nil {
+				return err
+			}
+			in = in[n:]
+		case 3:
+			if t.tab.t == nil {
+				return errZstdCorrupt
+			}
+		}
+	}
+	var br zstdBackBits
+	if err := br.init(in); err != nil {
+		return err
+	}
+	ll, of, ml := br.read(zr.ll.log), br.read(zr.of.log), br.read(zr.ml.log)
+	start := len(zr.hist)
+	for i := range nseq {
+		lle, ofe, mle := zr.ll.t[ll], zr.of.t[of], zr.ml.t[ml]
+		if lle.sym > 35 || ofe.sym > 31 || mle.sym > 52 {
+			return errZstdCorrupt
+		}
+		offVal := 1<<ofe.sym + int(br.read(uint(ofe.sym)))
+		mlen := int(zstdMLBase[mle.sym]) + int(br.read(uint(zstdMLBits[mle.sym])))
+		llen := int(zstdLLBase[lle.sym]) + int(br.read(uint(zstdLLBits[lle.sym])))
+		var offset int
+		if offVal > 3 {
+			offset = offVal - 3
+			zr.rep = [3]int{offset, zr.rep[0], zr.rep[1]}
+		} else {
+			idx := offVal - 1
+			if llen == 0 {
+				idx++
+			}
+			switch idx {
+			case 0:
+				offset = zr.rep[0]
+			case 1:
+				offset = zr.rep[1]
+				zr.rep[1], zr.rep[0] = zr.rep[0], offset
+			case 2:
+				offset = zr.rep[2]
+				zr.rep = [3]int{offset, zr.rep[0], zr.rep[1]}
+			default:
+				offset = zr.rep[0] - 1
+				if offset == 0 {
+					return errZstdCorrupt
+				}
+				zr.rep = [3]int{offset, zr.rep[0], zr.rep[1]}
+			}
+		}
+		if i < nseq-1 {
+			ll = uint64(lle.base) + br.read(uint(lle.bits))
+			ml = uint64(mle.base) + br.read(uint(mle.bits))
+			of = uint64(ofe.base) + br.read(uint(ofe.bits))
+		}
+		if llen > len(lits) || offset > len(zr.hist)+llen || len(zr.hist)-start+llen+mlen > zstdBlockMax {
+			return errZstdCorrupt
+		}
+		zr.hist = append(zr.hist, lits[:llen]...)
+		lits = lits[llen:]
+		from := len(zr.hist) - offset
+		if offset >= mlen {
+			zr.hist = append(zr.hist, zr.hist[from:from+mlen]...)
+			continue
+		}
+		for j := range mlen {
+			zr.hist = append(zr.hist, zr.hist[from+j])
+		}
+	}
+	if !br.done() {
+		return errZstdCorrupt
+	}
+	zr.hist = append(zr.hist, lits...)
+	return nil
+}
+
+// literals decodes the literals section, returning the literals and the
+// bytes used.
+func (zr *zstdReader) literals(in []byte) ([]byte, int, error) {
+	if len(in) == 0 {
+		return nil, 0, errZstdCorrupt
+	}
+	typ, format := in[0]&3, in[0]>>2&3
+	if typ < 2 {
+		var n, hl int
+		switch format {
+		case 0, 2:
+			n, hl = int(in[0]>>3), 1
+		case 1:
+			if len(in) < 2 {
+				return nil, 0, errZstdCorrupt
+			}
+			n, hl = int(in[0]>>4)+int(in[1])<<4, 2
+		case 3:
+			if len(in) < 3 {
+				return nil, 0, errZstdCorrupt
+			}
+			n, hl = int(in[0]>>4)+int(in[1])<<4+int(in[2])<<12, 3
+		}
+		if n > zstdBlockMax {
+			return nil, 0, errZstdCorrupt
+		}
+		if typ == 0 {
+			if hl+n > len(in) {
+				return nil, 0, errZstdCorrupt
+			}
+			return in[hl : hl+n], hl + n, nil
+		}
+		if hl >= len(in) {
+			return nil, 0, errZstdCorrupt
+		}
+		zr.lits = zr.lits[:0]
+		for range n {
+			zr.lits = append(zr.lits, in[hl])
+		}
+		return zr.lits, hl + 1, nil
+	}
+	var n, comp, hl int
+	streams := 4
+	switch format {
+	case 0, 1:
+		if len(in) < 3 {
+			return nil, 0, errZstdCorrupt
+		}
+		h := int(in[0]) | int(in[1])<<8 | int(in[2])<<16
+		n, comp, hl = h>>4&0x3ff, h>>14&0x3ff, 3
+		if format == 0 {
+			streams = 1
+		}
+	case 2:
+		if len(in) < 4 {
+			return nil, 0, errZstdCorrupt
+		}
+		h := int(binary.LittleEndian.Uint32(in))
+		n, comp, hl = h>>4&0x3fff, h>>18&0x3fff, 4
+	case 3:
+		if len(in) < 5 {
+			return nil, 0, errZstdCorrupt
+		}
+		h := uint64(binary.LittleEndian.Uint32(in)) | uint64(in[4])<<32
+		n, comp, hl = int(h>>4&0x3ffff), int(h>>22&0x3ffff), 5
+	}
+	if n > zstdBlockMax || hl+comp > len(in) {
+		return nil, 0, errZstdCorrupt
+	}
+	data := in[hl : hl+comp]
+	if typ == 2 {
+		t, maxBits, used, err := zstdReadHuff(data)
+		if err != nil {
+			return nil, 0, err
+		}
+		zr.huff, zr.hbits = t, maxBits
+		data = data[used:]
+	} else if zr.huff == nil {
+		return nil, 0, errZstdCorrupt
+	}
+	if cap(zr.lits) < n {
+		zr.lits = make([]byte, n)
+	}
+	lits := zr.lits[:n]
+	if streams == 1 {
+		if err := zstdHuffDecode(lits, data, zr.huff, zr.hbits); err != nil {
+			return nil, 0, err
+		}
+		return lits, hl + comp, nil
+	}
+	if len(data) < 6 {
+		return nil, 0, errZstdCorrupt
+	}
+	seg := (n + 3) / 4
+	if 3*seg > n {
+		return nil, 0, errZstdCorrupt
+	}
+	sizes := [4]int{
+		int(binary.LittleEndian.Uint16(data)),
+		int(binary.LittleEndian.Uint16(data[2:])),
+		int(binary.LittleEndian.Uint16(data[4:])),
+	}
+	data = data[6:]
+	sizes[3] = len(data) - sizes[0] - sizes[1] - sizes[2]
+	if sizes[3] < 0 {
+		return nil, 0, errZstdCorrupt
+	}
+	for i, size := range sizes {
+		out := lits[i*seg : min((i+1)*seg, n)]
+		if err := zstdHuffDecode(out, data[:size], zr.huff, zr.hbits); err != nil {
+			return nil, 0, err
+		}
+		data = data[size:]
+	}
+	return lits, hl + comp, nil
+}